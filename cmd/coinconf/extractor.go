@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+)
+
+// Finding records which file and pattern supplied a coin conf value, or why
+// none did.
+type Finding struct {
+	Network  string
+	Field    string
+	Value    string
+	File     string
+	Pattern  string
+	Required bool
+	Problems []string
+}
+
+// lookup is a pattern to search a source file for, the first submatch is
+// the value. Network lookups only search the current network's params class.
+type lookup struct {
+	file    string
+	pattern string
+	network bool
+}
+
+// Network is a chain whose params live in their own class of
+// chainparams.cpp.
+type Network struct {
+	Name   string
+	Class  string
+	Suffix string
+}
+
+// Networks are the chains a conf is written for, mainnet first.
+var Networks = []Network{
+	{Name: "main", Class: "CMainParams", Suffix: ""},
+	{Name: "test", Class: "CTestNetParams", Suffix: "-test"},
+	{Name: "regtest", Class: "CRegTestParams", Suffix: "-regtest"},
+}
+
+var paramsClass = regexp.MustCompile(`(?m)^class (C\w+Params)\b`)
+
+type sourceFile struct {
+	data string
+	err  error
+}
+
+// Extractor searches a coin's sources for the values of its coin conf and
+// keeps a finding per field.
+type Extractor struct {
+	Source   Source
+	Network  Network
+	Findings []Finding
+
+	files map[string]sourceFile
+}
+
+func NewExtractor(source Source) *Extractor {
+	return &Extractor{Source: source, files: make(map[string]sourceFile)}
+}
+
+func (e *Extractor) read(name string) (string, error) {
+	file, ok := e.files[name]
+	if !ok {
+		file.data, file.err = e.Source.ReadFile(name)
+		e.files[name] = file
+	}
+	return file.data, file.err
+}
+
+// block returns the current network's params class out of a file. Mainnet
+// falls back to the whole file for forks without params classes.
+func (e *Extractor) block(data string) (string, error) {
+	classes := paramsClass.FindAllStringSubmatchIndex(data, -1)
+	for i, class := range classes {
+		if data[class[2]:class[3]] != e.Network.Class {
+			continue
+		}
+
+		end := len(data)
+		if i+1 < len(classes) {
+			end = classes[i+1][0]
+		}
+		return data[class[0]:end], nil
+	}
+
+	if e.Network.Name == "main" {
+		return data, nil
+	}
+	return "", fmt.Errorf("no %s class", e.Network.Class)
+}
+
+// HasNetwork reports whether chainparams.cpp defines the current network.
+func (e *Extractor) HasNetwork() bool {
+	data, err := e.read("chainparams.cpp")
+	if err != nil {
+		return false
+	}
+	_, err = e.block(data)
+	return err == nil
+}
+
+// find tries every lookup in order and returns the submatches of the first
+// pattern that matches, or nil with the reasons recorded.
+func (e *Extractor) find(field string, required bool, lookups ...lookup) []string {
+	matches := e.search(field, required, 1, lookups)
+	if matches == nil {
+		return nil
+	}
+	return matches[0]
+}
+
+// findAll is find returning every match of the first pattern that matches.
+func (e *Extractor) findAll(field string, required bool, lookups ...lookup) [][]string {
+	return e.search(field, required, -1, lookups)
+}
+
+func (e *Extractor) search(field string, required bool, n int, lookups []lookup) [][]string {
+	finding := Finding{Field: field, Required: required}
+	for _, candidate := range lookups {
+		if candidate.network {
+			finding.Network = e.Network.Name
+		}
+	}
+
+	for _, candidate := range lookups {
+		data, err := e.read(candidate.file)
+		if err == nil && candidate.network {
+			data, err = e.block(data)
+		}
+		if err != nil {
+			problem := fmt.Sprintf("unable to read %s: %s", candidate.file, err)
+			if len(finding.Problems) == 0 || finding.Problems[len(finding.Problems)-1] != problem {
+				finding.Problems = append(finding.Problems, problem)
+			}
+			continue
+		}
+
+		matches := regexp.MustCompile(candidate.pattern).FindAllStringSubmatch(data, n)
+		if matches == nil {
+			where := e.Source.Describe(candidate.file)
+			if candidate.network {
+				where = e.Network.Class + " of " + where
+			}
+			finding.Problems = append(finding.Problems, fmt.Sprintf("`%s` not found in %s", candidate.pattern, where))
+			continue
+		}
+
+		finding.File = e.Source.Describe(candidate.file)
+		finding.Pattern = candidate.pattern
+		e.Findings = append(e.Findings, finding)
+		return matches
+	}
+
+	e.Findings = append(e.Findings, finding)
+	return nil
+}
+
+// fail records why the value of the last match of field couldn't be
+// derived.
+func (e *Extractor) fail(field string, problem string) string {
+	for i := len(e.Findings) - 1; i >= 0; i-- {
+		if e.Findings[i].Field == field {
+			e.Findings[i].Value = ""
+			e.Findings[i].Problems = append(e.Findings[i].Problems, problem)
+			break
+		}
+	}
+	return ""
+}
+
+// found sets the value derived from the last match of field.
+func (e *Extractor) found(field string, value string) string {
+	for i := len(e.Findings) - 1; i >= 0; i-- {
+		if e.Findings[i].Field == field {
+			e.Findings[i].Value = value
+			break
+		}
+	}
+	return value
+}
+
+// MissingRequired reports whether a value the phantom can't run without
+// wasn't found for the network.
+func (e *Extractor) MissingRequired(network string) bool {
+	for _, finding := range e.Findings {
+		if finding.Required && finding.Value == "" && (finding.Network == "" || finding.Network == network) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteReport prints where every value came from, followed by diagnostics
+// for the ones that couldn't be found.
+func (e *Extractor) WriteReport(w io.Writer) {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "NETWORK\tFIELD\tVALUE\tFILE\tPATTERN")
+	for _, finding := range e.Findings {
+		if finding.Value != "" {
+			fmt.Fprintf(table, "%s\t%s\t%q\t%s\t%s\n", networkName(finding.Network), finding.Field,
+				finding.Value, finding.File, finding.Pattern)
+		}
+	}
+	table.Flush()
+
+	for _, finding := range e.Findings {
+		if finding.Value != "" {
+			continue
+		}
+
+		severity := "warning"
+		if finding.Required {
+			severity = "error"
+		}
+
+		reason := strings.Join(finding.Problems, "; ")
+		if reason == "" {
+			reason = "the value found couldn't be parsed"
+		}
+		fmt.Fprintf(w, "%s: %s (%s) not found: %s\n", severity, finding.Field, networkName(finding.Network), reason)
+	}
+}
+
+func networkName(network string) string {
+	if network == "" {
+		return "all"
+	}
+	return network
+}
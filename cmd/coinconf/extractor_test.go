@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// mapSource serves source files out of memory.
+type mapSource map[string]string
+
+func (source mapSource) ReadFile(name string) (string, error) {
+	data, ok := source[name]
+	if !ok {
+		return "", fmt.Errorf("%s not found", name)
+	}
+	return data, nil
+}
+
+func (source mapSource) Describe(name string) string {
+	return "src/" + name
+}
+
+const testChainParams = `
+class CMainParams : public CChainParams {
+public:
+    CMainParams() {
+        pchMessageStart[0] = 0xbf;
+        pchMessageStart[1] = 0x0c;
+        pchMessageStart[2] = 0x6b;
+        pchMessageStart[3] = 0xbd;
+        nDefaultPort = 9999;
+        vSeeds.push_back(CDNSSeedData("dash.org", "dnsseed.dash.org"));
+        vSeeds.emplace_back("dnsseed.dashdot.io");
+        strSporkPubKey = "04549ac134f694c0243f503e8c8a9a986f5de6610049c40b07816809b0d1d06a21b07be27b9bb555931773f62ba6cf35a25fd52f694d4e1106ccd237a7bb899fdd";
+    }
+};
+
+class CTestNetParams : public CChainParams {
+public:
+    CTestNetParams() {
+        pchMessageStart[0] = 0xce;
+        pchMessageStart[1] = 0xe2;
+        pchMessageStart[2] = 0xca;
+        pchMessageStart[3] = 0xff;
+        nDefaultPort = 19999;
+    }
+};
+`
+
+func TestExtractorNetworkBlocks(t *testing.T) {
+	extractor := NewExtractor(mapSource{"chainparams.cpp": testChainParams})
+
+	tests := []struct {
+		network    Network
+		has        bool
+		magicbytes string
+		port       string
+	}{
+		{Networks[0], true, "BD6B0CBF", "9999"},
+		{Networks[1], true, "FFCAE2CE", "19999"},
+		{Networks[2], false, "", ""},
+	}
+
+	for _, test := range tests {
+		extractor.Network = test.network
+		if has := extractor.HasNetwork(); has != test.has {
+			t.Errorf("%s: HasNetwork() = %t, want %t", test.network.Name, has, test.has)
+		}
+		if magicbytes := extractor.LoadMagicBytes(); magicbytes != test.magicbytes {
+			t.Errorf("%s: LoadMagicBytes() = %q, want %q", test.network.Name, magicbytes, test.magicbytes)
+		}
+		if port := extractor.LoadPort(); port != test.port {
+			t.Errorf("%s: LoadPort() = %q, want %q", test.network.Name, port, test.port)
+		}
+	}
+
+	if extractor.MissingRequired("main") || extractor.MissingRequired("test") {
+		t.Error("MissingRequired() for a network that has its values")
+	}
+	if !extractor.MissingRequired("regtest") {
+		t.Error("MissingRequired() = false for a network without a params class")
+	}
+}
+
+func TestExtractorReport(t *testing.T) {
+	extractor := NewExtractor(mapSource{"chainparams.cpp": testChainParams})
+	extractor.Network = Networks[0]
+
+	extractor.LoadPort()
+	extractor.LoadMagicMessage()
+	if seeds := extractor.LoadDNSSeeds(); strings.Join(seeds, ",") != "dnsseed.dash.org,dnsseed.dashdot.io" {
+		t.Errorf("LoadDNSSeeds() = %v", seeds)
+	}
+
+	var report bytes.Buffer
+	extractor.WriteReport(&report)
+
+	lines := strings.Split(report.String(), "\n")
+	if fields := strings.Fields(lines[1]); len(fields) < 4 || fields[0] != "main" || fields[1] != "port" ||
+		fields[2] != `"9999"` || fields[3] != "src/chainparams.cpp" {
+		t.Errorf("the port's row is %q", lines[1])
+	}
+	for _, want := range []string{
+		"error: magic_message (all) not found: unable to read validation.cpp: validation.cpp not found; " +
+			"unable to read main.cpp: main.cpp not found",
+	} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("the report doesn't contain %q:\n%s", want, report.String())
+		}
+	}
+	if !extractor.MissingRequired("main") {
+		t.Error("MissingRequired() = false without a magic message")
+	}
+}
+
+func TestExtractorParseUint(t *testing.T) {
+	extractor := NewExtractor(mapSource{"chainparams.cpp": testChainParams})
+	extractor.Network = Networks[0]
+
+	if port := extractor.parseUint("port", extractor.LoadPort()); port != 9999 {
+		t.Errorf("parseUint() = %d, want 9999", port)
+	}
+	if port := extractor.parseUint("port", "99999999999"); port != 0 {
+		t.Errorf("parseUint() of an out of range value = %d", port)
+	}
+	if !extractor.MissingRequired("main") {
+		t.Error("a value that isn't a number still counts as found")
+	}
+
+	if interval := extractor.parseOptionalUint("ping_interval", ""); interval != nil {
+		t.Errorf("parseOptionalUint() of an empty value = %d, want unset", *interval)
+	}
+	if interval := extractor.parseOptionalUint("ping_interval", "0"); interval == nil || *interval != 0 {
+		t.Errorf("parseOptionalUint(\"0\") = %v, want 0", interval)
+	}
+	if interval := extractor.parseOptionalUint("ping_interval", "ten"); interval != nil {
+		t.Errorf("parseOptionalUint(\"ten\") = %d, want unset", *interval)
+	}
+}
@@ -6,7 +6,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
-	"net/http"
+	"net"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -15,206 +16,437 @@ import (
 
 var coinName string
 var gitUrl string
-var gitParts []string
+var srcDir string
 var explorer string
 
 //SIMPLE UTILITY TO GENERATE A COINCONF FOR A GIVEN COIN.
 func main() {
-	coinConf := phantom.CoinConf{}
-
 	flag.StringVar(&coinName, "coin_name", "", "the name of the coin")
 	flag.StringVar(&gitUrl, "git_hub", "", "the git url")
+	flag.StringVar(&srcDir, "src", "", "a local checkout of the coin (or its src directory) to read instead of GitHub")
 	flag.StringVar(&explorer, "explorer", "", "the bootstrap explorer")
 
 	flag.Parse()
 
-	gitParts = strings.Split(gitUrl, "/")
+	if coinName == "" {
+		log.Fatal("-coin_name is required.")
+	}
 
-	if len(gitParts) == 5 {
-		gitParts = append(gitParts, "tree")
-		gitParts = append(gitParts, "master")
+	var source Source
+	var err error
+	switch {
+	case srcDir != "":
+		source, err = NewLocalSource(srcDir)
+	case gitUrl != "":
+		source, err = NewGitHubSource(gitUrl)
+	default:
+		log.Fatal("Either -src or -git_hub is required.")
+	}
+	if err != nil {
+		log.Fatalf("Unable to read the coin sources: %s", err)
 	}
 
-	name := strings.ToUpper(coinName)
-	magicBytes := LoadMagicBytes()
-	port := LoadPort()
-	magicMessage := LoadMagicMessage()
-	protocolVersion := LoadProtocolVersion()
-	sentinelVersion := LoadSentinelVersion()
-	daemonVersion := LoadDaemonVersion()
+	extractor := NewExtractor(source)
 
-	coinConf.Name = name
+	//shared by every network
+	name := strings.ToUpper(coinName)
+	magicMessage := extractor.LoadMagicMessage()
+	protocolVersion := extractor.parseUint("protocol_number", extractor.LoadProtocolVersion())
+	sentinelVersion := extractor.LoadSentinelVersion()
+	daemonVersion := extractor.LoadDaemonVersion()
+	hashDepth := extractor.parseOptionalUint("hash_depth", extractor.LoadHashDepth())
+	sigTimeOffset := extractor.parseOptionalUint("sigtime_offset", extractor.LoadSigTimeOffset())
+	messageProfile := extractor.LoadMessageProfile()
+
+	var coinConfs []phantom.CoinConf
+	var suffixes []string
+
+	for _, network := range Networks {
+		extractor.Network = network
+		if network.Name != "main" && !extractor.HasNetwork() {
+			continue
+		}
+
+		coinConf := phantom.CoinConf{}
+
+		coinConf.Name = name
+		if network.Name != "main" {
+			coinConf.Network = network.Name
+		}
+
+		if explorer != "" && network.Name == "main" {
+			coinConf.BootstrapURL = explorer
+		}
+
+		coinConf.Magicbytes = extractor.LoadMagicBytes()
+		coinConf.MagicMessage = magicMessage
+		coinConf.MagicMessageNewline = true
+
+		coinConf.Port = extractor.parseUint("port", extractor.LoadPort())
+		coinConf.ProtocolNumber = protocolVersion
+		coinConf.SentinelVersion = sentinelVersion
+		coinConf.DaemonVersion = daemonVersion
+		coinConf.PingInterval = extractor.parseOptionalUint("ping_interval", extractor.LoadPingInterval())
+		coinConf.SigTimeOffset = sigTimeOffset
+		coinConf.HashDepth = hashDepth
+		coinConf.MessageProfile = messageProfile
+		coinConf.SporkPubKey = extractor.LoadSporkPubKey()
+		coinConf.MinProtocol = extractor.parseUint("min_protocol", extractor.LoadMinProtocol())
+		coinConf.DNSSeeds = strings.Join(extractor.LoadDNSSeeds(), ",")
+		coinConf.BootstrapIPs = strings.Join(extractor.LoadFixedSeeds(), ",")
+
+		coinConfs = append(coinConfs, coinConf)
+		suffixes = append(suffixes, network.Suffix)
+	}
 
-	if explorer != "" {
-		coinConf.BootstrapURL = explorer
+	extractor.WriteReport(os.Stderr)
+
+	for i, coinConf := range coinConfs {
+		network := coinConf.Network
+		if network == "" {
+			network = "main"
+		}
+
+		if extractor.MissingRequired(network) {
+			if network == "main" {
+				log.Fatal("Required values are missing, no coin configuration written.")
+			}
+			log.Printf("Required %s values are missing, skipping its coin configuration.", network)
+			continue
+		}
+
+		err := coinConf.Validate()
+		if err != nil {
+			if network == "main" {
+				log.Fatalf("The extracted coin configuration is invalid: %s", err)
+			}
+			log.Printf("The extracted %s coin configuration is invalid, skipping it: %s", network, err)
+			continue
+		}
+
+		coinConfJson, err := json.Marshal(coinConf)
+		if err != nil {
+			log.Fatalf("Error building json: %s", err)
+		}
+
+		err = ioutil.WriteFile(strings.ToLower(coinName) + suffixes[i] + ".json", coinConfJson, 0644)
+		if err != nil {
+			fmt.Println(err)
+		}
 	}
+}
 
-	coinConf.Magicbytes = magicBytes
-	coinConf.MagicMessage = magicMessage
-	coinConf.MagicMessageNewline = true
+// parseUint parses an extracted number, an empty value is left unset.
+func parseUint(value string) (uint, error) {
+	if value == "" {
+		return 0, nil
+	}
 
-	parsedPort, err := strconv.Atoi(port)
+	parsed, err := strconv.ParseUint(value, 10, 32)
 	if err != nil {
-		log.Fatal("Error parsing port")
+		return 0, fmt.Errorf("%q isn't a number", value)
 	}
-	coinConf.Port = uint(parsedPort)
+	return uint(parsed), nil
+}
 
-	parsedProtocl, err := strconv.Atoi(protocolVersion)
+// parseUint parses the value found for field, one that isn't a number is
+// recorded against the field and left unset.
+func (e *Extractor) parseUint(field string, value string) uint {
+	parsed, err := parseUint(value)
 	if err != nil {
-		log.Fatal("Error parsing protocol version")
+		e.fail(field, err.Error())
 	}
-	coinConf.ProtocolNumber = uint(parsedProtocl)
+	return parsed
+}
 
-	if sentinelVersion != "" {
-		coinConf.SentinelVersion = ConvertVersionHexToString(sentinelVersion)
+// parseOptionalUint parses the value found for a field that may be 0, an
+// empty value is left unset so the phantom's default applies.
+func (e *Extractor) parseOptionalUint(field string, value string) *uint {
+	parsed, err := parseUint(value)
+	if err != nil {
+		e.fail(field, err.Error())
 	}
-
-	if daemonVersion != "" {
-		coinConf.DaemonVersion = ConvertVersionHexToString(daemonVersion)
+	if value == "" || err != nil {
+		return nil
 	}
+	return phantom.UintSetting(parsed)
+}
 
-	coinConfJson, err := json.Marshal(coinConf)
-	if err != nil {
-		log.Fatal("Error building json")
+func (e *Extractor) LoadMagicBytes() string {
+	match := e.find("magicbytes", true,
+		lookup{"chainparams.cpp", `pchMessageStart\[0\] = 0x(..);\s*pchMessageStart\[1\] = 0x(..);\s*` +
+			`pchMessageStart\[2\] = 0x(..);\s*pchMessageStart\[3\] = 0x(..);`, true})
+	if match == nil {
+		return ""
 	}
 
-	err = ioutil.WriteFile(strings.ToLower(coinName) + ".json", coinConfJson, 0644)
-	if err != nil {
-		fmt.Println(err)
+	return e.found("magicbytes", strings.ToUpper(match[4] + match[3] + match[2] + match[1]))
+}
+
+func (e *Extractor) LoadPort() string {
+	match := e.find("port", true, lookup{"chainparams.cpp", `nDefaultPort = (\d+);`, true})
+	if match == nil {
+		return ""
 	}
+
+	return e.found("port", match[1])
 }
 
-func LoadMagicBytes() string {
-	data, err := LoadFile(UrlForFile("chainparams.cpp"))
-	if err != nil {
-		log.Fatal()
+func (e *Extractor) LoadMagicMessage() string {
+	pattern := `const .*string strMessageMagic = "(.*)\\n";`
+	match := e.find("magic_message", true, lookup{"validation.cpp", pattern, false},
+		lookup{"main.cpp", pattern, false})
+	if match == nil {
+		return ""
 	}
 
-	re := regexp.MustCompile(`pchMessageStart\[\d\] = 0x(..);`)
-	result := re.FindAllStringSubmatch(data, 4)
+	return e.found("magic_message", match[1])
+}
 
-	return strings.ToUpper(result[3][1] + result[2][1] + result[1][1] + result[0][1])
+func (e *Extractor) LoadProtocolVersion() string {
+	match := e.find("protocol_number", true, lookup{"version.h", `static const int PROTOCOL_VERSION = (\d+)`, false})
+	if match == nil {
+		return ""
+	}
+
+	return e.found("protocol_number", match[1])
 }
 
-func LoadPort() string {
-	data, err := LoadFile(UrlForFile("chainparams.cpp"))
-	if err != nil {
-		log.Fatal()
+// LoadSentinelVersion returns the dotted sentinel version, masternode.h
+// packs it a byte per part (0x010001) while clientversion.h keeps it in the
+// decimal client version form (1000100).
+func (e *Extractor) LoadSentinelVersion() string {
+	match := e.find("sentinel_version", false,
+		lookup{"masternode.h", `#define MIN_SENTINEL_VERSION 0x([0-9a-fA-F]+)`, false},
+		lookup{"masternode.h", `#define DEFAULT_SENTINEL_VERSION 0x([0-9a-fA-F]+)`, false},
+		lookup{"clientversion.h", `#define CLIENT_SENTINEL_VERSION (\d+)`, false})
+	if match == nil {
+		return ""
 	}
 
-	re := regexp.MustCompile(`nDefaultPort = (\d+);`)
-	return re.FindStringSubmatch(data)[1]
+	return e.version("sentinel_version", match)
 }
 
-func LoadMagicMessage() string {
-	data, err := LoadFile(UrlForFile("validation.cpp"))
-	if err != nil {
-		log.Fatal()
+func (e *Extractor) LoadDaemonVersion() string {
+	match := e.find("daemon_version", false,
+		lookup{"clientversion.h", `#define CLIENT_MASTERNODE_VERSION (\d+)`, false})
+	if match == nil {
+		return ""
 	}
 
-	re := regexp.MustCompile(`const .*string strMessageMagic = "(.*)\\n";`)
-	matches := re.FindStringSubmatch(data)
+	return e.version("daemon_version", match)
+}
 
-	if len(matches) > 0 {
-		return matches[1]
+// version converts a matched version to its dotted form, hex ones are told
+// apart by their 0x prefix.
+func (e *Extractor) version(field string, match []string) string {
+	convert := ConvertVersionIntToString
+	if strings.Contains(match[0], "0x") {
+		convert = ConvertVersionHexToString
 	}
 
-	data, err = LoadFile(UrlForFile("main.cpp"))
+	version, err := convert(match[1])
 	if err != nil {
-		log.Fatal()
+		return e.fail(field, err.Error())
 	}
+	return e.found(field, version)
+}
 
-	matches = re.FindStringSubmatch(data)
-	if len(matches) > 0 {
-		return matches[1]
+func (e *Extractor) LoadPingInterval() string {
+	//forks that shorten it off mainnet keep it in the params classes
+	//PIVX: #define MASTERNODE_MIN_MNP_SECONDS (10 * 60)
+	//Dash: static const int MASTERNODE_MIN_MNP_SECONDS = 10 * 60;
+	match := e.find("ping_interval", false,
+		lookup{"chainparams.cpp", `\w*MinMnpSeconds\w*\s*=\s*\(?([\d\s\*]+)\)?;`, true},
+		lookup{"masternode.h", `MASTERNODE_MIN_MNP_SECONDS\s*=?\s*\(?([\d\s\*]+)\)?`, false})
+	if match == nil {
+		return ""
 	}
 
-	log.Fatal("No magic message found.")
-	return ""
+	return e.found("ping_interval", EvaluateProduct(match[1]))
 }
 
-func LoadProtocolVersion() string {
-	data, err := LoadFile(UrlForFile("version.h"))
-	if err != nil {
-		log.Fatal()
+func (e *Extractor) LoadHashDepth() string {
+	//most forks set the ping block hash in the CMasternodePing constructor
+	pattern := `chainActive\.Height\(\) - (\d+)\]`
+	match := e.find("hash_depth", false, lookup{"masternode.h", pattern, false},
+		lookup{"masternode.cpp", pattern, false})
+	if match == nil {
+		return ""
 	}
 
-	re := regexp.MustCompile(`static const int PROTOCOL_VERSION = (\d+)`)
-	return re.FindStringSubmatch(data)[1]
+	return e.found("hash_depth", match[1])
 }
 
-func LoadSentinelVersion() string {
-	data, err := LoadFile(UrlForFile("masternode.h"))
-	if err != nil {
-		log.Fatal()
+func (e *Extractor) LoadSigTimeOffset() string {
+	//forks that sign their own pings ahead of time do it in the CMasternodePing
+	//constructor, Dash and PIVX use GetAdjustedTime() as is and keep the default
+	pattern := `sigTime = GetAdjustedTime\(\) \+ \(?([\d\s\*]+)\)?;`
+	match := e.find("sigtime_offset", false, lookup{"masternode.h", pattern, false},
+		lookup{"masternode.cpp", pattern, false})
+	if match == nil {
+		return ""
 	}
 
-	re := regexp.MustCompile(`#define MIN_SENTINEL_VERSION 0x(\d+)`)
-	matches := re.FindStringSubmatch(data)
-	if len(matches) > 0 {
-		return matches[1]
+	return e.found("sigtime_offset", EvaluateProduct(match[1]))
+}
+
+// LoadMessageProfile guesses the masternode message layout from the fields
+// the fork serializes.
+func (e *Extractor) LoadMessageProfile() string {
+	match := e.find("message_profile", false,
+		lookup{"masternode.h", `READWRITE\((masternodeOutpoint)\)`, false},
+		lookup{"masternode.h", `READWRITE\((fSentinelIsCurrent)\)`, false},
+		lookup{"masternode.h", `READWRITE\((nLastDsq)\)`, false})
+	if match == nil {
+		return ""
 	}
 
-	re = regexp.MustCompile(`#define DEFAULT_SENTINEL_VERSION 0x(\d+)`)
-	matches = re.FindStringSubmatch(data)
-	if len(matches) > 0 {
-		return matches[1]
+	switch match[1] {
+	case "masternodeOutpoint":
+		return e.found("message_profile", "dash-12.2")
+	case "fSentinelIsCurrent":
+		return e.found("message_profile", "dash-12.1")
 	}
 
-	data, err = LoadFile(UrlForFile("clientversion.h"))
-	matches = re.FindStringSubmatch(data)
-	if err != nil {
-		log.Fatal()
+	data, _ := e.read("masternode.h")
+	if strings.Contains(data, "PIVX") {
+		return e.found("message_profile", "pivx")
 	}
+	return e.found("message_profile", "dash-12.0")
+}
 
-	re = regexp.MustCompile(`#define CLIENT_SENTINEL_VERSION (\d+)`)
-	if len(matches) > 0 {
-		return matches[1]
+func (e *Extractor) LoadSporkPubKey() string {
+	match := e.find("spork_pubkey", false,
+		lookup{"chainparams.cpp", `strSporkPubKey\s*=\s*"([0-9a-fA-F]+)"`, true},
+		lookup{"chainparams.cpp", `strSporkKey\s*=\s*"([0-9a-fA-F]+)"`, true})
+	if match == nil {
+		return ""
 	}
 
-	return ""
+	return e.found("spork_pubkey", match[1])
 }
 
-func LoadDaemonVersion() string {
-	data, err := LoadFile(UrlForFile("clientversion.h"))
-	if err != nil {
-		log.Fatal()
+// LoadMinProtocol returns the oldest protocol masternodes may run and still
+// be paid, which is what ranks them.
+func (e *Extractor) LoadMinProtocol() string {
+	match := e.find("min_protocol", false,
+		lookup{"chainparams.cpp", `\w*MinMasternodeProto\w*\s*=\s*(\d+);`, true},
+		lookup{"masternode-payments.h", `MIN_MASTERNODE_PAYMENT_PROTO_VERSION_2\s*=\s*(\d+);`, false},
+		lookup{"masternode-payments.h", `MIN_MASTERNODE_PAYMENT_PROTO_VERSION(?:_1)?\s*=\s*(\d+);`, false})
+	if match == nil {
+		return ""
 	}
 
-	re := regexp.MustCompile(`#define CLIENT_MASTERNODE_VERSION (\d+)`)
-	matches := re.FindStringSubmatch(data)
-	if len(matches) > 0 {
-		return matches[1]
+	return e.found("min_protocol", match[1])
+}
+
+// LoadDNSSeeds returns the seed hosts, the last quoted argument of
+// vSeeds.push_back(CDNSSeedData("name", "host")) or vSeeds.emplace_back("host").
+func (e *Extractor) LoadDNSSeeds() []string {
+	matches := e.findAll("dns_seeds", false,
+		lookup{"chainparams.cpp", `vSeeds\.(?:push_back|emplace_back)\((.*)\);`, true})
+
+	quoted := regexp.MustCompile(`"([^"]+)"`)
+
+	var seeds []string
+	for _, match := range matches {
+		arguments := quoted.FindAllStringSubmatch(match[1], -1)
+		if len(arguments) > 0 {
+			seeds = append(seeds, arguments[len(arguments)-1][1])
+		}
 	}
 
-	return ""
+	e.found("dns_seeds", strings.Join(seeds, ","))
+	return seeds
 }
 
-func LoadFile(url string) (string, error) {
-	response, err := http.Get(url)
-	if err != nil {
-		log.Printf("%s", err)
-		return "", err
+// LoadFixedSeeds returns the IPv4 fixed seeds, the array vFixedSeeds is
+// built from is read out of chainparamsseeds.h.
+func (e *Extractor) LoadFixedSeeds() []string {
+	match := e.find("bootstrap_ips", false,
+		lookup{"chainparams.cpp", `vFixedSeeds = std::vector<SeedSpec6>\((\w+),`, true})
+	if match == nil {
+		return nil
 	}
-	defer response.Body.Close()
-	bytes, err := ioutil.ReadAll(response.Body)
+
+	data, err := e.read("chainparamsseeds.h")
 	if err != nil {
-		log.Printf("%s", err)
-		return "", err
+		e.fail("bootstrap_ips", fmt.Sprintf("unable to read chainparamsseeds.h: %s", err))
+		return nil
 	}
 
-	return string(bytes), err
+	array := regexp.MustCompile(`(?s)` + match[1] + `\[\]\s*=\s*\{(.*?)\};`).FindStringSubmatch(data)
+	if array == nil {
+		e.fail("bootstrap_ips", fmt.Sprintf("%s not found in chainparamsseeds.h", match[1]))
+		return nil
+	}
+
+	var seeds []string
+	entry := regexp.MustCompile(`\{\{([^}]*)\},\s*(\d+)\}`)
+	for _, seed := range entry.FindAllStringSubmatch(array[1], -1) {
+		var ip net.IP
+		for _, octet := range strings.Split(seed[1], ",") {
+			value, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(octet), "0x"), 16, 8)
+			if err != nil {
+				break
+			}
+			ip = append(ip, byte(value))
+		}
+
+		//the phantom only connects to IPv4 peers
+		if len(ip) == net.IPv6len && ip.To4() != nil {
+			seeds = append(seeds, net.JoinHostPort(ip.To4().String(), seed[2]))
+		}
+	}
+
+	e.found("bootstrap_ips", strings.Join(seeds, ","))
+	return seeds
 }
 
-func UrlForFile(file string) string {
-	url := "https://raw.githubusercontent.com/" + gitParts[3] + "/" + gitParts[4] + "/" + gitParts[6] + "/src/" + file
-	return url
+//turns a C expression such as "10 * 60" into "600"
+func EvaluateProduct(expr string) string {
+	result := 1
+	for _, factor := range strings.Split(expr, "*") {
+		value, err := strconv.Atoi(strings.TrimSpace(factor))
+		if err != nil {
+			return ""
+		}
+		result *= value
+	}
+	return strconv.Itoa(result)
 }
 
-func ConvertVersionHexToString(str string) string {
-	result := ""
-	for i := 0; i<len(str); i += 2 {
-		result += str[i:i+2]
+// ConvertVersionHexToString turns a packed version such as 010001 into the
+// dotted form the coin conf expects (1.0.1), one byte per part.
+func ConvertVersionHexToString(str string) (string, error) {
+	if len(str)%2 != 0 {
+		str = "0" + str
+	}
+
+	var parts []string
+	for i := 0; i < len(str); i += 2 {
+		part, err := strconv.ParseUint(str[i:i+2], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid hex version %q", str)
+		}
+		parts = append(parts, strconv.FormatUint(part, 10))
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// ConvertVersionIntToString turns a client version such as 1000100, built as
+// major*1000000 + minor*10000 + revision*100 + build, into its dotted form
+// (1.0.1). The build is only kept when it isn't 0.
+func ConvertVersionIntToString(str string) (string, error) {
+	version, err := strconv.ParseUint(str, 10, 32)
+	if err != nil {
+		return "", fmt.Errorf("invalid client version %q", str)
+	}
+
+	dotted := fmt.Sprintf("%d.%d.%d", version/1000000, version/10000%100, version/100%100)
+	if build := version % 100; build != 0 {
+		dotted += fmt.Sprintf(".%d", build)
 	}
-	return result
+	return dotted, nil
 }
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestConvertVersionHexToString(t *testing.T) {
+	tests := []struct {
+		hex     string
+		version string
+		valid   bool
+	}{
+		{"010001", "1.0.1", true},
+		{"10001", "1.0.1", true},
+		{"0a0b0c", "10.11.12", true},
+		{"FF00", "255.0", true},
+		{"01zz01", "", false},
+	}
+
+	for _, test := range tests {
+		version, err := ConvertVersionHexToString(test.hex)
+		if (err == nil) != test.valid || version != test.version {
+			t.Errorf("%s: ConvertVersionHexToString() = %q, %v, want %q", test.hex, version, err, test.version)
+		}
+	}
+}
+
+func TestConvertVersionIntToString(t *testing.T) {
+	tests := []struct {
+		client  string
+		version string
+		valid   bool
+	}{
+		{"1000100", "1.0.1", true},
+		{"1000000", "1.0.0", true},
+		{"120203", "0.12.2.3", true},
+		{"1020001", "1.2.0.1", true},
+		{"0x010001", "", false},
+		{"", "", false},
+	}
+
+	for _, test := range tests {
+		version, err := ConvertVersionIntToString(test.client)
+		if (err == nil) != test.valid || version != test.version {
+			t.Errorf("%q: ConvertVersionIntToString() = %q, %v, want %q", test.client, version, err, test.version)
+		}
+	}
+}
+
+func TestLoadVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    mapSource
+		sentinel string
+		daemon   string
+	}{
+		{
+			name:     "hex sentinel",
+			files:    mapSource{"masternode.h": "#define MIN_SENTINEL_VERSION 0x010001\n"},
+			sentinel: "1.0.1",
+		},
+		{
+			name:     "hex sentinel with letters",
+			files:    mapSource{"masternode.h": "#define DEFAULT_SENTINEL_VERSION 0x01000a\n"},
+			sentinel: "1.0.10",
+		},
+		{
+			name: "client versions",
+			files: mapSource{"clientversion.h": "#define CLIENT_SENTINEL_VERSION 1000100\n" +
+				"#define CLIENT_MASTERNODE_VERSION 120203\n"},
+			sentinel: "1.0.1",
+			daemon:   "0.12.2.3",
+		},
+		{
+			name:  "none",
+			files: mapSource{},
+		},
+	}
+
+	for _, test := range tests {
+		extractor := NewExtractor(test.files)
+		extractor.Network = Networks[0]
+
+		if sentinel := extractor.LoadSentinelVersion(); sentinel != test.sentinel {
+			t.Errorf("%s: LoadSentinelVersion() = %q, want %q", test.name, sentinel, test.sentinel)
+		}
+		if daemon := extractor.LoadDaemonVersion(); daemon != test.daemon {
+			t.Errorf("%s: LoadDaemonVersion() = %q, want %q", test.name, daemon, test.daemon)
+		}
+	}
+}
+
+func TestLoadSharedValues(t *testing.T) {
+	extractor := NewExtractor(mapSource{
+		"validation.cpp": `const std::string strMessageMagic = "DarkCoin Signed Message:\n";`,
+		"version.h":      "static const int PROTOCOL_VERSION = 70208;",
+		"masternode.h": "static const int MASTERNODE_MIN_MNP_SECONDS = 10 * 60;\n" +
+			"sigTime = GetAdjustedTime() + 2 * 60;\n" +
+			"blockHash = chainActive[chainActive.Height() - 12]\n" +
+			"READWRITE(masternodeOutpoint);\n",
+		"masternode-payments.h": "static const int MIN_MASTERNODE_PAYMENT_PROTO_VERSION_2 = 70208;",
+	})
+	extractor.Network = Networks[0]
+
+	tests := []struct {
+		name  string
+		load  func() string
+		value string
+	}{
+		{"magic message", extractor.LoadMagicMessage, "DarkCoin Signed Message:"},
+		{"protocol version", extractor.LoadProtocolVersion, "70208"},
+		{"ping interval", extractor.LoadPingInterval, "600"},
+		{"sigtime offset", extractor.LoadSigTimeOffset, "120"},
+		{"hash depth", extractor.LoadHashDepth, "12"},
+		{"message profile", extractor.LoadMessageProfile, "dash-12.2"},
+		{"min protocol", extractor.LoadMinProtocol, "70208"},
+	}
+
+	for _, test := range tests {
+		if value := test.load(); value != test.value {
+			t.Errorf("%s: loaded %q, want %q", test.name, value, test.value)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Source reads the files of a coin's src directory.
+type Source interface {
+	ReadFile(name string) (string, error)
+
+	// Describe returns where a file is read from, for the report.
+	Describe(name string) string
+}
+
+// LocalSource reads files from a source checkout on disk.
+type LocalSource struct {
+	Dir string
+
+	paths map[string]string
+}
+
+// NewLocalSource accepts either the checkout's root or its src directory.
+func NewLocalSource(dir string) (*LocalSource, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "src", "chainparams.cpp")); err == nil {
+		dir = filepath.Join(dir, "src")
+	}
+
+	return &LocalSource{Dir: dir, paths: make(map[string]string)}, nil
+}
+
+func (source *LocalSource) ReadFile(name string) (string, error) {
+	path, err := source.locate(name)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (source *LocalSource) Describe(name string) string {
+	if path, err := source.locate(name); err == nil {
+		return path
+	}
+	return filepath.Join(source.Dir, name)
+}
+
+// locate finds a file in src, or in a subdirectory of it since newer forks
+// moved the masternode code (e.g. src/masternode/masternode.h).
+func (source *LocalSource) locate(name string) (string, error) {
+	if path, ok := source.paths[name]; ok {
+		return path, nil
+	}
+
+	path := filepath.Join(source.Dir, name)
+	if _, err := os.Stat(path); err != nil {
+		path = ""
+
+		errFound := errors.New("found")
+		filepath.Walk(source.Dir, func(candidate string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info.IsDir() && (info.Name() == "test" || info.Name() == "qt" || strings.HasPrefix(info.Name(), ".")) &&
+				candidate != source.Dir {
+				return filepath.SkipDir
+			}
+			if !info.IsDir() && info.Name() == name {
+				path = candidate
+				return errFound
+			}
+			return nil
+		})
+
+		if path == "" {
+			return "", fmt.Errorf("%s not found under %s", name, source.Dir)
+		}
+	}
+
+	source.paths[name] = path
+	return path, nil
+}
+
+// GitHubSource downloads files from raw.githubusercontent.com.
+type GitHubSource struct {
+	Owner  string
+	Repo   string
+	Branch string
+}
+
+// NewGitHubSource parses a repository url such as
+// https://github.com/owner/repo or https://github.com/owner/repo/tree/branch.
+func NewGitHubSource(repoURL string) (*GitHubSource, error) {
+	parsed, err := url.Parse(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Host != "github.com" && parsed.Host != "www.github.com" {
+		return nil, fmt.Errorf("%s is not a github.com url", repoURL)
+	}
+
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	source := &GitHubSource{Branch: "master"}
+
+	switch {
+	case len(parts) == 2:
+	case len(parts) >= 4 && parts[2] == "tree":
+		source.Branch = strings.Join(parts[3:], "/")
+	default:
+		return nil, fmt.Errorf("%s is not a repository url (https://github.com/owner/repo[/tree/branch])", repoURL)
+	}
+
+	source.Owner = parts[0]
+	source.Repo = strings.TrimSuffix(parts[1], ".git")
+	return source, nil
+}
+
+func (source *GitHubSource) ReadFile(name string) (string, error) {
+	response, err := http.Get(source.Describe(name))
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", source.Describe(name), response.Status)
+	}
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func (source *GitHubSource) Describe(name string) string {
+	return "https://raw.githubusercontent.com/" + source.Owner + "/" + source.Repo + "/" + source.Branch + "/src/" + name
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testCheckout writes files, relative paths to their contents, under a
+// temporary directory. The returned function removes it.
+func testCheckout(t *testing.T, files map[string]string) (string, func()) {
+	dir, err := ioutil.TempDir("", "coinconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+	}
+	return dir, cleanup
+}
+
+func TestNewLocalSource(t *testing.T) {
+	dir, cleanup := testCheckout(t, map[string]string{
+		"src/chainparams.cpp": "",
+		"README.md":           "",
+	})
+	defer cleanup()
+
+	for _, path := range []string{dir, filepath.Join(dir, "src")} {
+		source, err := NewLocalSource(path)
+		if err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+		if source.Dir != filepath.Join(dir, "src") {
+			t.Errorf("%s: reads from %s, want its src directory", path, source.Dir)
+		}
+	}
+
+	if _, err := NewLocalSource(filepath.Join(dir, "README.md")); err == nil {
+		t.Error("NewLocalSource() of a file succeeded")
+	}
+	if _, err := NewLocalSource(filepath.Join(dir, "missing")); err == nil {
+		t.Error("NewLocalSource() of a missing directory succeeded")
+	}
+}
+
+func TestLocalSourceLocate(t *testing.T) {
+	dir, cleanup := testCheckout(t, map[string]string{
+		"src/chainparams.cpp":                  "chainparams",
+		"src/masternode/masternode.h":          "masternode",
+		"src/test/version.h":                   "test",
+		"src/qt/clientversion.h":               "qt",
+		"src/.git/validation.cpp":              "git",
+		"src/evo/deep/spork.h":                 "spork",
+		"src/masternode-payments.h":            "top",
+		"src/masternode/masternode-payments.h": "nested",
+	})
+	defer cleanup()
+
+	source, err := NewLocalSource(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		path string
+	}{
+		{"chainparams.cpp", "chainparams", "chainparams.cpp"},
+		{"masternode.h", "masternode", "masternode/masternode.h"},
+		{"spork.h", "spork", "evo/deep/spork.h"},
+		{"masternode-payments.h", "top", "masternode-payments.h"},
+		{"version.h", "", ""},
+		{"clientversion.h", "", ""},
+		{"validation.cpp", "", ""},
+	}
+
+	for _, test := range tests {
+		data, err := source.ReadFile(test.name)
+		if test.path == "" {
+			if err == nil || !strings.Contains(err.Error(), test.name+" not found under ") {
+				t.Errorf("%s: ReadFile() = %q, %v, want it skipped", test.name, data, err)
+			}
+			continue
+		}
+
+		if err != nil || data != test.data {
+			t.Errorf("%s: ReadFile() = %q, %v, want %q", test.name, data, err, test.data)
+		}
+		if want := filepath.Join(dir, "src", filepath.FromSlash(test.path)); source.Describe(test.name) != want {
+			t.Errorf("%s: Describe() = %s, want %s", test.name, source.Describe(test.name), want)
+		}
+	}
+
+	//the located path is kept
+	if err := os.Remove(filepath.Join(dir, "src", "masternode", "masternode.h")); err != nil {
+		t.Fatal(err)
+	}
+	if path := source.Describe("masternode.h"); path != filepath.Join(dir, "src", "masternode", "masternode.h") {
+		t.Errorf("Describe() after the walk = %s", path)
+	}
+}
+
+func TestNewGitHubSource(t *testing.T) {
+	tests := []struct {
+		url      string
+		valid    bool
+		describe string
+	}{
+		{"https://github.com/dashpay/dash", true,
+			"https://raw.githubusercontent.com/dashpay/dash/master/src/chainparams.cpp"},
+		{"https://github.com/dashpay/dash.git", true,
+			"https://raw.githubusercontent.com/dashpay/dash/master/src/chainparams.cpp"},
+		{"https://github.com/dashpay/dash/tree/v0.12.3.x", true,
+			"https://raw.githubusercontent.com/dashpay/dash/v0.12.3.x/src/chainparams.cpp"},
+		{"https://github.com/dashpay/dash/tree/release/1.0", true,
+			"https://raw.githubusercontent.com/dashpay/dash/release/1.0/src/chainparams.cpp"},
+		{"https://gitlab.com/dashpay/dash", false, ""},
+		{"https://github.com/dashpay", false, ""},
+	}
+
+	for _, test := range tests {
+		source, err := NewGitHubSource(test.url)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: NewGitHubSource() error %v", test.url, err)
+			continue
+		}
+		if err == nil && source.Describe("chainparams.cpp") != test.describe {
+			t.Errorf("%s: Describe() = %s, want %s", test.url, source.Describe("chainparams.cpp"), test.describe)
+		}
+	}
+}
+
+func TestLoadFixedSeeds(t *testing.T) {
+	chainParams := `
+class CMainParams : public CChainParams {
+    CMainParams() {
+        vFixedSeeds = std::vector<SeedSpec6>(pnSeed6_main, pnSeed6_main + ARRAYLEN(pnSeed6_main));
+    }
+};
+class CTestNetParams : public CChainParams {
+    CTestNetParams() {
+        vFixedSeeds = std::vector<SeedSpec6>(pnSeed6_test, pnSeed6_test + ARRAYLEN(pnSeed6_test));
+    }
+};
+class CRegTestParams : public CChainParams {
+    CRegTestParams() {
+        vFixedSeeds.clear();
+    }
+};
+`
+	seeds := `
+static SeedSpec6 pnSeed6_main[] = {
+    {{0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0xff,0xff,0x2d,0x32,0x16,0x7d}, 9999},
+    {{0x20,0x01,0x0d,0xb8,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x01}, 9999},
+    {{0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0xff,0xff,0x05,0x09,0x0e,0x4b}, 9998}
+};
+
+static SeedSpec6 pnSeed6_test[] = {
+    {{0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0xff,0xff,0x7f,0x00,0x00,0x01}, 19999}
+};
+`
+
+	tests := []struct {
+		name  string
+		files mapSource
+		want  []string
+	}{
+		{"main", mapSource{"chainparams.cpp": chainParams, "chainparamsseeds.h": seeds},
+			[]string{"45.50.22.125:9999", "5.9.14.75:9998"}},
+		{"test", mapSource{"chainparams.cpp": chainParams, "chainparamsseeds.h": seeds},
+			[]string{"127.0.0.1:19999"}},
+		{"regtest", mapSource{"chainparams.cpp": chainParams, "chainparamsseeds.h": seeds}, nil},
+		{"main", mapSource{"chainparams.cpp": chainParams}, nil},
+		{"main", mapSource{"chainparams.cpp": chainParams, "chainparamsseeds.h": "static SeedSpec6 other[] = {};"},
+			nil},
+	}
+
+	for i, test := range tests {
+		extractor := NewExtractor(test.files)
+		for _, network := range Networks {
+			if network.Name == test.name {
+				extractor.Network = network
+			}
+		}
+
+		found := extractor.LoadFixedSeeds()
+		if strings.Join(found, ",") != strings.Join(test.want, ",") {
+			t.Errorf("%d %s: LoadFixedSeeds() = %v, want %v", i, test.name, found, test.want)
+		}
+
+		finding := extractor.Findings[len(extractor.Findings)-1]
+		if len(test.want) == 0 && len(finding.Problems) == 0 {
+			t.Errorf("%d %s: no problem was recorded", i, test.name)
+		}
+	}
+}
+
+func TestEvaluateProduct(t *testing.T) {
+	tests := []struct {
+		expr  string
+		value string
+	}{
+		{"600", "600"},
+		{"10 * 60", "600"},
+		{" 2*60 ", "120"},
+		{"24 * 60 * 60", "86400"},
+		{"10 * 60 ", "600"},
+		{"", ""},
+		{"10 * ", ""},
+		{"MINUTE * 10", ""},
+	}
+
+	for _, test := range tests {
+		if value := EvaluateProduct(test.expr); value != test.value {
+			t.Errorf("%q: EvaluateProduct() = %q, want %q", test.expr, value, test.value)
+		}
+	}
+}
+
+func TestLoadPingIntervalExpressions(t *testing.T) {
+	extractor := NewExtractor(mapSource{
+		"chainparams.cpp": "class CMainParams : public CChainParams {\n" +
+			"        consensus.nMasternodeMinMnpSeconds = 10 * 60;\n};\n" +
+			"class CTestNetParams : public CChainParams {\n" +
+			"        consensus.nMasternodeMinMnpSeconds = (5 * 60);\n};\n" +
+			"class CRegTestParams : public CChainParams {\n};\n",
+		"masternode.h": "#define MASTERNODE_MIN_MNP_SECONDS (15 * 60)\n",
+	})
+
+	for i, want := range []string{"600", "300", "900"} {
+		extractor.Network = Networks[i]
+		if interval := extractor.LoadPingInterval(); interval != want {
+			t.Errorf("%s: LoadPingInterval() = %q, want %q", Networks[i].Name, interval, want)
+		}
+	}
+}
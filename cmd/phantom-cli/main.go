@@ -0,0 +1,96 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"os"
+)
+
+const usage = `usage: phantom-cli [-socket path] <command> [args]
+
+commands:
+  status                          the daemon's version, connections and alerts
+  peers                           every peer connection and its state
+  addpeer <ip[:port]>             connect to a peer
+  disconnect <ip[:port]>          drop a peer connection
+  masternode list                 every masternode and its next ping
+  masternode add <alias> <ip:port> <masternodeprivkey> <txid> <index> [epoch]
+                                  add a masternode to the masternode file
+  masternode remove <alias>       remove a masternode from the masternode file
+  masternode pause <alias>        stop pinging a masternode
+  masternode resume <alias>       start pinging a paused or suspended masternode
+  ping now <alias>                send a masternode's ping right away
+  hashqueue                       the block hashes pings are signed with
+  reload                          re-read the masternode file
+`
+
+//SIMPLE CLIENT FOR THE CONTROL SOCKET OF A RUNNING PHANTOM.
+func main() {
+	var socket string
+
+	flag.StringVar(&socket, "socket", "phantom.sock", "the control socket of the phantom (its -control_socket)")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage, "\nflags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	result, err := phantom.CallControl(socket, flag.Arg(0), flag.Args()[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	//messages print as they are, everything else as indented JSON
+	var message string
+	if json.Unmarshal(result, &message) == nil {
+		fmt.Println(message)
+		return
+	}
+
+	var indented bytes.Buffer
+	err = json.Indent(&indented, result, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	fmt.Println(indented.String())
+}
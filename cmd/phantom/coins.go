@@ -0,0 +1,86 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/breakcrypto/phantom/pkg/coins"
+	"os"
+	"text/tabwriter"
+)
+
+// runCoins lists the coin registry or prints one of its configurations.
+func runCoins(args []string, registry coins.Registry) error {
+	usage := errors.New("usage: phantom coins list | phantom coins show <ticker>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "list":
+		entries, errs := registry.List()
+
+		table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(table, "TICKER\tNAME\tPORT\tPROTOCOL\tSOURCE")
+		for _, entry := range entries {
+			fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%s\n", entry.Ticker, entry.Conf.Name, entry.Conf.Port,
+				entry.Conf.ProtocolNumber, entry.Source)
+		}
+		table.Flush()
+
+		for ticker, err := range errs {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", ticker, err)
+		}
+		return nil
+
+	case "show":
+		if len(args) < 2 {
+			return usage
+		}
+
+		entry, err := registry.Lookup(args[1])
+		if err != nil {
+			return err
+		}
+
+		conf, err := json.MarshalIndent(entry.Conf, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("# %s\n%s\n", entry.Source, conf)
+		return nil
+	}
+
+	return usage
+}
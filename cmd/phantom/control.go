@@ -0,0 +1,352 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const peerRequestTimeout = 30 * time.Second
+
+// peerRequest asks sendPings, which owns the connection set, to connect to
+// or disconnect from a peer.
+type peerRequest struct {
+	address    wire.NetAddress
+	disconnect bool
+	reply      chan error
+}
+
+// controller runs the commands phantom-cli sends over the control socket.
+type controller struct {
+	coin         string
+	started      time.Time
+	scheduler    *phantom.PingScheduler
+	hashQueue    *phantom.Queue
+	pingChannel  chan phantom.MasternodePing
+	peerRequests chan peerRequest
+
+	//serializes edits of the masternode file
+	fileMux sync.Mutex
+}
+
+type daemonStatus struct {
+	Version        string    `json:"version"`
+	Coin           string    `json:"coin,omitempty"`
+	Uptime         string    `json:"uptime"`
+	ProtocolNumber uint32    `json:"protocol_number"`
+	Connections    int       `json:"connections"`
+	MaxConnections uint      `json:"max_connections"`
+	LastBlock      time.Time `json:"last_block"`
+	PingHash       string    `json:"ping_hash,omitempty"`
+	Masternodes    int       `json:"masternodes"`
+	Suspended      int       `json:"suspended"`
+	Rejects        int       `json:"rejects"`
+	Alerts         []string  `json:"alerts,omitempty"`
+}
+
+type peerStatus struct {
+	Address        string `json:"address"`
+	Status         string `json:"status"`
+	ProtocolNumber uint32 `json:"protocol_number"`
+	QueuedPings    int    `json:"queued_pings"`
+
+	//messages dropped because a consumer fell behind, by command
+	Dropped map[string]uint64 `json:"dropped,omitempty"`
+}
+
+// startControl serves the control socket at path.
+func startControl(path string, control *controller) (*phantom.ControlServer, error) {
+	server := &phantom.ControlServer{
+		Path: path,
+		Handlers: map[string]phantom.ControlHandler{
+			"status":     control.status,
+			"peers":      control.peers,
+			"addpeer":    control.addPeer,
+			"disconnect": control.disconnect,
+			"masternode": control.masternode,
+			"ping":       control.ping,
+			"hashqueue":  control.hashes,
+			"reload":     control.reload,
+		},
+	}
+	return server, server.Listen()
+}
+
+func (control *controller) status(args []string) (interface{}, error) {
+	lastBlockTimeMux.Lock()
+	lastBlock := lastBlockTime
+	lastBlockTimeMux.Unlock()
+
+	status := daemonStatus{
+		Version:        VERSION,
+		Coin:           control.coin,
+		Uptime:         time.Since(control.started).Round(time.Second).String(),
+		ProtocolNumber: protocolNumber,
+		Connections:    len(connectedPingers()),
+		MaxConnections: maxConnections,
+		LastBlock:      lastBlock.UTC(),
+		Alerts:         notifier.Active(),
+	}
+
+	if hash := control.hashQueue.Peek(); hash != nil {
+		status.PingHash = hash.String()
+	}
+
+	for _, masternode := range control.scheduler.Status() {
+		status.Masternodes++
+		status.Rejects += masternode.Rejects
+		if masternode.Suspended {
+			status.Suspended++
+		}
+	}
+
+	return status, nil
+}
+
+func (control *controller) peers(args []string) (interface{}, error) {
+	activeConnectionsMux.Lock()
+	pingers := append([]*phantom.PingerConnection(nil), activeConnections...)
+	activeConnectionsMux.Unlock()
+
+	peers := make([]peerStatus, 0, len(pingers))
+	for _, pinger := range pingers {
+		status := "connecting"
+		switch {
+		case pinger.GetStatus() > 0:
+			status = "connected"
+		case pinger.GetStatus() < 0:
+			status = "closing"
+		}
+
+		peers = append(peers, peerStatus{
+			Address:        net.JoinHostPort(pinger.IpAddress, strconv.Itoa(int(pinger.Port))),
+			Status:         status,
+			ProtocolNumber: pinger.ProtocolNumber,
+			QueuedPings:    len(pinger.PingChannel),
+			Dropped:        pinger.Dropped(),
+		})
+	}
+	return peers, nil
+}
+
+func (control *controller) addPeer(args []string) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("usage: addpeer <ip[:port]>")
+	}
+
+	address, err := control.peerAddress(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	err = control.requestPeer(peerRequest{address: address})
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Sprintf("Connecting to %s.", args[0]), nil
+}
+
+func (control *controller) disconnect(args []string) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("usage: disconnect <ip[:port]>")
+	}
+
+	address, err := control.peerAddress(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	err = control.requestPeer(peerRequest{address: address, disconnect: true})
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Sprintf("Disconnected from %s.", address.IP), nil
+}
+
+//peerAddress parses ip:port, the coin's port is used when it's left out
+func (control *controller) peerAddress(address string) (wire.NetAddress, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+		address = net.JoinHostPort(address, strconv.Itoa(int(defaultPort)))
+	}
+
+	//SplitAddress can't read IPv6 literals, tell why they're refused
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		return wire.NetAddress{}, errors.New("only IPv4 peers are supported")
+	}
+	return phantom.SplitAddress(address)
+}
+
+func (control *controller) requestPeer(request peerRequest) error {
+	request.reply = make(chan error, 1)
+
+	select {
+	case control.peerRequests <- request:
+	case <-time.After(peerRequestTimeout):
+		return errors.New("the connection manager is busy, try again")
+	}
+
+	select {
+	case err := <-request.reply:
+		return err
+	case <-time.After(peerRequestTimeout):
+		return errors.New("the connection manager didn't respond")
+	}
+}
+
+func (control *controller) masternode(args []string) (interface{}, error) {
+	usage := errors.New("usage: masternode list | add <alias> <ip:port> <masternodeprivkey> <txid> <index> [epoch] | " +
+		"remove <alias> | pause <alias> | resume <alias>")
+	if len(args) == 0 {
+		return nil, usage
+	}
+
+	switch args[0] {
+	case "list":
+		return control.scheduler.Status(), nil
+
+	case "add":
+		if len(args) != 6 && len(args) != 7 {
+			return nil, usage
+		}
+
+		entry, err := phantom.ParseMasternodeEntry(strings.Join(args[1:], " "))
+		if err != nil {
+			return nil, err
+		}
+		control.fileMux.Lock()
+		if len(args) == 6 {
+			entry.Epoch, err = control.spreadEpoch()
+		}
+		if err == nil {
+			err = phantom.AddMasternodeEntry(masternodeConf, entry)
+		}
+		control.fileMux.Unlock()
+		if err != nil {
+			return nil, err
+		}
+
+		control.scheduler.Reload()
+		return fmt.Sprintf("Added %s to %s.", entry.Alias, masternodeConf), nil
+
+	case "remove":
+		if len(args) != 2 {
+			return nil, usage
+		}
+
+		control.fileMux.Lock()
+		err := phantom.RemoveMasternodeEntry(masternodeConf, args[1])
+		control.fileMux.Unlock()
+		if err != nil {
+			return nil, err
+		}
+
+		control.scheduler.Reload()
+		return fmt.Sprintf("Removed %s from %s.", args[1], masternodeConf), nil
+
+	case "pause":
+		if len(args) != 2 {
+			return nil, usage
+		}
+		if !control.scheduler.Pause(args[1]) {
+			return nil, fmt.Errorf("%s isn't scheduled or is already suspended", args[1])
+		}
+		return fmt.Sprintf("Paused %s.", args[1]), nil
+
+	case "resume":
+		if len(args) != 2 {
+			return nil, usage
+		}
+		if !control.scheduler.Resume(args[1]) {
+			return nil, fmt.Errorf("%s isn't suspended", args[1])
+		}
+		return fmt.Sprintf("Resumed %s.", args[1]), nil
+	}
+
+	return nil, usage
+}
+
+//spreadEpoch picks an epoch whose pings fall between the ones of the
+//masternodes in the file
+func (control *controller) spreadEpoch() (int64, error) {
+	existing, err := phantom.ReadMasternodeFile(masternodeConf)
+	if err != nil {
+		return 0, err
+	}
+
+	epochs := make([]int64, 0, len(existing))
+	for _, entry := range existing {
+		epochs = append(epochs, entry.Epoch)
+	}
+	return phantom.SpreadEpochs(epochs, 1, pingInterval, time.Now())[0], nil
+}
+
+func (control *controller) ping(args []string) (interface{}, error) {
+	if len(args) != 2 || args[0] != "now" {
+		return nil, errors.New("usage: ping now <alias>")
+	}
+
+	ping, err := control.scheduler.PingNow(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case control.pingChannel <- ping:
+	default:
+		return nil, errors.New("the ping queue is full")
+	}
+	return fmt.Sprintf("Sending a ping for %s.", ping.Name), nil
+}
+
+func (control *controller) hashes(args []string) (interface{}, error) {
+	hashes := control.hashQueue.Hashes()
+
+	encoded := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		encoded = append(encoded, hash.String())
+	}
+	return encoded, nil
+}
+
+func (control *controller) reload(args []string) (interface{}, error) {
+	control.scheduler.Reload()
+	return fmt.Sprintf("Reloading %s.", masternodeConf), nil
+}
@@ -0,0 +1,335 @@
+//go:build !windows
+// +build !windows
+
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"github.com/breakcrypto/phantom/pkg/phantom/phantomtest"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// controlTest serves a controller scheduling mn1 and mn2 on a temporary
+// socket. The returned function stops it and restores the globals.
+func controlTest(t *testing.T) (*controller, string, func()) {
+	dir, err := ioutil.TempDir("", "phantom")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wif, err := btcutil.NewWIF(key, &chaincfg.MainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := filepath.Join(dir, "masternode.txt")
+	lines := fmt.Sprintf("mn1 45.50.22.125:9999 %s %s 0 1555555555\n", wif.String(), testCollateral) +
+		fmt.Sprintf("mn2 45.50.22.126:9999 %s %s 1 1555555855\n", wif.String(), testCollateral)
+	if err := ioutil.WriteFile(conf, []byte(lines), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	savedConf, savedPort, savedInterval := masternodeConf, defaultPort, pingInterval
+	masternodeConf, defaultPort, pingInterval = conf, 9999, 10*time.Minute
+
+	hashes := phantom.NewQueue(2)
+	hashes.Push(&chainhash.Hash{7})
+
+	control := &controller{
+		coin:    "DASH",
+		started: time.Now(),
+		scheduler: &phantom.PingScheduler{
+			MasternodeConf: conf,
+			HashQueue:      hashes,
+			PingInterval:   pingInterval,
+			Clock:          phantomtest.NewFakeClock(time.Unix(1555555555, 0)),
+		},
+		hashQueue:    hashes,
+		pingChannel:  make(chan phantom.MasternodePing, 1),
+		peerRequests: make(chan peerRequest),
+	}
+	if err := control.scheduler.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := startControl(filepath.Join(dir, "phantom.sock"), control)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return control, server.Path, func() {
+		server.Close()
+		masternodeConf, defaultPort, pingInterval = savedConf, savedPort, savedInterval
+		os.RemoveAll(dir)
+	}
+}
+
+// callControl runs a command over the socket and decodes its result.
+func callControl(t *testing.T, path string, result interface{}, command string, args ...string) error {
+	t.Helper()
+
+	raw, err := phantom.CallControl(path, command, args)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, result); err != nil {
+		t.Fatalf("%s %q: %s", command, args, err)
+	}
+	return nil
+}
+
+// expectReply checks a command succeeds with the message.
+func expectReply(t *testing.T, path string, want string, command string, args ...string) {
+	t.Helper()
+
+	var reply string
+	if err := callControl(t, path, &reply, command, args...); err != nil {
+		t.Errorf("%s %q: %s", command, args, err)
+	} else if reply != want {
+		t.Errorf("%s %q = %q, want %q", command, args, reply, want)
+	}
+}
+
+// expectError checks a command fails with an error containing want.
+func expectError(t *testing.T, path string, want string, command string, args ...string) {
+	t.Helper()
+
+	var reply interface{}
+	if err := callControl(t, path, &reply, command, args...); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("%s %q: %v, want an error with %q", command, args, err, want)
+	}
+}
+
+func masternodeList(t *testing.T, path string) map[string]phantom.MasternodeStatus {
+	t.Helper()
+
+	var statuses []phantom.MasternodeStatus
+	if err := callControl(t, path, &statuses, "masternode", "list"); err != nil {
+		t.Fatal(err)
+	}
+	list := make(map[string]phantom.MasternodeStatus)
+	for _, status := range statuses {
+		list[status.Alias] = status
+	}
+	return list
+}
+
+func TestControlPauseResume(t *testing.T) {
+	control, path, cleanup := controlTest(t)
+	defer cleanup()
+
+	list := masternodeList(t, path)
+	if len(list) != 2 || list["mn1"].Outpoint != testCollateral+":0" || list["mn1"].Suspended ||
+		!list["mn2"].NextPing.Equal(time.Unix(1555555855, 0)) {
+		t.Errorf("masternode list = %+v", list)
+	}
+
+	expectReply(t, path, "Paused mn1.", "masternode", "pause", "mn1")
+	expectError(t, path, "mn1 isn't scheduled or is already suspended", "masternode", "pause", "mn1")
+	expectError(t, path, "mn3 isn't scheduled", "masternode", "pause", "mn3")
+	if list := masternodeList(t, path); !list["mn1"].Suspended || list["mn1"].SuspendReason == "" || list["mn2"].Suspended {
+		t.Errorf("masternode list after pausing mn1 = %+v", list)
+	}
+	expectError(t, path, "suspended", "ping", "now", "mn1")
+
+	var status daemonStatus
+	if err := callControl(t, path, &status, "status"); err != nil {
+		t.Fatal(err)
+	}
+	if status.Version != VERSION || status.Coin != "DASH" || status.Masternodes != 2 || status.Suspended != 1 ||
+		status.PingHash != (chainhash.Hash{7}).String() {
+		t.Errorf("status = %+v", status)
+	}
+
+	expectReply(t, path, "Resumed mn1.", "masternode", "resume", "mn1")
+	expectError(t, path, "mn1 isn't suspended", "masternode", "resume", "mn1")
+	if list := masternodeList(t, path); list["mn1"].Suspended {
+		t.Errorf("mn1 still suspended: %+v", list["mn1"])
+	}
+
+	//rejects show up in the list and the status
+	control.scheduler.Reject(phantom.Rejection{Alias: "mn2", Peer: "45.50.22.127"})
+	if err := callControl(t, path, &status, "status"); err != nil {
+		t.Fatal(err)
+	}
+	if list := masternodeList(t, path); list["mn2"].Rejects != 1 || status.Rejects != 1 || status.Suspended != 0 {
+		t.Errorf("after a reject of mn2: status %+v, list %+v", status, list)
+	}
+}
+
+func TestControlPingNow(t *testing.T) {
+	control, path, cleanup := controlTest(t)
+	defer cleanup()
+
+	expectReply(t, path, "Sending a ping for mn2.", "ping", "now", "mn2")
+	select {
+	case ping := <-control.pingChannel:
+		if ping.Name != "mn2" || ping.OutpointIndex != 1 {
+			t.Errorf("queued ping %+v, want mn2's", ping)
+		}
+	default:
+		t.Fatal("no ping was queued")
+	}
+
+	expectError(t, path, "no masternode named mn3", "ping", "now", "mn3")
+	expectError(t, path, "usage: ping now <alias>", "ping", "mn1")
+
+	expectReply(t, path, "Sending a ping for mn1.", "ping", "now", "mn1")
+	expectError(t, path, "the ping queue is full", "ping", "now", "mn1")
+}
+
+func TestControlAddRemove(t *testing.T) {
+	_, path, cleanup := controlTest(t)
+	defer cleanup()
+
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wif, err := btcutil.NewWIF(key, &chaincfg.MainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txid := strings.Repeat("ab", 32)
+
+	expectReply(t, path, "Added mn3 to "+masternodeConf+".", "masternode", "add", "mn3", "45.50.22.127:9999",
+		wif.String(), txid, "0")
+	expectReply(t, path, "Added mn4 to "+masternodeConf+".", "masternode", "add", "mn4", "45.50.22.128:9999",
+		wif.String(), txid, "1", "1555555655")
+	expectError(t, path, "already has an entry for mn3", "masternode", "add", "mn3", "45.50.22.129:9999",
+		wif.String(), txid, "2")
+	expectError(t, path, "mn3 already uses the collateral", "masternode", "add", "mn5", "45.50.22.129:9999",
+		wif.String(), txid, "0")
+	expectError(t, path, "invalid collateral output index", "masternode", "add", "mn5", "45.50.22.129:9999",
+		wif.String(), txid, "x")
+	expectError(t, path, "usage: masternode list | add", "masternode", "add", "mn5")
+
+	entries, err := phantom.ReadMasternodeFile(masternodeConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	epochs := make(map[string]int64)
+	for _, entry := range entries {
+		epochs[entry.Alias] = entry.Epoch
+	}
+	if len(epochs) != 4 || epochs["mn4"] != 1555555655 || epochs["mn3"] == 0 {
+		t.Errorf("masternode file epochs %v", epochs)
+	}
+
+	expectReply(t, path, "Removed mn3 from "+masternodeConf+".", "masternode", "remove", "mn3")
+	expectError(t, path, "has no entry for mn3", "masternode", "remove", "mn3")
+
+	entries, err = phantom.ReadMasternodeFile(masternodeConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("%d entries left, want 3", len(entries))
+	}
+
+	expectReply(t, path, "Reloading "+masternodeConf+".", "reload")
+}
+
+func TestControlPeers(t *testing.T) {
+	control, path, cleanup := controlTest(t)
+	defer cleanup()
+
+	requests := make(chan peerRequest, 2)
+	go func() {
+		for request := range control.peerRequests {
+			requests <- request
+			if request.disconnect {
+				request.reply <- fmt.Errorf("not connected to %s", request.address.IP)
+			} else {
+				request.reply <- nil
+			}
+		}
+	}()
+	defer close(control.peerRequests)
+
+	expectReply(t, path, "Connecting to 45.50.22.127.", "addpeer", "45.50.22.127")
+	if request := <-requests; request.disconnect || request.address.IP.String() != "45.50.22.127" ||
+		request.address.Port != 9999 {
+		t.Errorf("request %+v, want a connection to 45.50.22.127:9999", request)
+	}
+
+	expectError(t, path, "not connected to 45.50.22.127", "disconnect", "45.50.22.127:19999")
+	if request := <-requests; !request.disconnect || request.address.Port != 19999 {
+		t.Errorf("request %+v, want a disconnect from port 19999", request)
+	}
+
+	expectError(t, path, "only IPv4 peers are supported", "addpeer", "[::1]:9999")
+	expectError(t, path, "only IPv4 peers are supported", "addpeer", "::1")
+	expectError(t, path, `invalid ip address "seed.example.com"`, "addpeer", "seed.example.com")
+	expectError(t, path, "usage: addpeer <ip[:port]>", "addpeer")
+	expectError(t, path, "usage: disconnect <ip[:port]>", "disconnect")
+
+	var peers []peerStatus
+	if err := callControl(t, path, &peers, "peers"); err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 0 {
+		t.Errorf("peers = %+v, none are connected", peers)
+	}
+}
+
+func TestControlRequests(t *testing.T) {
+	_, path, cleanup := controlTest(t)
+	defer cleanup()
+
+	var hashes []string
+	if err := callControl(t, path, &hashes, "hashqueue"); err != nil {
+		t.Fatal(err)
+	}
+	if len(hashes) != 1 || hashes[0] != (chainhash.Hash{7}).String() {
+		t.Errorf("hashqueue = %q", hashes)
+	}
+
+	expectError(t, path, `unknown command "bogus"`, "bogus")
+	expectError(t, path, "usage: masternode list", "masternode")
+	expectError(t, path, "usage: masternode list", "masternode", "bogus")
+}
@@ -0,0 +1,140 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"net"
+	"strconv"
+	"time"
+)
+
+const probeTimeout = 15 * time.Second
+const probePeers = 3
+
+// parseInterspersed parses flags that may follow positional arguments, i.e.
+// phantom probe 1.2.3.4:9999 -magicbytes=BD6B0CBF, and returns the latter.
+func parseInterspersed(args []string) []string {
+	var positional []string
+	for {
+		flag.CommandLine.Parse(args)
+		args = flag.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// runProbe prints what each peer reports about itself and how the
+// configured protocol number compares.
+func runProbe(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: phantom probe <ip:port> [ip:port...] -magicbytes=<hex>")
+	}
+	if magicBytes == 0 {
+		return errors.New("the magic bytes are required, set -magicbytes or -coin_conf")
+	}
+
+	for _, address := range args {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			address = net.JoinHostPort(address, strconv.Itoa(int(defaultPort)))
+		}
+
+		result, err := phantom.Probe(address, wire.BitcoinNet(magicBytes), protocolNumber, userAgent, probeTimeout)
+		if err != nil {
+			fmt.Printf("%s: %s\n\n", address, err)
+			continue
+		}
+
+		fmt.Println("Peer: ", result.Address)
+		fmt.Println("Protocol Version: ", result.ProtocolVersion)
+		fmt.Println("User Agent: ", result.UserAgent)
+		fmt.Println("Services: ", result.Services)
+		fmt.Println("Best Height: ", result.BestHeight)
+		if result.RejectReason != "" {
+			fmt.Println("Rejected: ", result.RejectReason)
+		}
+
+		suggested, warning := result.CheckProtocol(protocolNumber)
+		if warning != "" {
+			fmt.Println("Warning: ", warning)
+		}
+		if suggested != 0 {
+			fmt.Println("Suggested Protocol Number: ", suggested)
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
+
+// checkProtocol probes a few peers at startup and warns when they expect a
+// different protocol number, switching to it when adjust is set.
+func checkProtocol(peerSet map[string]wire.NetAddress, adjust bool) {
+	probed := 0
+	for _, peer := range peerSet {
+		if probed >= probePeers {
+			return
+		}
+		probed++
+
+		address := net.JoinHostPort(peer.IP.String(), strconv.Itoa(int(peer.Port)))
+		result, err := phantom.Probe(address, wire.BitcoinNet(magicBytes), protocolNumber, userAgent, probeTimeout)
+		if err != nil {
+			networkLog.With("peer", address).Debugf("Unable to probe: %s", err)
+			continue
+		}
+
+		networkLog.With("peer", address).Infof("Peer runs protocol %d (%s) at height %d.",
+			result.ProtocolVersion, result.UserAgent, result.BestHeight)
+
+		suggested, warning := result.CheckProtocol(protocolNumber)
+		if warning == "" {
+			return
+		}
+
+		if adjust && suggested != 0 {
+			networkLog.Warnf("%s Switching to protocol %d.", warning, suggested)
+			protocolNumber = suggested
+		} else if suggested != 0 {
+			networkLog.Warnf("%s Set -protocol_number=%d or -auto_protocol to switch.", warning, suggested)
+		} else {
+			networkLog.Warnf("%s", warning)
+		}
+		return
+	}
+}
@@ -0,0 +1,283 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/breakcrypto/phantom/pkg/logging"
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"sync"
+	"time"
+)
+
+const votePeers = 3
+const voteConnectTimeout = 1 * time.Minute
+const voteSyncTimeout = 2 * time.Minute
+
+//vars so the tests don't have to wait this long
+var voteSporkTimeout = 30 * time.Second
+var voteRelayWait = 30 * time.Second
+
+var voteLog = logging.New("vote")
+
+// runVote casts a funding vote on a proposal for the given aliases (every
+// alias when none are given) and relays it through the bootstrap peers.
+func runVote(args []string, peerSet map[string]wire.NetAddress) error {
+	if len(args) < 2 {
+		return errors.New("usage: phantom vote <proposal-hash> yes|no|abstain [alias...]")
+	}
+
+	proposalHash, err := chainhash.NewHashFromStr(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid proposal hash: %s", err)
+	}
+
+	outcome, err := phantom.ParseVoteOutcome(args[1])
+	if err != nil {
+		return err
+	}
+
+	voters, err := loadVoters(args[2:])
+	if err != nil {
+		return err
+	}
+
+	if len(peerSet) == 0 {
+		return errors.New("no peers to vote through, set bootstrap_ips")
+	}
+
+	var waitGroup sync.WaitGroup
+	governanceChannel := make(chan wire.MsgGovObj, 100)
+
+	var sporkChannel chan wire.MsgSpork
+	if sporkTable != nil {
+		sporkChannel = make(chan wire.MsgSpork, 100)
+		go processSporks(sporkChannel, sporkTable)
+	}
+
+	pingers := make([]*phantom.PingerConnection, 0, votePeers)
+	for _, peer := range peerSet {
+		if len(pingers) == votePeers {
+			break
+		}
+
+		addrChannel := make(chan wire.NetAddress, 1500)
+		hashChannel := make(chan chainhash.Hash, 1500)
+		go drainVoteChannels(addrChannel, hashChannel)
+
+		pinger := &phantom.PingerConnection{
+			MagicBytes:        magicBytes,
+			IpAddress:         peer.IP.String(),
+			Port:              peer.Port,
+			ProtocolNumber:    protocolNumber,
+			SentinelVersion:   sentinelVersion,
+			DaemonVersion:     daemonVersion,
+			Profile:           messageProfile,
+			PingChannel:       make(chan phantom.MasternodePing),
+			AddrChannel:       addrChannel,
+			HashChannel:       hashChannel,
+			SporkChannel:      sporkChannel,
+			GovernanceChannel: governanceChannel,
+			RelayChannel:      make(chan phantom.RelayMessage, 100),
+			WaitGroup:         &waitGroup,
+		}
+
+		waitGroup.Add(1)
+		go pinger.Start(userAgent)
+		pingers = append(pingers, pinger)
+	}
+
+	defer func() {
+		for _, pinger := range pingers {
+			close(pinger.PingChannel)
+		}
+		waitGroup.Wait()
+	}()
+
+	connected := waitForVotePeers(pingers, voteConnectTimeout)
+	if len(connected) == 0 {
+		return errors.New("unable to connect to any peer")
+	}
+
+	proposal, err := syncProposal(connected, governanceChannel, *proposalHash, voteSyncTimeout)
+	if err != nil {
+		return err
+	}
+	voteLog.With("proposal", proposalHash.String()).Infof("Voting %s on \"%s\".", args[1],
+		phantom.GovernanceObjectName(&proposal))
+
+	scheme := voteSignatureScheme(sporkTable, signatureScheme, voteSporkTimeout)
+
+	for _, voter := range voters {
+		voter.SignatureScheme = scheme
+
+		vote, err := voter.GenerateGovernanceVote(*proposalHash, outcome, time.Now())
+		if err != nil {
+			voteLog.With("alias", voter.Name).Errorf("Unable to sign the vote: %s", err)
+			continue
+		}
+
+		voteHash := vote.GetHash()
+		relay := phantom.RelayMessage{
+			Inv:     *wire.NewInvVect(wire.InvTypeGovernanceVote, &voteHash),
+			Message: &vote,
+		}
+
+		for _, pinger := range connected {
+			pinger.RelayChannel <- relay
+		}
+		voteLog.With("alias", voter.Name).Infof("Relaying vote %s.", relay.Inv.Hash)
+	}
+
+	//give the peers time to request the votes
+	time.Sleep(voteRelayWait)
+
+	return nil
+}
+
+// voteSignatureScheme waits for SPORK_6_NEW_SIGS to arrive with the spork
+// sync, a vote signed with the wrong scheme is rejected. Coins that don't
+// send the spork in time are voted on with the configured scheme.
+func voteSignatureScheme(table *phantom.SporkTable, configured phantom.SignatureScheme,
+	timeout time.Duration) phantom.SignatureScheme {
+
+	if table == nil {
+		return configured
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, known := table.Value(phantom.SporkNewSigs); known {
+			if table.IsActive(phantom.SporkNewSigs) {
+				return phantom.SignatureHash
+			}
+			return configured
+		}
+
+		if time.Now().After(deadline) {
+			voteLog.Warnf("%s wasn't received, signing with the %s signature scheme.",
+				phantom.SporkName(phantom.SporkNewSigs), configured)
+			return configured
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// loadVoters returns the masternodes matching aliases, or all of them.
+func loadVoters(aliases []string) ([]phantom.MasternodePing, error) {
+	masternodes, err := phantom.LoadPingsFromMasternodeFile(masternodeConf, nil, magicMessage,
+		sentinelVersion, daemonVersion, pingInterval, sigTimeOffset, messageProfile, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(aliases) == 0 {
+		return masternodes, nil
+	}
+
+	voters := make([]phantom.MasternodePing, 0, len(aliases))
+	for _, alias := range aliases {
+		found := false
+		for _, masternode := range masternodes {
+			if masternode.Name == alias {
+				voters = append(voters, masternode)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("alias %s is not in %s", alias, masternodeConf)
+		}
+	}
+	return voters, nil
+}
+
+func drainVoteChannels(addrChannel chan wire.NetAddress, hashChannel chan chainhash.Hash) {
+	for {
+		select {
+		case <-addrChannel:
+		case <-hashChannel:
+		}
+	}
+}
+
+// waitForVotePeers returns the pingers that finished the handshake in time.
+func waitForVotePeers(pingers []*phantom.PingerConnection, timeout time.Duration) []*phantom.PingerConnection {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		connected := make([]*phantom.PingerConnection, 0, len(pingers))
+		for _, pinger := range pingers {
+			if pinger.GetStatus() == 1 {
+				connected = append(connected, pinger)
+			}
+		}
+
+		if len(connected) == len(pingers) || (len(connected) > 0 && time.Now().After(deadline)) {
+			return connected
+		}
+		if time.Now().After(deadline) {
+			return nil
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// syncProposal asks the peers for the proposal and waits until one sends it.
+func syncProposal(pingers []*phantom.PingerConnection, governanceChannel chan wire.MsgGovObj,
+	proposalHash chainhash.Hash, timeout time.Duration) (wire.MsgGovObj, error) {
+
+	for _, pinger := range pingers {
+		pinger.Send(&wire.MsgGovSync{Hash: proposalHash})
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case govObj := <-governanceChannel:
+			if govObj.GetHash() != proposalHash {
+				continue
+			}
+			if govObj.ObjectType != wire.GovObjTypeProposal {
+				return wire.MsgGovObj{}, fmt.Errorf("%s is not a proposal", proposalHash)
+			}
+			return govObj, nil
+
+		case <-timer.C:
+			return wire.MsgGovObj{}, fmt.Errorf("no peer knows proposal %s", proposalHash)
+		}
+	}
+}
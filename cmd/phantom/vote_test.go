@@ -0,0 +1,223 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"github.com/breakcrypto/phantom/pkg/phantom/phantomtest"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testMagicMessage = "DarkCoin Signed Message:\n"
+const testCollateral = "2bcd3c84c84f87eaa86e4e56834c92927a07f9e18718810b92e0d0324456a67c"
+
+// voteTest points the vote command's globals at a fake peer serving the
+// proposal and sporks, with mn1 in the masternode file. The returned
+// function restores the globals and stops the peer.
+func voteTest(t *testing.T, proposal wire.MsgGovObj, sporks []wire.MsgSpork,
+	table *phantom.SporkTable) (*phantomtest.FakePeer, *btcec.PrivateKey, map[string]wire.NetAddress, func()) {
+
+	profile, err := wire.LookupMessageProfile("dash-12.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peer, err := phantomtest.NewFakePeer(wire.BitcoinNet(0xBD6B0CBF), 70208)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer.Profile = profile
+	proposal.Profile = profile
+	peer.GovernanceObjects = []wire.MsgGovObj{proposal}
+	peer.Sporks = sporks
+	peer.Start()
+
+	dir, err := ioutil.TempDir("", "phantom")
+	if err != nil {
+		peer.Close()
+		t.Fatal(err)
+	}
+
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wif, err := btcutil.NewWIF(key, &chaincfg.MainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := filepath.Join(dir, "masternode.txt")
+	line := fmt.Sprintf("mn1 45.50.22.125:9999 %s %s 1 1555555555\n", wif.String(), testCollateral)
+	if err := ioutil.WriteFile(conf, []byte(line), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := []interface{}{magicBytes, protocolNumber, magicMessage, masternodeConf, userAgent, pingInterval,
+		messageProfile, signatureScheme, sporkTable, voteSporkTimeout, voteRelayWait}
+	restore := func() {
+		magicBytes, protocolNumber = saved[0].(uint32), saved[1].(uint32)
+		magicMessage, masternodeConf, userAgent = saved[2].(string), saved[3].(string), saved[4].(string)
+		pingInterval = saved[5].(time.Duration)
+		messageProfile = saved[6].(*wire.MessageProfile)
+		signatureScheme = saved[7].(phantom.SignatureScheme)
+		sporkTable = saved[8].(*phantom.SporkTable)
+		voteSporkTimeout, voteRelayWait = saved[9].(time.Duration), saved[10].(time.Duration)
+
+		peer.Close()
+		os.RemoveAll(dir)
+	}
+
+	magicBytes = uint32(peer.Magic)
+	protocolNumber = peer.ProtocolVersion
+	magicMessage = testMagicMessage
+	masternodeConf = conf
+	userAgent = "/phantomtest:0.0.1/"
+	pingInterval = 10 * time.Minute
+	messageProfile = profile
+	signatureScheme = phantom.SignatureLegacy
+	sporkTable = table
+	voteSporkTimeout = 500 * time.Millisecond
+	voteRelayWait = time.Second
+
+	peerSet := map[string]wire.NetAddress{
+		peer.IpAddress(): *wire.NewNetAddressIPPort(net.ParseIP(peer.IpAddress()), peer.Port(), 0),
+	}
+	return peer, key, peerSet, restore
+}
+
+func testProposal() wire.MsgGovObj {
+	collateral, _ := chainhash.NewHashFromStr(testCollateral)
+	return wire.MsgGovObj{
+		Revision:   1,
+		Time:       1555555555,
+		Data:       hex.EncodeToString([]byte(`{"name":"fund-the-devs","type":1}`)),
+		ObjectType: wire.GovObjTypeProposal,
+		Vin:        *wire.NewTxIn(wire.NewOutPoint(collateral, 0), nil, nil),
+	}
+}
+
+func signedNewSigsSpork(t *testing.T, key *btcec.PrivateKey, value int64) wire.MsgSpork {
+	spork := wire.MsgSpork{SporkID: phantom.SporkNewSigs, Value: value, TimeSigned: 1555555555}
+	hash := spork.SignatureHash()
+	sig, err := btcec.SignCompact(btcec.S256(), key, hash[:], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spork.Sig = sig
+	return spork
+}
+
+// TestRunVote votes through a fake peer, the signature scheme follows
+// SPORK_6_NEW_SIGS once it's synced and the coin conf's otherwise.
+func TestRunVote(t *testing.T) {
+	sporkKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name   string
+		sporks []wire.MsgSpork
+		track  bool
+		want   phantom.SignatureScheme
+	}{
+		{"spork active", []wire.MsgSpork{signedNewSigsSpork(t, sporkKey, 1500000000)}, true, phantom.SignatureHash},
+		{"spork inactive", []wire.MsgSpork{signedNewSigsSpork(t, sporkKey, 4070908800)}, true, phantom.SignatureLegacy},
+		{"spork not sent", nil, true, phantom.SignatureLegacy},
+		{"sporks not tracked", []wire.MsgSpork{signedNewSigsSpork(t, sporkKey, 1500000000)}, false,
+			phantom.SignatureLegacy},
+	} {
+		var table *phantom.SporkTable
+		if test.track {
+			table = phantom.NewSporkTable(sporkKey.PubKey(), testMagicMessage)
+		}
+
+		proposal := testProposal()
+		peer, key, peerSet, restore := voteTest(t, proposal, test.sporks, table)
+
+		err := runVote([]string{proposal.GetHash().String(), "no"}, peerSet)
+		votes := peer.Votes()
+		restore()
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		if len(votes) != 1 {
+			t.Errorf("%s: the peer received %d votes, want 1", test.name, len(votes))
+			continue
+		}
+		vote := votes[0]
+		if vote.ParentHash != proposal.GetHash() || vote.Outcome != wire.VoteOutcomeNo ||
+			vote.Vin.PreviousOutPoint.String() != testCollateral+":1" {
+			t.Errorf("%s: received vote %+v", test.name, vote)
+		}
+		if err := phantomtest.VerifyVote(&vote, test.want, testMagicMessage, key.PubKey()); err != nil {
+			t.Errorf("%s: %s vote: %s", test.name, test.want, err)
+		}
+	}
+}
+
+func TestRunVoteErrors(t *testing.T) {
+	proposal := testProposal()
+	_, _, peerSet, restore := voteTest(t, proposal, nil, nil)
+	defer restore()
+
+	hash := proposal.GetHash().String()
+	for _, test := range []struct {
+		args    []string
+		peerSet map[string]wire.NetAddress
+		want    string
+	}{
+		{[]string{hash}, peerSet, "usage"},
+		{[]string{"not a hash", "yes"}, peerSet, "invalid proposal hash"},
+		{[]string{hash, "maybe"}, peerSet, "unknown vote outcome"},
+		{[]string{hash, "yes", "mn2"}, peerSet, "alias mn2 is not in"},
+		{[]string{hash, "yes"}, nil, "no peers"},
+	} {
+		err := runVote(test.args, test.peerSet)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("runVote(%q) = %v, want %q", test.args, err, test.want)
+		}
+	}
+}
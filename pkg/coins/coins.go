@@ -0,0 +1,128 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+// Package coins is the registry of coin configurations built into the
+// phantom, generated from the configs directory.
+package coins
+
+//go:generate go run gen.go
+
+import (
+	"fmt"
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BuiltIn is the source of the embedded coin configurations.
+const BuiltIn = "built-in"
+
+// Entry is a coin configuration and where it came from.
+type Entry struct {
+	Ticker string
+	Conf   phantom.CoinConf
+	Source string
+}
+
+// Registry looks coin configurations up by ticker. Configurations named
+// <ticker>.json in OverrideDir shadow the built-in ones.
+type Registry struct {
+	OverrideDir string
+}
+
+// Lookup returns the configuration of a ticker, case insensitively.
+func (registry Registry) Lookup(ticker string) (Entry, error) {
+	ticker = strings.ToLower(ticker)
+
+	if registry.OverrideDir != "" {
+		path := filepath.Join(registry.OverrideDir, ticker+".json")
+		data, err := ioutil.ReadFile(path)
+		if err == nil {
+			return parseEntry(ticker, path, data)
+		}
+		if !os.IsNotExist(err) {
+			return Entry{}, err
+		}
+	}
+
+	data, ok := embedded[ticker]
+	if !ok {
+		return Entry{}, fmt.Errorf("unknown coin %q, see phantom coins list", ticker)
+	}
+	return parseEntry(ticker, BuiltIn, []byte(data))
+}
+
+// List returns every coin, overrides included, sorted by ticker. Entries
+// that fail to parse are returned with their error.
+func (registry Registry) List() ([]Entry, map[string]error) {
+	tickers := make(map[string]bool)
+	for ticker := range embedded {
+		tickers[ticker] = true
+	}
+
+	if registry.OverrideDir != "" {
+		paths, _ := filepath.Glob(filepath.Join(registry.OverrideDir, "*.json"))
+		for _, path := range paths {
+			tickers[strings.ToLower(strings.TrimSuffix(filepath.Base(path), ".json"))] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(tickers))
+	for ticker := range tickers {
+		sorted = append(sorted, ticker)
+	}
+	sort.Strings(sorted)
+
+	var entries []Entry
+	errs := make(map[string]error)
+	for _, ticker := range sorted {
+		entry, err := registry.Lookup(ticker)
+		if err != nil {
+			errs[ticker] = err
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, errs
+}
+
+func parseEntry(ticker string, source string, data []byte) (Entry, error) {
+	conf, err := phantom.ParseCoinConf(data)
+	if err == nil {
+		err = conf.Validate()
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("%s: %s", source, err)
+	}
+	return Entry{Ticker: ticker, Conf: conf, Source: source}, nil
+}
@@ -0,0 +1,90 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package coins
+
+import (
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedConfigsValid(t *testing.T) {
+	if len(embedded) == 0 {
+		t.Fatal("no embedded coin configurations")
+	}
+
+	for ticker, data := range embedded {
+		conf, err := phantom.ParseCoinConf([]byte(data))
+		if err == nil {
+			err = conf.Validate()
+		}
+		if err != nil {
+			t.Errorf("%s: %s", ticker, err)
+		}
+	}
+}
+
+// TestEmbeddedConfigsInSync fails when configs_generated.go wasn't
+// regenerated after the configs directory changed, run go generate.
+func TestEmbeddedConfigsInSync(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "..", "configs", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no coin configurations in the configs directory")
+	}
+
+	onDisk := make(map[string]bool)
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ticker := strings.ToLower(strings.TrimSuffix(filepath.Base(path), ".json"))
+		onDisk[ticker] = true
+
+		embeddedData, ok := embedded[ticker]
+		if !ok {
+			t.Errorf("%s isn't embedded, run go generate", path)
+		} else if embeddedData != strings.TrimSpace(string(data)) {
+			t.Errorf("%s differs from its embedded copy, run go generate", path)
+		}
+	}
+
+	for ticker := range embedded {
+		if !onDisk[ticker] {
+			t.Errorf("%s is embedded but has no config file, run go generate", ticker)
+		}
+	}
+}
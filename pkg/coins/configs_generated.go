@@ -0,0 +1,53 @@
+// Code generated by gen.go from the configs directory. DO NOT EDIT.
+
+package coins
+
+var embedded = map[string]string{
+	"aias":      "{\"name\":\"AIAS\",\"magicbytes\":\"18203203\",\"port\":10721,\"protocol_number\":70130,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"https://aiasexplorer.com\"}",
+	"ands":      "{\n  \"name\":\"ANDS\",\n  \"magicbytes\": \"E4D2411C\",\n  \"port\":1929,\n  \"protocol_number\":70209,\n  \"magic_message\":\"ProtonCoin Signed Message:\",\n  \"magic_message_newline\":true,\n  \"bootstrap_url\":\"http://explorer.anodoscrypto.com:3001\"\n}",
+	"axe":       "{\"name\":\"AXE\",\"magicbytes\":\"046BCEB5\",\"port\":9937,\"protocol_number\":70213,\"magic_message\":\"DarkCoin Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"https://axe-explorer.arcpool.com\",\"sentinel_version\":\"1.0.1\",\"pow_algorithm\":\"x11\",\"pow_limit\":\"00000fffffffffffffffffffffffffffffffffffffffffffffffffffffffffff\"}",
+	"beet":      "{\"name\":\"BEET\",\"magicbytes\":\"18094810\",\"port\":3133,\"protocol_number\":70101,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"https://explorer.beetlecoin.io\"}",
+	"bitg":      "{\"name\":\"BITG\",\"magicbytes\":\"AE12DC54\",\"port\":9333,\"protocol_number\":70914,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"https://explorer.savebitcoin.io\"}",
+	"cdm":       "{\"name\":\"CDM\",\"magicbytes\":\"388BBAC9\",\"port\":33588,\"protocol_number\":70007,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"https://chain.cdmcoin.org\"}",
+	"cnmc":      "{\"name\":\"CNMC\",\"magicbytes\":\"16EA62CD\",\"port\":44219,\"protocol_number\":71209,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"http://explorer.cryptonodes.ch\"}",
+	"d":         "{\"name\":\"Denarius\",\"magicbytes\":\"b4eff2fa\",\"port\":33369,\"protocol_number\":33500,\"magic_message\":\"Denarius Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"https://denarius.pro\",\"bootstrap_ips\":\"144.202.108.83:33369\"}",
+	"dev":       "{\"name\":\"DEV\",\"magicbytes\":\"B8ADB428\",\"port\":22618,\"protocol_number\":70914,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"http://explorer.deviantcoin.io\"}",
+	"dkpc":      "{\"name\":\"DKPC\",\"magicbytes\":\"4B523444\",\"port\":6667,\"protocol_number\":70914,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"http://46.101.231.40:3001\"}",
+	"emp":       "{\"name\":\"EMP\",\"magicbytes\":\"19043210\",\"port\":14321,\"protocol_number\":70015,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"http://45.76.39.254\"}",
+	"feirm":     "{\n  \"name\":\"Feirm\",\n  \"magicbytes\": \"9FA4FB4D\",\n  \"port\":4918,\n  \"protocol_number\":70917,\n  \"magic_message\":\"DarkNet Signed Message:\",\n  \"magic_message_newline\":true,\n  \"bootstrap_url\":\"https://explorer.feirm.com\"\n}",
+	"gbx":       "{\"name\":\"GBX\",\"magicbytes\":\"D4C3B21A\",\"port\":12455,\"protocol_number\":70209,\"magic_message\":\"DarkCoin Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"https://explorer.gobyte.network\",\"sentinel_version\":\"1.0.1\"}",
+	"gic":       "{\"name\":\"GIC\",\"magicbytes\":\"1987ABBA\",\"port\":40444,\"protocol_number\":70719,\"magic_message\":\"GIANT Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"https://explorer.giantpay.network\"}",
+	"gin":       "{\"name\":\"GIN\",\"magicbytes\":\"BD6B0CBF\",\"port\":10111,\"protocol_number\":70208,\"magic_message\":\"DarkCoin Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"https://explorer.gincoin.io\",\"sentinel_version\":\"1.0.1\"}",
+	"hlm":       "{\"name\":\"HLM\",\"magicbytes\":\"E0C00A0F\",\"port\":9009,\"protocol_number\":71029,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true}",
+	"hlx":       "{\"name\":\"HLX\",\"magicbytes\":\"A9C173F1\",\"port\":7979,\"protocol_number\":70208,\"magic_message\":\"DarkCoin Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"http://explorer.hiluxcoin.com\",\"sentinel_version\":\"1.0.1\"}",
+	"isf":       "{\"name\":\"ISF\",\"magicbytes\":\"2E49EE71\",\"port\":3509,\"protocol_number\":70914,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"http://explorer.insifa.io\"}",
+	"isf_test":  "{\"name\":\"ISF\",\"magicbytes\":\"63442E04\",\"port\":35099,\"protocol_number\":70914,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true}",
+	"linda":     "{\"name\":\"LINDA\",\"magicbytes\":\"0117D39C\",\"port\":33820,\"protocol_number\":70004,\"magic_message\":\"Linda Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"https://lindaexplorer.kdhsolutions.co.uk\"}",
+	"lpc":       "{\"name\":\"LPC\",\"magicbytes\":\"5A4F726E\",\"port\":39797,\"protocol_number\":70810,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true}",
+	"marc":      "{\"name\":\"MARC\",\"magicbytes\":\"AD11DB53\",\"port\":44004,\"protocol_number\":70916,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"https://explorer.marcoin.cc\"}",
+	"mill":      "{\"name\":\"MILL\",\"magicbytes\":\"71113CEE\",\"port\":5792,\"protocol_number\":70210,\"magic_message\":\"DarkCoin Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"http://explorer.millenniumclub.ca:3001/\",\"sentinel_version\":\"1.0.1\"}",
+	"mnpr":      "{\"name\":\"MNPR\",\"magicbytes\":\"A3C9F326\",\"port\":30229,\"protocol_number\":70952,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"http://209.250.224.186:3099\"}",
+	"omega":     "{\"name\":\"OMEGA\",\"magicbytes\":\"BD6B0CBF\",\"port\":7777,\"protocol_number\":70209,\"magic_message\":\"DarkCoin Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"http://explorer.omegablockchain.net\",\"sentinel_version\":\"1.0.1\"}",
+	"pac":       "{\n  \"name\":\"$PAC\",\n  \"magicbytes\": \"2C61E5C8\",\n  \"port\":7112,\n  \"protocol_number\":70215,\n  \"magic_message\":\"DarkCoin Signed Message:\",\n  \"magic_message_newline\":true,\n  \"bootstrap_url\":\"http://explorer.paccoin.net\",\n  \"sentinel_version\":\"1.2.0\",\n  \"pow_algorithm\":\"x11\",\n  \"pow_limit\":\"00000fffffffffffffffffffffffffffffffffffffffffffffffffffffffffff\"\n}",
+	"pivx-test": "{\"name\":\"PIVX\",\"magicbytes\":\"BA657645\",\"port\":51474,\"protocol_number\":70915,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"\"}",
+	"pivx":      "{\"name\":\"PIVX\",\"magicbytes\":\"E9FDC490\",\"port\":51472,\"protocol_number\":70915,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"\"}",
+	"polis":     "{\"name\":\"POLIS\",\"magicbytes\":\"BD6B0CBF\",\"port\":24126,\"protocol_number\":70214,\"magic_message\":\"DarkCoin Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"https://explorer.polispay.org\",\"sentinel_version\":\"1.0.1\",\"pow_algorithm\":\"x11\",\"pow_limit\":\"00000fffffffffffffffffffffffffffffffffffffffffffffffffffffffffff\"}",
+	"prx":       "{\"name\":\"PRX\",\"magicbytes\":\"D92DD5AF\",\"port\":12195,\"protocol_number\":70200,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"http://proxynode.network:8080\"}",
+	"rapid":     "{\"name\":\"RAPID\",\"magicbytes\":\"7CA6C65E\",\"port\":50451,\"protocol_number\":70208,\"magic_message\":\"DarkCoin Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"https://explorer.therapidcoin.com\",\"sentinel_version\":\"1.0.1\"}",
+	"seko":      "{\"name\":\"SEKO\",\"magicbytes\":\"F8203109\",\"port\":4786,\"protocol_number\":70210,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"https://blocks.sekopay.co.uk\"}",
+	"sins":      "{\"name\":\"SINS\",\"magicbytes\":\"91E74A42\",\"port\":39105,\"protocol_number\":70921,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"http://explorer.safeinsure.io\"}",
+	"slx":       "{\"name\":\"SLX\",\"magicbytes\":\"81B5EAA3\",\"port\":37415,\"protocol_number\":70916,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true}",
+	"smart":     "{\"name\":\"SMART\",\"magicbytes\":\"1EABA15C\",\"port\":9678,\"protocol_number\":90029,\"magic_message\":\"SmartCash Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"https://smart.ccore.online\"}",
+	"smrtc":     "{\"name\":\"SMRTC\",\"magicbytes\":\"45C2BA1D\",\"port\":9887,\"protocol_number\":70003,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"max_connections\":25,\"bootstrap_ips\":\"80.211.43.92:9887,212.237.21.95:9887,94.177.203.229:9887,134.209.9.135:9887,45.76.18.145:9887,195.29.154.212:9887,159.65.2.175:9887,159.69.149.99:9887,206.189.85.175:9887,139.99.161.11:9887,140.82.18.15:9887,45.32.135.29:9887\"}",
+	"sparks":    "{\n  \"name\":\"Sparks\",\n  \"magicbytes\": \"D4C3B21A\",\n  \"port\":8890,\n  \"protocol_number\":70210,\n  \"magic_message\":\"DarkCoin Signed Message:\",\n  \"magic_message_newline\":true,\n  \"bootstrap_url\":\"http://explorer.sparkspay.io\"\n}",
+	"sys":       "{\"name\":\"SYS\",\"magicbytes\":\"D9B4BEF9\",\"port\":8369,\"protocol_number\":70227,\"magic_message\":\"Syscoin Signed Message:\",\"magic_message_newline\":true,\"sentinel_version\":\"1.0.1\"}",
+	"telos":     "{\"name\":\"TELOS\",\"magicbytes\":\"394D752F\",\"port\":22123,\"protocol_number\":71002,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"http://159.69.33.243:3001\"}",
+	"vulc":      "{\"name\":\"VULC\",\"magicbytes\":\"17010208\",\"port\":62543,\"protocol_number\":70860,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true}",
+	"wgr":       "{\"name\":\"WGR\",\"magicbytes\":\"FD612D84\",\"port\":55002,\"protocol_number\":70924,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true}",
+	"wolf":      "{\"name\":\"WOLF\",\"magicbytes\":\"BD6B0CBF\",\"port\":4836,\"protocol_number\":70210,\"magic_message\":\"DarkCoin Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"http://blockexplorer.wolfpackbot.com\",\"sentinel_version\":\"1.0.1\"}",
+	"xap":       "{\"name\":\"XAP\",\"magicbytes\":\"FDEBD190\",\"port\":12218,\"protocol_number\":70915,\"magic_message\":\"DarkNet Signed Message:\",\"magic_message_newline\":true}",
+	"xbi":       "{\n  \"name\":\"Bitcoin Incognito\",\n  \"magicbytes\": \"FEF8A489\",\n  \"port\":7339,\n  \"protocol_number\":70997,\n  \"magic_message\":\"DarkNet Signed Message:\",\n  \"magic_message_newline\":true,\n  \"bootstrap_url\":\"http://explorer.bitcoinincognito.org\"\n}",
+	"xlq":       "{\"name\":\"XLQ\",\"magicbytes\":\"14150494\",\"port\":55500,\"protocol_number\":70717,\"magic_message\":\"ALQO Signed Message:\",\"magic_message_newline\":true}",
+	"xmn":       "{\"name\":\"XMN\",\"magicbytes\":\"745D7EAE\",\"port\":7979,\"protocol_number\":70208,\"magic_message\":\"DarkCoin Signed Message:\",\"magic_message_newline\":true,\"bootstrap_url\":\"http://motionexplorer.tk/\",\"sentinel_version\":\"1.0.1\"}",
+	"xzc":       "{\"name\":\"XZC\",\"magicbytes\":\"F1FED9E3\",\"port\":8168,\"protocol_number\":90026,\"magic_message\":\"Zcoin Signed Message:\",\"magic_message_newline\":true}",
+}
@@ -0,0 +1,95 @@
+// +build ignore
+
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+// gen embeds the configs directory into the registry, refusing configs
+// that don't parse.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func main() {
+	paths, err := filepath.Glob(filepath.Join("..", "..", "configs", "*.json"))
+	if err != nil {
+		log.Fatalf("Unable to list the coin configurations: %s", err)
+	}
+	sort.Strings(paths)
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by gen.go from the configs directory. DO NOT EDIT.\n\n")
+	b.WriteString("package coins\n\n")
+	b.WriteString("var embedded = map[string]string{\n")
+
+	failed := false
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Unable to read %s: %s", path, err)
+		}
+
+		conf, err := phantom.ParseCoinConf(data)
+		if err == nil {
+			err = conf.Validate()
+		}
+		if err != nil {
+			log.Printf("%s: %s", path, err)
+			failed = true
+			continue
+		}
+
+		ticker := strings.ToLower(strings.TrimSuffix(filepath.Base(path), ".json"))
+		fmt.Fprintf(&b, "\t%q: %q,\n", ticker, strings.TrimSpace(string(data)))
+	}
+	b.WriteString("}\n")
+
+	if failed {
+		log.Fatal("Invalid coin configurations, fix them before building.")
+	}
+
+	source, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatalf("Unable to format the registry: %s", err)
+	}
+
+	err = ioutil.WriteFile("configs_generated.go", source, 0644)
+	if err != nil {
+		log.Fatalf("Unable to write the registry: %s", err)
+	}
+}
@@ -0,0 +1,299 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+// Package logging is a small leveled logger with per-subsystem levels and
+// key/value fields, written as text or JSON lines.
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (level Level) String() string {
+	if name, ok := levelNames[level]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int(level))
+}
+
+func ParseLevel(str string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, errors.New("unknown log level: " + str)
+}
+
+type Format int
+
+const (
+	FormatText Format = iota
+	FormatJSON
+)
+
+func ParseFormat(str string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "text", "":
+		return FormatText, nil
+	case "json":
+		return FormatJSON, nil
+	}
+	return FormatText, errors.New("unknown log format: " + str)
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+var (
+	mux             sync.Mutex
+	output          io.Writer = os.Stderr
+	format                    = FormatText
+	defaultLevel              = LevelInfo
+	subsystemLevels           = make(map[string]Level)
+	globalFields    []field
+)
+
+func SetOutput(w io.Writer) {
+	mux.Lock()
+	defer mux.Unlock()
+
+	output = w
+}
+
+func SetFormat(f Format) {
+	mux.Lock()
+	defer mux.Unlock()
+
+	format = f
+}
+
+// SetLevel sets the level used by every subsystem without its own level.
+func SetLevel(level Level) {
+	mux.Lock()
+	defer mux.Unlock()
+
+	defaultLevel = level
+}
+
+func SetSubsystemLevel(subsystem string, level Level) {
+	mux.Lock()
+	defer mux.Unlock()
+
+	subsystemLevels[subsystem] = level
+}
+
+// SetSubsystemLevels parses a list such as "wire=debug,scheduler=info".
+func SetSubsystemLevels(spec string) error {
+	for _, part := range strings.Split(spec, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		pair := strings.SplitN(part, "=", 2)
+		if len(pair) != 2 {
+			return errors.New("invalid subsystem level, expected name=level: " + part)
+		}
+
+		level, err := ParseLevel(pair[1])
+		if err != nil {
+			return err
+		}
+		SetSubsystemLevel(strings.TrimSpace(pair[0]), level)
+	}
+	return nil
+}
+
+// AddGlobalField attaches a field, such as the coin name, to every entry.
+func AddGlobalField(key string, value interface{}) {
+	mux.Lock()
+	defer mux.Unlock()
+
+	globalFields = append(globalFields, field{key, value})
+}
+
+type Logger struct {
+	subsystem string
+	fields    []field
+}
+
+func New(subsystem string) *Logger {
+	return &Logger{subsystem: subsystem}
+}
+
+// With returns a child logger that adds key=value to every entry.
+func (logger *Logger) With(key string, value interface{}) *Logger {
+	fields := make([]field, len(logger.fields), len(logger.fields)+1)
+	copy(fields, logger.fields)
+
+	return &Logger{
+		subsystem: logger.subsystem,
+		fields:    append(fields, field{key, value}),
+	}
+}
+
+func (logger *Logger) Enabled(level Level) bool {
+	mux.Lock()
+	defer mux.Unlock()
+
+	return level >= logger.levelLocked()
+}
+
+func (logger *Logger) levelLocked() Level {
+	if level, ok := subsystemLevels[logger.subsystem]; ok {
+		return level
+	}
+	return defaultLevel
+}
+
+func (logger *Logger) Debugf(msg string, args ...interface{}) {
+	logger.log(LevelDebug, msg, args...)
+}
+
+func (logger *Logger) Infof(msg string, args ...interface{}) {
+	logger.log(LevelInfo, msg, args...)
+}
+
+func (logger *Logger) Warnf(msg string, args ...interface{}) {
+	logger.log(LevelWarn, msg, args...)
+}
+
+func (logger *Logger) Errorf(msg string, args ...interface{}) {
+	logger.log(LevelError, msg, args...)
+}
+
+// Fatalf logs at the error level and exits the process.
+func (logger *Logger) Fatalf(msg string, args ...interface{}) {
+	logger.log(LevelError, msg, args...)
+	os.Exit(1)
+}
+
+func (logger *Logger) log(level Level, msg string, args ...interface{}) {
+	mux.Lock()
+	defer mux.Unlock()
+
+	if level < logger.levelLocked() {
+		return
+	}
+
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+
+	fields := make([]field, 0, len(globalFields)+len(logger.fields))
+	fields = append(fields, globalFields...)
+	fields = append(fields, logger.fields...)
+
+	var line []byte
+	if format == FormatJSON {
+		line = formatJSON(time.Now().UTC(), level, logger.subsystem, msg, fields)
+	} else {
+		line = formatText(time.Now().UTC(), level, logger.subsystem, msg, fields)
+	}
+
+	output.Write(line)
+}
+
+func formatText(now time.Time, level Level, subsystem string, msg string, fields []field) []byte {
+	var b strings.Builder
+
+	b.WriteString(now.Format(time.RFC3339))
+	b.WriteString(" ")
+	b.WriteString(fmt.Sprintf("%-5s", strings.ToUpper(level.String())))
+	b.WriteString(" [")
+	b.WriteString(subsystem)
+	b.WriteString("] ")
+	b.WriteString(msg)
+
+	for _, f := range fields {
+		value := fmt.Sprint(f.value)
+		if strings.ContainsAny(value, " \t\"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		b.WriteString(" ")
+		b.WriteString(f.key)
+		b.WriteString("=")
+		b.WriteString(value)
+	}
+	b.WriteString("\n")
+
+	return []byte(b.String())
+}
+
+func formatJSON(now time.Time, level Level, subsystem string, msg string, fields []field) []byte {
+	entry := make(map[string]interface{}, len(fields)+4)
+	for _, f := range fields {
+		if err, ok := f.value.(error); ok {
+			entry[f.key] = err.Error()
+		} else {
+			entry[f.key] = f.value
+		}
+	}
+	entry["time"] = now.Format(time.RFC3339)
+	entry["level"] = level.String()
+	entry["subsystem"] = subsystem
+	entry["msg"] = msg
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		line, _ = json.Marshal(map[string]string{"level": "error", "msg": "unable to encode log entry: " + err.Error()})
+	}
+	return append(line, '\n')
+}
@@ -0,0 +1,180 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureLog sends every entry to the returned buffer, the returned
+// function restores the package defaults.
+func captureLog() (*bytes.Buffer, func()) {
+	var buffer bytes.Buffer
+	SetOutput(&buffer)
+
+	return &buffer, func() {
+		mux.Lock()
+		defer mux.Unlock()
+
+		output = os.Stderr
+		format = FormatText
+		defaultLevel = LevelInfo
+		subsystemLevels = make(map[string]Level)
+		globalFields = nil
+	}
+}
+
+func TestLevelFiltering(t *testing.T) {
+	buffer, restore := captureLog()
+	defer restore()
+
+	SetLevel(LevelWarn)
+	if err := SetSubsystemLevels("wire=debug, scheduler=error"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		subsystem string
+		level     Level
+		logged    bool
+	}{
+		{"phantom", LevelInfo, false},
+		{"phantom", LevelWarn, true},
+		{"wire", LevelDebug, true},
+		{"scheduler", LevelWarn, false},
+		{"scheduler", LevelError, true},
+	}
+
+	for _, test := range tests {
+		buffer.Reset()
+		logger := New(test.subsystem)
+		logger.log(test.level, "entry %d", 1)
+
+		if logged := buffer.Len() > 0; logged != test.logged {
+			t.Errorf("%s at %s: logged %t, want %t", test.subsystem, test.level, logged, test.logged)
+		}
+		if logger.Enabled(test.level) != test.logged {
+			t.Errorf("%s at %s: Enabled() = %t", test.subsystem, test.level, !test.logged)
+		}
+	}
+
+	if err := SetSubsystemLevels("wire"); err == nil {
+		t.Error("SetSubsystemLevels() accepted an entry without a level")
+	}
+	if err := SetSubsystemLevels("wire=loud"); err == nil {
+		t.Error("SetSubsystemLevels() accepted an unknown level")
+	}
+}
+
+func TestTextFormat(t *testing.T) {
+	buffer, restore := captureLog()
+	defer restore()
+
+	AddGlobalField("coin", "DASH")
+	New("scheduler").With("alias", "mn 1").With("count", 2).Warnf("skipped %s", "a slot")
+
+	line := buffer.String()
+	if !strings.HasSuffix(line, " WARN  [scheduler] skipped a slot coin=DASH alias=\"mn 1\" count=2\n") {
+		t.Errorf("text entry %q", line)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	buffer, restore := captureLog()
+	defer restore()
+
+	SetFormat(FormatJSON)
+	AddGlobalField("coin", "DASH")
+	parent := New("wire").With("peer", "45.50.22.125:9999")
+	parent.With("err", errors.New("connection reset")).Errorf("lost %d peers", 3)
+	parent.Infof("connected")
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%d entries written, want 2:\n%s", len(lines), buffer.String())
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("the entry %q isn't JSON: %s", lines[0], err)
+	}
+	want := map[string]string{
+		"level":     "error",
+		"subsystem": "wire",
+		"msg":       "lost 3 peers",
+		"coin":      "DASH",
+		"peer":      "45.50.22.125:9999",
+		"err":       "connection reset",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %q", key, entry[key], value)
+		}
+	}
+	if _, ok := entry["time"]; !ok {
+		t.Error("the entry has no time")
+	}
+
+	//the child's field isn't added to its parent
+	entry = nil
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := entry["err"]; ok || entry["msg"] != "connected" {
+		t.Errorf("the parent's entry is %s", lines[1])
+	}
+}
+
+func TestParseLevelAndFormat(t *testing.T) {
+	for _, name := range []string{"debug", "INFO", " warn", "warning", "error"} {
+		if _, err := ParseLevel(name); err != nil {
+			t.Errorf("ParseLevel(%q): %s", name, err)
+		}
+	}
+	if _, err := ParseLevel("trace"); err == nil {
+		t.Error("ParseLevel() accepted trace")
+	}
+
+	if f, err := ParseFormat(""); err != nil || f != FormatText {
+		t.Errorf("ParseFormat(\"\") = %d, %v", f, err)
+	}
+	if f, err := ParseFormat("JSON"); err != nil || f != FormatJSON {
+		t.Errorf("ParseFormat(\"JSON\") = %d, %v", f, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat() accepted xml")
+	}
+}
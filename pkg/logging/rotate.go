@@ -0,0 +1,122 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package logging
+
+import (
+	"os"
+	"strconv"
+	"sync"
+)
+
+// RotatingFile is an io.Writer that rolls the file over once it reaches
+// maxSize bytes, keeping maxBackups old copies as path.1, path.2, ...
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+	mux  sync.Mutex
+}
+
+func NewRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	rotating := &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	err := rotating.open()
+	if err != nil {
+		return nil, err
+	}
+	return rotating, nil
+}
+
+func (rotating *RotatingFile) Write(p []byte) (int, error) {
+	rotating.mux.Lock()
+	defer rotating.mux.Unlock()
+
+	if rotating.maxSize > 0 && rotating.size+int64(len(p)) > rotating.maxSize && rotating.size > 0 {
+		err := rotating.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rotating.file.Write(p)
+	rotating.size += int64(n)
+	return n, err
+}
+
+func (rotating *RotatingFile) Close() error {
+	rotating.mux.Lock()
+	defer rotating.mux.Unlock()
+
+	return rotating.file.Close()
+}
+
+func (rotating *RotatingFile) open() error {
+	file, err := os.OpenFile(rotating.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	rotating.file = file
+	rotating.size = info.Size()
+	return nil
+}
+
+func (rotating *RotatingFile) rotate() error {
+	rotating.file.Close()
+
+	if rotating.maxBackups > 0 {
+		for i := rotating.maxBackups - 1; i > 0; i-- {
+			os.Rename(rotating.backupName(i), rotating.backupName(i+1))
+		}
+		os.Rename(rotating.path, rotating.backupName(1))
+	} else {
+		os.Remove(rotating.path)
+	}
+
+	return rotating.open()
+}
+
+func (rotating *RotatingFile) backupName(i int) string {
+	return rotating.path + "." + strconv.Itoa(i)
+}
@@ -0,0 +1,154 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package logging
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "phantom.log")
+	if err := ioutil.WriteFile(path, []byte("0123\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rotating, err := NewRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rotating.Close()
+
+	//the existing 5 bytes count towards the first file
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeee\n", "ffff\n", "gggggggggggggggg\n"} {
+		if _, err := rotating.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"phantom.log", "gggggggggggggggg\n"},
+		{"phantom.log.1", "ffff\n"},
+		{"phantom.log.2", "dddd\neeee\n"},
+		{"phantom.log.3", ""},
+	}
+
+	for _, test := range tests {
+		data, err := ioutil.ReadFile(filepath.Join(dir, test.name))
+		if test.data == "" {
+			if !os.IsNotExist(err) {
+				t.Errorf("%s: kept past the backup limit", test.name)
+			}
+			continue
+		}
+		if err != nil || string(data) != test.data {
+			t.Errorf("%s: %q, %v, want %q", test.name, data, err, test.data)
+		}
+	}
+}
+
+func TestRotatingFileWithoutBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "phantom.log")
+	rotating, err := NewRotatingFile(path, 8, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rotating.Close()
+
+	for _, line := range []string{"aaaa\n", "bbbb\n"} {
+		if _, err := rotating.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("%d files in the log directory, want only the log", len(files))
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "bbbb\n" {
+		t.Errorf("the log is %q, want the last line", data)
+	}
+}
+
+func TestLoggerToRotatingFile(t *testing.T) {
+	_, restore := captureLog()
+	defer restore()
+
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "phantom.log")
+	rotating, err := NewRotatingFile(path, 200, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rotating.Close()
+
+	SetOutput(rotating)
+	SetFormat(FormatJSON)
+	logger := New("phantom")
+	for i := 0; i < 5; i++ {
+		logger.Infof("entry %d", i)
+	}
+
+	for _, name := range []string{path, path + ".1"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() == 0 || info.Size() > 200 {
+			t.Errorf("%s is %d bytes, want at most 200", name, info.Size())
+		}
+	}
+}
@@ -30,17 +30,21 @@
 package phantom
 
 import (
+	"bytes"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"github.com/breakcrypto/phantom/pkg/socket/wire"
 	"strconv"
+	"strings"
 )
 
 type Bootstrapper struct {
 	BaseURL string
+	HashDepth int
 }
 
 type GetPeerInfoResponse struct {
@@ -53,41 +57,92 @@ type PossiblePeer struct {
 }
 
 func (b Bootstrapper) LoadBlockHash() (chainhash.Hash, error) {
-	var strBlockHash string
+	blockCount, err := b.LoadBlockCount()
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	return b.LoadBlockHashAt(blockCount-b.HashDepth)
+}
 
+// LoadBlockCount returns the height of the explorer's chain tip.
+func (b Bootstrapper) LoadBlockCount() (int, error) {
 	response, err := http.Get(b.BaseURL + "/api/getblockcount")
 	if err != nil {
-		log.Printf("%s", err)
+		bootstrapLog.Warnf("Unable to load the block count from %s: %s", b.BaseURL, err)
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		bootstrapLog.Warnf("Unable to read the block count: %s", err)
+		return 0, err
+	}
+
+	blockCount, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+	if err != nil {
+		bootstrapLog.Warnf("Unable to parse the block count %q.", contents)
+		return 0, err
+	}
+	return blockCount, nil
+}
+
+// LoadBlockHashAt returns the hash of the block at height.
+func (b Bootstrapper) LoadBlockHashAt(height int) (chainhash.Hash, error) {
+	var strBlockHash string
+
+	response, err := http.Get(b.BaseURL + "/api/getblockhash?index=" + strconv.Itoa(height))
+	if err != nil {
+		bootstrapLog.Warnf("Unable to load the block hash from %s: %s", b.BaseURL, err)
 		return chainhash.Hash{}, err
 	} else {
 		defer response.Body.Close()
 		contents, err := ioutil.ReadAll(response.Body)
 		if err != nil {
-			log.Printf("%s", err)
+			bootstrapLog.Warnf("Unable to read the block hash: %s", err)
 			return chainhash.Hash{}, err
 		}
-		blockCount, _ := strconv.Atoi(string(contents))
 
-		response, err := http.Get(b.BaseURL + "/api/getblockhash?index=" + strconv.Itoa(blockCount-12))
-		if err != nil {
-			log.Printf("%s", err)
-			return chainhash.Hash{}, err
-		} else {
-			defer response.Body.Close()
-			contents, err = ioutil.ReadAll(response.Body)
-			if err != nil {
-				log.Printf("%s", err)
-				return chainhash.Hash{}, err
-			}
+		strBlockHash = string(contents)
+	}
 
-			strBlockHash = string(contents)
-		}
+	var blockHash chainhash.Hash
+	err = chainhash.Decode(&blockHash,strBlockHash)
+
+	return blockHash, err
+}
+
+// LoadTransaction fetches a raw transaction from the explorer.
+func (b Bootstrapper) LoadTransaction(hash chainhash.Hash) (*wire.MsgTx, error) {
+	response, err := http.Get(b.BaseURL + "/api/getrawtransaction?txid=" + hash.String() + "&decrypt=0")
+	if err != nil {
+		bootstrapLog.Warnf("Unable to load transaction %s from %s: %s", hash, b.BaseURL, err)
+		return nil, err
 	}
+	defer response.Body.Close()
 
-	var bootstrapHash chainhash.Hash
-	chainhash.Decode(&bootstrapHash,strBlockHash)
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		bootstrapLog.Warnf("Unable to read transaction %s: %s", hash, err)
+		return nil, err
+	}
+
+	raw, err := hex.DecodeString(strings.TrimSpace(string(contents)))
+	if err != nil {
+		return nil, fmt.Errorf("the explorer didn't return a raw transaction: %.80q", contents)
+	}
+
+	tx := &wire.MsgTx{}
+	err = tx.Deserialize(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the transaction: %s", err)
+	}
+	if tx.TxHash() != hash {
+		return nil, fmt.Errorf("the explorer returned transaction %s", tx.TxHash())
+	}
 
-	return bootstrapHash, nil
+	return tx, nil
 }
 
 func (b Bootstrapper) LoadPossiblePeers(portFilter uint16) ([]wire.NetAddress, error) {
@@ -95,20 +150,20 @@ func (b Bootstrapper) LoadPossiblePeers(portFilter uint16) ([]wire.NetAddress, e
 
 	response, err := http.Get(b.BaseURL + "/api/getpeerinfo")
 	if err != nil {
-		log.Printf("%s", err)
+		bootstrapLog.Warnf("Unable to load peers from %s: %s", b.BaseURL, err)
 		return nil, err
 	}
 
 	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		log.Printf("%s", err)
+		bootstrapLog.Warnf("Unable to read the peer list: %s", err)
 		return nil, err
 	}
 
 	var s = new([]PossiblePeer)
 	err = json.Unmarshal(body, &s)
 	if err != nil {
-		log.Printf("%s", err)
+		bootstrapLog.Warnf("Unable to parse the peer list: %s", err)
 		return nil, err
 	}
 
@@ -116,14 +171,14 @@ func (b Bootstrapper) LoadPossiblePeers(portFilter uint16) ([]wire.NetAddress, e
 		var possible wire.NetAddress
 
 		if possiblePeer.Addr != "" {
-			possible, _ = SplitAddress(possiblePeer.Addr)
+			possible, err = SplitAddress(possiblePeer.Addr)
 			if err == nil {
 				possiblePeers = addPossiblePeer(possible, possiblePeers, portFilter)
 			}
 		}
 
 		if possiblePeer.Addr != "" {
-			possible, _ = SplitAddress(possiblePeer.Addrlocal)
+			possible, err = SplitAddress(possiblePeer.Addrlocal)
 			if err == nil {
 				possiblePeers = addPossiblePeer(possible, possiblePeers, portFilter)
 			}
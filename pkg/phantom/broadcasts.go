@@ -0,0 +1,328 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantom
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// broadcastAnnounceLifetime is how long after its sigTime a new broadcast is
+// announced along with its pings when no list sync says otherwise.
+const broadcastAnnounceLifetime = 24 * time.Hour
+
+// BroadcastStore keeps the newest valid mnb seen for every collateral
+// outpoint and persists them so templates survive a restart.
+type BroadcastStore struct {
+	path       string
+	broadcasts map[string]wire.MsgMNB
+	lastSeen   map[string]time.Time
+	announce   map[string]time.Time //until when, zero until a list sync
+	dirty      bool                 //changed since the last save
+	mux        sync.RWMutex
+	saveMux    sync.Mutex
+
+	Clock        Clock
+	Profile      *wire.MessageProfile
+	MagicMessage string
+}
+
+func NewBroadcastStore(path string) *BroadcastStore {
+	return &BroadcastStore{
+		path:       path,
+		broadcasts: make(map[string]wire.MsgMNB),
+		lastSeen:   make(map[string]time.Time),
+		announce:   make(map[string]time.Time),
+	}
+}
+
+func OutpointKey(hash string, index uint32) string {
+	return hash + ":" + strconv.Itoa(int(index))
+}
+
+// Load reads the persisted broadcasts, a missing file is not an error.
+func (store *BroadcastStore) Load() error {
+	if store.path == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var encoded map[string]string
+	err = json.Unmarshal(data, &encoded)
+	if err != nil {
+		return err
+	}
+
+	//the cache is checked like broadcasts from peers, an edited or corrupt
+	//file mustn't become a template
+	now := clockOrDefault(store.Clock).Now()
+	loaded := make(map[string]wire.MsgMNB, len(encoded))
+	for outpoint, hexData := range encoded {
+		raw, err := hex.DecodeString(hexData)
+		if err != nil {
+			broadcastLog.With("outpoint", outpoint).Warnf("Skipping unreadable cached broadcast: %s", err)
+			continue
+		}
+
+		mnb := wire.MsgMNB{Profile: store.Profile}
+		err = mnb.BtcDecode(bytes.NewReader(raw), 0, wire.BaseEncoding)
+		if err != nil {
+			broadcastLog.With("outpoint", outpoint).Warnf("Skipping unreadable cached broadcast: %s", err)
+			continue
+		}
+
+		if mnb.Vin.PreviousOutPoint.String() != outpoint {
+			broadcastLog.With("outpoint", outpoint).Warnf("Skipping a cached broadcast stored under another outpoint.")
+			continue
+		}
+		err = validateBroadcast(&mnb, now, store.MagicMessage)
+		if err != nil {
+			broadcastLog.With("outpoint", outpoint).Warnf("Skipping invalid cached broadcast: %s", err)
+			continue
+		}
+
+		loaded[outpoint] = mnb
+	}
+
+	store.mux.Lock()
+	defer store.mux.Unlock()
+
+	for outpoint, mnb := range loaded {
+		store.broadcasts[outpoint] = mnb
+	}
+
+	return nil
+}
+
+// Save writes every stored broadcast to disk, replacing the file atomically.
+func (store *BroadcastStore) Save() error {
+	if store.path == "" {
+		return nil
+	}
+
+	store.saveMux.Lock()
+	defer store.saveMux.Unlock()
+
+	store.mux.RLock()
+	encoded := make(map[string]string, len(store.broadcasts))
+	for outpoint, mnb := range store.broadcasts {
+		var buf bytes.Buffer
+		err := mnb.Serialize(&buf)
+		if err != nil {
+			store.mux.RUnlock()
+			return err
+		}
+		encoded[outpoint] = hex.EncodeToString(buf.Bytes())
+	}
+	store.mux.RUnlock()
+
+	data, err := json.MarshalIndent(encoded, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := store.path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, store.path)
+}
+
+// Flush saves the broadcasts if any was stored since the last flush. A list
+// sync stores hundreds of them, so they're written out periodically rather
+// than one file rewrite each.
+func (store *BroadcastStore) Flush() error {
+	store.mux.Lock()
+	dirty := store.dirty
+	store.dirty = false
+	store.mux.Unlock()
+
+	if !dirty {
+		return nil
+	}
+
+	err := store.Save()
+	if err != nil {
+		store.mux.Lock()
+		store.dirty = true
+		store.mux.Unlock()
+	}
+	return err
+}
+
+// Add records that a valid mnb has been seen on the network and keeps it
+// if it's newer than the broadcast already stored for its outpoint. Invalid
+// broadcasts don't count as seen, a forged one mustn't hide a masternode
+// that dropped from the list.
+func (store *BroadcastStore) Add(mnb wire.MsgMNB) (bool, error) {
+	outpoint := mnb.Vin.PreviousOutPoint.String()
+	now := clockOrDefault(store.Clock).Now()
+
+	//recovering the signing keys is slow, don't hold up readers for it
+	err := validateBroadcast(&mnb, now, store.MagicMessage)
+	if err != nil {
+		return false, err
+	}
+
+	store.mux.Lock()
+	defer store.mux.Unlock()
+
+	store.lastSeen[outpoint] = now
+
+	current, ok := store.broadcasts[outpoint]
+	if ok && current.SigTime >= mnb.SigTime {
+		return false, nil
+	}
+
+	store.broadcasts[outpoint] = mnb
+	store.dirty = true
+	if !ok {
+		//announce new templates until a list sync shows the network has them,
+		//or for a day without syncs
+		store.announce[outpoint] = time.Unix(int64(mnb.SigTime), 0).Add(broadcastAnnounceLifetime)
+	}
+
+	return true, nil
+}
+
+func (store *BroadcastStore) Get(outpoint string) (wire.MsgMNB, bool) {
+	store.mux.RLock()
+	defer store.mux.RUnlock()
+
+	mnb, ok := store.broadcasts[outpoint]
+	return mnb, ok
+}
+
+// All returns every stored broadcast.
+func (store *BroadcastStore) All() []wire.MsgMNB {
+	store.mux.RLock()
+	defer store.mux.RUnlock()
+
+	broadcasts := make([]wire.MsgMNB, 0, len(store.broadcasts))
+	for _, mnb := range store.broadcasts {
+		broadcasts = append(broadcasts, mnb)
+	}
+	return broadcasts
+}
+
+func (store *BroadcastStore) LastSeen(outpoint string) time.Time {
+	store.mux.RLock()
+	defer store.mux.RUnlock()
+
+	return store.lastSeen[outpoint]
+}
+
+// SetAnnounce flags whether the outpoint's mnb should ride along with its
+// next pings.
+func (store *BroadcastStore) SetAnnounce(outpoint string, announce bool) {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+
+	if announce {
+		store.announce[outpoint] = time.Time{}
+	} else {
+		delete(store.announce, outpoint)
+	}
+}
+
+func (store *BroadcastStore) NeedsAnnounce(outpoint string) bool {
+	store.mux.RLock()
+	defer store.mux.RUnlock()
+
+	until, ok := store.announce[outpoint]
+	if !ok {
+		return false
+	}
+	return until.IsZero() || clockOrDefault(store.Clock).Now().Before(until)
+}
+
+// validateBroadcast checks the keys, the sigTime and that the collateral
+// key signed the broadcast, in either signature scheme.
+func validateBroadcast(mnb *wire.MsgMNB, now time.Time, magicMessage string) error {
+	collateralKey, err := btcec.ParsePubKey(mnb.PubKeyCollateralAddress, btcec.S256())
+	if err != nil {
+		return errors.New("invalid collateral public key: " + err.Error())
+	}
+
+	_, err = btcec.ParsePubKey(mnb.PubKeyMasternode, btcec.S256())
+	if err != nil {
+		return errors.New("invalid masternode public key: " + err.Error())
+	}
+
+	if len(mnb.Sig) == 0 {
+		return errors.New("missing signature")
+	}
+
+	sigTime := time.Unix(int64(mnb.SigTime), 0)
+	if sigTime.After(now.Add(time.Hour)) {
+		return errors.New("signature time is in the future")
+	}
+
+	hash := mnb.SignatureHash()
+	if !signedBy(mnb.Sig, collateralKey, hash[:], signedMessageHash(magicMessage, mnb.SignatureMessage()),
+		signedMessageHash(magicMessage, mnb.OldSignatureMessage())) {
+		return errors.New("not signed by the collateral key")
+	}
+
+	return nil
+}
+
+// SeenSince counts the outpoints that have had a broadcast seen after t.
+func (store *BroadcastStore) SeenSince(t time.Time) int {
+	store.mux.RLock()
+	defer store.mux.RUnlock()
+
+	count := 0
+	for _, seen := range store.lastSeen {
+		if !seen.Before(t) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,290 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantom
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testMagicMessage = "DarkCoin Signed Message:\n"
+
+// fixedClock is a Clock stopped at a point in time. phantomtest.FakeClock
+// can't be used from inside the package.
+type fixedClock time.Time
+
+func (clock fixedClock) Now() time.Time {
+	return time.Time(clock)
+}
+
+func (clock fixedClock) NewTimer(d time.Duration) Timer {
+	return SystemClock.NewTimer(d)
+}
+
+// signedBroadcast returns an mnb for outpoint index signed by collateral
+// with the given scheme.
+func signedBroadcast(t *testing.T, collateral *btcec.PrivateKey, index uint32, sigTime time.Time,
+	scheme SignatureScheme) wire.MsgMNB {
+
+	masternode, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mnb := wire.MsgMNB{
+		Vin:                     *wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, index), nil, nil),
+		PubKeyCollateralAddress: collateral.PubKey().SerializeCompressed(),
+		PubKeyMasternode:        masternode.PubKey().SerializeCompressed(),
+		SigTime:                 uint64(sigTime.Unix()),
+		ProtocolVersion:         70208,
+	}
+	copy(mnb.Addr.IpAddress[:], net.ParseIP("1.2.3.4").To16())
+	mnb.Addr.Port = 9999
+
+	hash := mnb.SignatureHash()
+	digest := hash[:]
+	if scheme == SignatureLegacy {
+		digest = signedMessageHash(testMagicMessage, mnb.SignatureMessage())
+	}
+
+	mnb.Sig, err = btcec.SignCompact(btcec.S256(), collateral, digest, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mnb
+}
+
+func TestBroadcastStoreAdd(t *testing.T) {
+	start := time.Unix(1555555555, 0)
+
+	collateral, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	forger, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forged := signedBroadcast(t, forger, 0, start.Add(time.Minute), SignatureLegacy)
+	forged.PubKeyCollateralAddress = collateral.PubKey().SerializeCompressed()
+
+	oldSigned := signedBroadcast(t, collateral, 0, start, SignatureLegacy)
+	oldSigned.Sig, _ = btcec.SignCompact(btcec.S256(), collateral,
+		signedMessageHash(testMagicMessage, oldSigned.OldSignatureMessage()), false)
+
+	tests := []struct {
+		name   string
+		mnb    wire.MsgMNB
+		stored bool
+		valid  bool
+	}{
+		{"legacy", signedBroadcast(t, collateral, 0, start, SignatureLegacy), true, true},
+		{"hash", signedBroadcast(t, collateral, 1, start, SignatureHash), true, true},
+		{"raw key message", oldSigned, true, true},
+		{"forged", forged, false, false},
+		{"future", signedBroadcast(t, collateral, 3, start.Add(2*time.Hour), SignatureLegacy), false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewBroadcastStore("")
+			store.Clock = fixedClock(start)
+			store.MagicMessage = testMagicMessage
+
+			stored, err := store.Add(test.mnb)
+			if (err == nil) != test.valid {
+				t.Fatalf("Add() error = %v, want valid %t", err, test.valid)
+			}
+			if stored != test.stored {
+				t.Errorf("Add() stored = %t, want %t", stored, test.stored)
+			}
+
+			seen := store.LastSeen(test.mnb.Vin.PreviousOutPoint.String())
+			if seen.IsZero() == test.valid {
+				t.Errorf("LastSeen() = %s, valid broadcast %t", seen, test.valid)
+			}
+		})
+	}
+}
+
+func TestBroadcastStoreKeepsNewest(t *testing.T) {
+	start := time.Unix(1555555555, 0)
+	collateral, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewBroadcastStore("")
+	store.Clock = fixedClock(start)
+	store.MagicMessage = testMagicMessage
+
+	newer := signedBroadcast(t, collateral, 0, start, SignatureLegacy)
+	older := signedBroadcast(t, collateral, 0, start.Add(-time.Hour), SignatureLegacy)
+
+	if stored, err := store.Add(newer); !stored || err != nil {
+		t.Fatalf("Add(newer) = %t, %v", stored, err)
+	}
+	if stored, err := store.Add(older); stored || err != nil {
+		t.Fatalf("Add(older) = %t, %v, want it ignored", stored, err)
+	}
+
+	kept, _ := store.Get(newer.Vin.PreviousOutPoint.String())
+	if kept.SigTime != newer.SigTime {
+		t.Errorf("kept sigTime %d, want %d", kept.SigTime, newer.SigTime)
+	}
+}
+
+func TestBroadcastStoreFlush(t *testing.T) {
+	start := time.Unix(1555555555, 0)
+	collateral, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "phantom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "broadcasts.json")
+
+	store := NewBroadcastStore(path)
+	store.Clock = fixedClock(start)
+	store.MagicMessage = testMagicMessage
+
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Flush() without changes wrote the cache: %v", err)
+	}
+
+	for i := uint32(0); i < 3; i++ {
+		if _, err := store.Add(signedBroadcast(t, collateral, i, start, SignatureHash)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Add() wrote the cache: %v", err)
+	}
+
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//nothing new, the file is left alone
+	if err := os.Chtimes(path, start, start); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.Stat(path); !after.ModTime().Equal(start) || after.Size() != info.Size() {
+		t.Error("Flush() rewrote an unchanged cache")
+	}
+
+	loaded := NewBroadcastStore(path)
+	loaded.Clock = fixedClock(start)
+	loaded.MagicMessage = testMagicMessage
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(loaded.All()); got != 3 {
+		t.Errorf("loaded %d broadcasts, want 3", got)
+	}
+}
+
+func TestBroadcastStoreLoadRevalidates(t *testing.T) {
+	start := time.Unix(1555555555, 0)
+	collateral, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := signedBroadcast(t, collateral, 0, start, SignatureHash)
+	tampered := signedBroadcast(t, collateral, 1, start, SignatureHash)
+	tampered.ProtocolVersion++
+	moved := signedBroadcast(t, collateral, 2, start, SignatureHash)
+
+	encoded := make(map[string]string)
+	for outpoint, mnb := range map[string]wire.MsgMNB{
+		valid.Vin.PreviousOutPoint.String():        valid,
+		tampered.Vin.PreviousOutPoint.String():     tampered,
+		OutpointKey(chainhash.Hash{9}.String(), 0): moved,
+	} {
+		var buf bytes.Buffer
+		if err := mnb.Serialize(&buf); err != nil {
+			t.Fatal(err)
+		}
+		encoded[outpoint] = hex.EncodeToString(buf.Bytes())
+	}
+	data, err := json.Marshal(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "phantom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "broadcasts.json")
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewBroadcastStore(path)
+	store.Clock = fixedClock(start)
+	store.MagicMessage = testMagicMessage
+	if err := store.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	all := store.All()
+	if len(all) != 1 || all[0].Vin.PreviousOutPoint != valid.Vin.PreviousOutPoint {
+		t.Errorf("loaded %d broadcasts, want only the valid one", len(all))
+	}
+}
@@ -27,13 +27,15 @@
 *    it in the license file.
 */
 
+
 package phantom
 
 import (
 	"bufio"
 	"bytes"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
-	"log"
+	"github.com/breakcrypto/phantom/pkg/logging"
+	"io"
 	"net"
 	"github.com/breakcrypto/phantom/pkg/socket/wire"
 	"strconv"
@@ -42,6 +44,15 @@ import (
 	"time"
 )
 
+const (
+	dialTimeout   = 30 * time.Second
+	readTimeout   = 10 * time.Minute //peers ping us every couple of minutes
+	writeTimeout  = 30 * time.Second
+	outboundDepth = 100
+	inboundDepth  = 100
+	relayLifetime = 10 * time.Minute //how long relayed votes are served
+)
+
 type PingerConnection struct {
 	MagicBytes uint32
 	IpAddress string
@@ -49,19 +60,178 @@ type PingerConnection struct {
 	ProtocolNumber uint32
 	SentinelVersion uint32
 	DaemonVersion uint32
+	Profile *wire.MessageProfile
 	BootstrapHash chainhash.Hash
 	PingChannel chan MasternodePing
+	//the reader never waits on these, give them a buffer, anything that
+	//doesn't fit is dropped and counted in Dropped
 	AddrChannel chan wire.NetAddress
 	HashChannel chan chainhash.Hash
 	BroadcastChannel chan wire.MsgMNB
+	SporkChannel chan wire.MsgSpork
+	GovernanceChannel chan wire.MsgGovObj
+	PaymentChannel chan wire.MsgMNW
+	VerificationChannel chan Verification
+	RejectChannel chan Rejection
+	TxChannel chan wire.MsgTx
+	Headers *HeaderTracker
+	RelayChannel chan RelayMessage
 	Status int8
 	WaitGroup *sync.WaitGroup
 	Mutex sync.Mutex
+
+	Clock Clock
+
+	session *peerSession
+	log     *logging.Logger
+
+	//when relayed messages stop being served, only used by dispatch
+	relayExpiry map[string]time.Time
+
+	//alias of every relayed ping and broadcast, to tie rejects back to a
+	//masternode
+	relayedAliases map[string]string
+
+	//messages dropped because their consumer fell behind, by command
+	dropped map[string]uint64
+}
+
+// Rejection is a reject received from a peer, tied back to the masternode
+// whose message was rejected when it's one of ours.
+type Rejection struct {
+	Reject   wire.MsgReject
+	Peer     string
+	Hash     string
+	Alias    string
+	Outpoint string
+}
+
+// RelayMessage is a message announced to the peer by inv and served from
+// the message map when the peer asks for it.
+type RelayMessage struct {
+	Inv     wire.InvVect
+	Message wire.Message
+}
+
+// peerSession is a single TCP connection to a peer. The reader goroutine
+// feeds inbound, the writer goroutine is the only one touching the socket
+// for writes and drains outbound.
+type peerSession struct {
+	conn     net.Conn
+	magic    wire.BitcoinNet
+	pver     uint32
+	profile  *wire.MessageProfile
+	log      *logging.Logger
+	inbound  chan wire.Message
+	outbound chan wire.Message
+	errs     chan error
+	done     chan struct{}
+	once     sync.Once
+}
+
+func newPeerSession(conn net.Conn, peer string, pver uint32, magic wire.BitcoinNet,
+	profile *wire.MessageProfile) *peerSession {
+	return &peerSession{
+		conn:     conn,
+		magic:    magic,
+		pver:     pver,
+		profile:  profile,
+		log:      wireLog.With("peer", peer),
+		inbound:  make(chan wire.Message, inboundDepth),
+		outbound: make(chan wire.Message, outboundDepth),
+		errs:     make(chan error, 2),
+		done:     make(chan struct{}),
+	}
+}
+
+// send queues msg for the writer without ever blocking the caller.
+func (session *peerSession) send(msg wire.Message) bool {
+	select {
+	case session.outbound <- msg:
+		return true
+	case <-session.done:
+		return false
+	default:
+		session.log.With("command", msg.Command()).Warnf("Outbound queue full, dropping message.")
+		return false
+	}
+}
+
+func (session *peerSession) fail(err error) {
+	select {
+	case session.errs <- err:
+	default: //an error is already pending
+	}
+}
+
+func (session *peerSession) close() {
+	session.once.Do(func() {
+		close(session.done)
+		session.conn.Close()
+	})
+}
+
+func (session *peerSession) writeLoop() {
+	for {
+		select {
+		case msg := <-session.outbound:
+			session.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			_, err := wire.WriteMessageN(session.conn, msg, session.pver, session.magic)
+			session.log.With("command", msg.Command()).Debugf("Sent message.")
+			if err != nil {
+				session.fail(err)
+				return
+			}
+		case <-session.done:
+			return
+		}
+	}
+}
+
+func (session *peerSession) readLoop() {
+	bufReader := bufio.NewReader(session.conn)
+	decodeErrors := 0
+
+	for {
+		session.conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		_, msg, _, err := wire.ReadMessageWithProfileN(bufReader, session.pver, session.magic, session.profile)
+		if err != nil {
+			if strings.Contains(err.Error(), "unhandled command") {
+				session.log.Debugf("%s", err)
+				continue
+			}
+
+			if _, ok := err.(net.Error); ok || err == io.EOF {
+				session.fail(err)
+				return
+			}
+
+			//malformed message, the stream is still aligned so keep going
+			session.log.Warnf("Unable to decode message: %s", err)
+			decodeErrors++
+			if decodeErrors >= 10 {
+				session.fail(err)
+				return
+			}
+			continue
+		}
+
+		decodeErrors = 0
+		session.log.With("command", msg.Command()).Debugf("Received message.")
+
+		select {
+		case session.inbound <- msg:
+		case <-session.done:
+			return
+		}
+	}
 }
 
 func (pinger *PingerConnection) Start(userAgent string) {
 
-	log.Printf("%s : STARTING CLIENT\n", pinger.IpAddress)
+	pinger.log = peerLog.With("peer", pinger.IpAddress)
+	pinger.log.Infof("Starting client.")
 
 	//make sure we close out the waitGroup
 	defer pinger.WaitGroup.Done()
@@ -89,7 +259,7 @@ func (pinger *PingerConnection) Start(userAgent string) {
 	version := wire.MsgVersion{
 		ProtocolVersion: int32(pinger.ProtocolNumber),
 		Services:        0,
-		Timestamp:       time.Unix(time.Now().Unix(), 0),
+		Timestamp:       time.Unix(clockOrDefault(pinger.Clock).Now().Unix(), 0),
 		AddrYou:         you,
 		AddrMe:          me,
 		Nonce:           0xDEADBEEF,
@@ -100,7 +270,7 @@ func (pinger *PingerConnection) Start(userAgent string) {
 
 	tcpAddr, err := net.ResolveTCPAddr("tcp4", pinger.IpAddress + ":" + strconv.Itoa(int(pinger.Port)))
 	if(err != nil) {
-		log.Println(err)
+		pinger.log.Errorf("Unable to resolve the peer address: %s", err)
 		pinger.SetStatus(-1)
 		return
 	}
@@ -110,239 +280,494 @@ func (pinger *PingerConnection) Start(userAgent string) {
 
 	for {
 
-		if connectionAttempts >= 10 || len(pinger.PingChannel) > 10 {
-			log.Println("Unable to connect -- closing connection / channel too full.")
+		if connectionAttempts >= 10 {
+			pinger.log.Warnf("Unable to connect -- closing connection.")
 			pinger.SetStatus(-1)
 			return
 		}
 
-		conn, err := net.DialTCP("tcp", nil, tcpAddr)
+		conn, err := net.DialTimeout("tcp", tcpAddr.String(), dialTimeout)
 		if (err != nil) {
-			log.Println(err)
+			pinger.log.Warnf("Unable to connect: %s", err)
 			connectionAttempts++
+			if !pinger.waitForReconnect(10 * time.Second) {
+				return
+			}
 			continue
 		}
 
-		var buf bytes.Buffer
-		wire.WriteMessageN(&buf, &version, pinger.ProtocolNumber, magic)
-		conn.Write(buf.Bytes())
+		session := newPeerSession(conn, pinger.IpAddress, pinger.ProtocolNumber, magic, pinger.Profile)
+		go session.writeLoop()
+		go session.readLoop()
 
-		bufReader := bufio.NewReader(conn)
+		session.send(&version)
+		pinger.setSession(session)
 
-		for {
+		if !pinger.dispatch(session, messageMap) {
+			pinger.setSession(nil)
+			session.close()
+			return
+		}
+		pinger.setSession(nil)
+		session.close()
+		pinger.SetStatus(0) //stop receiving pings until we're reconnected
 
-			if pinger.GetStatus() < 0 {
-				return
+		//we've disconnected, so try again
+		connectionAttempts++
+		pinger.log.Warnf("There's been an error, attempting to reconnect.")
+		if !pinger.waitForReconnect(1 * time.Minute) {
+			return
+		}
+	}
+}
+
+// dispatch handles inbound messages and outbound pings for one session. It
+// returns false once the pinger has been shut down or has dropped a
+// misbehaving peer, true on a disconnect.
+func (pinger *PingerConnection) dispatch(session *peerSession, messageMap map[string]wire.Message) bool {
+	for {
+		select {
+		case msg := <-session.inbound:
+			if !pinger.handleMessage(session, msg, messageMap) {
+				return false
 			}
 
-			//connection failed, set the status to -1 and let it be reaped
-			if connectionAttempts >= 10 || len(pinger.PingChannel) > 10 {
-				log.Println("Unable to connect -- closing connection / channel too full (inside).")
-				pinger.SetStatus(-1)
-				return
+		case ping, ok := <-pinger.PingChannel:
+			if !ok {
+				return false //the pinger has been reaped
 			}
+			pinger.relayPing(session, ping, messageMap)
 
-			_, msg, _, err := wire.ReadMessageN(bufReader, pinger.ProtocolNumber, magic)
+		case relay := <-pinger.RelayChannel:
+			inv := wire.MsgInv{}
+			inv.AddInvVect(&relay.Inv)
+			session.send(&inv)
 
-			if (err != nil) {
-				if strings.Contains(err.Error(), "unhandled command") {
-					//log.Println(err)
-					continue
-				}
-				log.Printf("%s : %s\n", pinger.IpAddress, err)
-				connectionAttempts++
-				continue
-			} else {
+			messageMap[relay.Inv.Hash.String()] = relay.Message
 
-				//log.Println("COMMAND: ", msg.Command())
+			if pinger.relayExpiry == nil {
+				pinger.relayExpiry = make(map[string]time.Time)
+			}
+			pinger.relayExpiry[relay.Inv.Hash.String()] = clockOrDefault(pinger.Clock).Now().Add(relayLifetime)
 
-				connectionAttempts = 0
+		case err := <-session.errs:
+			pinger.log.Warnf("Connection lost: %s", err)
+			return true
+		}
+	}
+}
 
-				if (msg.Command() == "inv") {
-					inv := msg.(*wire.MsgInv)
-					for _, inventory := range (inv.InvList) {
-						if inventory.Type.String() == "MSG_BLOCK" {
-							log.Println("New block received: \n" + inventory.Hash.String())
-							pinger.HashChannel <- inventory.Hash
-						}
+// waitForReconnect sleeps before the next dial, discarding pings that arrive
+// while there's no connection to send them on.
+func (pinger *PingerConnection) waitForReconnect(delay time.Duration) bool {
+	timer := clockOrDefault(pinger.Clock).NewTimer(delay)
+	defer timer.Stop()
 
-						if inventory.Type.String() == "Unknown InvType (14)" {
-							//MNANNOUNCE RECEIVED FOR OUR NODE
-							getdata := wire.MsgGetData{}
-							getdata.AddInvVect(inventory)
+	for {
+		select {
+		case <-timer.C():
+			return true
+		case ping, ok := <-pinger.PingChannel:
+			if !ok {
+				return false
+			}
+			pinger.log.With("alias", ping.Name).Warnf("Not connected, dropping ping.")
+		}
+	}
+}
 
-							var buf bytes.Buffer
-							wire.WriteMessageN(&buf, &getdata, pinger.ProtocolNumber, magic)
-							conn.Write(buf.Bytes())
-						}
+// handleMessage acts on a message from the peer, it returns false when the
+// peer misbehaved and has to be dropped.
+func (pinger *PingerConnection) handleMessage(session *peerSession, msg wire.Message, messageMap map[string]wire.Message) bool {
+
+	if (msg.Command() == "inv") {
+		inv := msg.(*wire.MsgInv)
+		for _, inventory := range (inv.InvList) {
+			if inventory.Type == wire.InvTypeBlock {
+				pinger.log.Infof("New block received: %s", inventory.Hash.String())
+				if pinger.Headers == nil {
+					select {
+					case pinger.HashChannel <- inventory.Hash:
+					default:
+						pinger.drop("block")
 					}
+				} else if getheaders := pinger.Headers.Announced(inventory.Hash); getheaders != nil {
+					//the hash is only used once its header checks out
+					getheaders.ProtocolVersion = pinger.ProtocolNumber
+					session.send(getheaders)
 				}
+			}
 
-				if (msg.Command() == "version") {
-					verack := wire.MsgVerAck{}
+			if inventory.Type == wire.InvTypeMasternodeAnnounce {
+				//MNANNOUNCE RECEIVED FOR OUR NODE
+				getdata := wire.MsgGetData{}
+				getdata.AddInvVect(inventory)
 
-					var buf bytes.Buffer
-					wire.WriteMessageN(&buf, &verack, pinger.ProtocolNumber, magic)
-					conn.Write(buf.Bytes())
+				session.send(&getdata)
+			}
 
-					pinger.SetStatus(1) //we're connected and ready to start pinging
+			if inventory.Type == wire.InvTypeMasternodeWinner && pinger.PaymentChannel != nil {
+				getdata := wire.MsgGetData{}
+				getdata.AddInvVect(inventory)
 
-					//ignore the request but relay our own 'getaddr' request
-					getaddr := wire.MsgGetAddr{}
+				session.send(&getdata)
+			}
 
-					var bufAddr bytes.Buffer
-					wire.WriteMessageN(&bufAddr, &getaddr, pinger.ProtocolNumber, magic)
-					conn.Write(bufAddr.Bytes())
+			if inventory.Type == wire.InvTypeMasternodeVerify && pinger.VerificationChannel != nil {
+				getdata := wire.MsgGetData{}
+				getdata.AddInvVect(inventory)
 
-					log.Println("Sending getaddr")
+				session.send(&getdata)
+			}
 
-					defaultHash := chainhash.Hash{}
-					if pinger.BootstrapHash != defaultHash {
-						getblocks := wire.MsgGetBlocks{}
+			if inventory.Type == wire.InvTypeGovernanceObject && pinger.GovernanceChannel != nil {
+				getdata := wire.MsgGetData{}
+				getdata.AddInvVect(inventory)
 
-						getblocks.BlockLocatorHashes = []*chainhash.Hash{&pinger.BootstrapHash}
-						getblocks.ProtocolVersion = pinger.ProtocolNumber
+				session.send(&getdata)
+			}
 
-						var bufBlocks bytes.Buffer
-						wire.WriteMessageN(&bufBlocks, &getblocks, pinger.ProtocolNumber, magic)
-						conn.Write(bufBlocks.Bytes())
+			if inventory.Type == wire.InvTypeSpork && pinger.SporkChannel != nil {
+				getdata := wire.MsgGetData{}
+				getdata.AddInvVect(inventory)
 
-						log.Println("Sending getblocks to bootstrap")
-					}
-				}
+				session.send(&getdata)
+			}
+		}
+	}
 
-				if (msg.Command() == "ping") {
-
-					ping := msg.(*wire.MsgPing)
-
-					pong := wire.MsgPong{Nonce: ping.Nonce}
-
-					var buf bytes.Buffer
-					wire.WriteMessageN(&buf, &pong, pinger.ProtocolNumber, magic)
-					conn.Write(buf.Bytes())
-
-					log.Printf("%s : PONG!\n", pinger.IpAddress)
-
-					//clear out the message map
-					for hash, message := range messageMap {
-						if message.Command() == "mnp" {
-							ping := message.(*wire.MsgMNP)
-							pingTime := time.Unix(int64(ping.SigTime), 0)
-							//if the ping is more than 5 minutes old, delete it
-							if pingTime.Add(time.Minute * 5).Before(time.Now().UTC()) {
-								delete(messageMap, hash)
-							}
-						}
-
-						if message.Command() == "mnb" {
-							mnb := message.(*wire.MsgMNB)
-							pingTime := time.Unix(int64(mnb.LastPing.SigTime), 0)
-							//if the ping is more than 5 minutes old, delete it
-							if pingTime.Add(time.Minute * 5).Before(time.Now().UTC()) {
-								delete(messageMap, hash)
-							}
-						}
-					}
-				}
+	if (msg.Command() == "version") {
+		verack := wire.MsgVerAck{}
+		session.send(&verack)
 
-				if (msg.Command() == "addr") {
-					msgAddr := msg.(*wire.MsgAddr)
-					for _, addr := range msgAddr.AddrList {
-						//log.Println("PEER: ", addr.IP, ":", addr.Port)
-						pinger.AddrChannel <- *addr
-					}
-				}
+		pinger.SetStatus(1) //we're connected and ready to start pinging
 
-				//let broadcast channels relay back broadcasts
-				if (msg.Command() == "mnb") {
-					mnb := msg.(*wire.MsgMNB)
-					if pinger.BroadcastChannel != nil {
-						log.Println("Masternode broadcast detected for: ", mnb.Vin.PreviousOutPoint.String())
-						pinger.BroadcastChannel <- *mnb
-					}
-				}
+		//ignore the request but relay our own 'getaddr' request
+		getaddr := wire.MsgGetAddr{}
+		session.send(&getaddr)
 
-				//non-blocking select
-				select {
-					case ping := <-pinger.PingChannel:
-						log.Printf("REQUEST RECIEVED, RELAYING: %s\n",
-							ping.Name)
+		pinger.log.Debugf("Sending getaddr")
 
-						mnp := ping.GenerateMasternodePing(pinger.SentinelVersion, pinger.DaemonVersion)
+		if pinger.SporkChannel != nil {
+			session.send(&wire.MsgGetSporks{})
+			pinger.log.Debugf("Sending getsporks")
+		}
 
-						//check to see if this is a broadcast relay
-						if ping.BroadcastTemplate != nil {
-							//USING BROADCAST TEMPLATE
+		defaultHash := chainhash.Hash{}
+		if pinger.BootstrapHash != defaultHash {
+			getblocks := wire.MsgGetBlocks{}
 
-							mnb := *ping.BroadcastTemplate
-							mnb.LastPing = mnp
+			getblocks.BlockLocatorHashes = []*chainhash.Hash{&pinger.BootstrapHash}
+			getblocks.ProtocolVersion = pinger.ProtocolNumber
 
-							inv := wire.MsgInv{}
-							invVec := wire.InvVect{}
-							invVec.Type = 14
-							invVec.Hash = mnb.GetHash()
-							inv.AddInvVect(&invVec)
+			session.send(&getblocks)
 
-							var buf bytes.Buffer
-							wire.WriteMessageN(&buf, &inv, pinger.ProtocolNumber, magic)
+			pinger.log.Infof("Sending getblocks to bootstrap")
+		}
+	}
 
-							byteData := buf.Bytes()
+	if (msg.Command() == "ping") {
 
-							conn.Write(byteData)
+		ping := msg.(*wire.MsgPing)
 
-							messageMap[invVec.Hash.String()] = &mnb
-						}
+		pong := wire.MsgPong{Nonce: ping.Nonce}
+		session.send(&pong)
 
-						//ALWAYS SEND THE PINGS
-						//serialize to a []byte
-						w := new(bytes.Buffer)
-						mnp.Serialize(w)
-						mnpBytes := w.Bytes()
+		pinger.log.Debugf("PONG!")
 
-						inv := wire.MsgInv{}
-						invVec := wire.InvVect{}
-						invVec.Type = 15
-						invVec.Hash = chainhash.DoubleHashH(mnpBytes)
-						inv.AddInvVect(&invVec)
+		//clear out the message map
+		now := clockOrDefault(pinger.Clock).Now()
+		expireMessages(messageMap, now)
+		for hash, expiry := range pinger.relayExpiry {
+			if expiry.Before(now) {
+				delete(messageMap, hash)
+				delete(pinger.relayExpiry, hash)
+			}
+		}
+		for hash := range pinger.relayedAliases {
+			if _, ok := messageMap[hash]; !ok {
+				delete(pinger.relayedAliases, hash)
+			}
+		}
+	}
 
-						//send the ping inv
-						var buf bytes.Buffer
-						wire.WriteMessageN(&buf, &inv, pinger.ProtocolNumber, magic)
-						conn.Write(buf.Bytes())
+	if (msg.Command() == "headers" && pinger.Headers != nil) {
+		headers := msg.(*wire.MsgHeaders)
+		valid, rejected := pinger.Headers.Headers(headers.Headers, pinger.IpAddress)
+		for _, hash := range valid {
+			select {
+			case pinger.HashChannel <- hash:
+			default:
+				pinger.drop("block")
+			}
+		}
 
-						//store the ping
-						messageMap[invVec.Hash.String()] = &mnp
+		if rejected > 0 {
+			pinger.log.Warnf("Disconnecting, the peer served %d block header(s) with invalid proof of work.", rejected)
+			pinger.SetStatus(-1)
+			return false
+		}
+	}
 
-					default:
-						//fmt.Println("no message received")
-				}
+	if (msg.Command() == "reject") {
+		pinger.handleReject(msg.(*wire.MsgReject), messageMap)
+	}
 
-				//this should really be a hashMap with expiring entries
-				if (msg.Command() == "getdata") {
+	if (msg.Command() == "addr") {
+		msgAddr := msg.(*wire.MsgAddr)
+		for _, addr := range msgAddr.AddrList {
+			select {
+			case pinger.AddrChannel <- *addr:
+			default:
+				pinger.drop("addr")
+			}
+		}
+	}
 
-					getData := msg.(*wire.MsgGetData)
+	//let broadcast channels relay back broadcasts
+	if (msg.Command() == "mnb") {
+		mnb := msg.(*wire.MsgMNB)
+		if pinger.BroadcastChannel != nil {
+			pinger.log.With("outpoint", mnb.Vin.PreviousOutPoint.String()).Infof("Masternode broadcast detected.")
+			select {
+			case pinger.BroadcastChannel <- *mnb:
+			default:
+				pinger.drop("mnb")
+			}
+		}
+	}
 
-					for _, inv := range getData.InvList {
-						//check the map
-						str := inv.Hash.String()
-						if val, ok := messageMap[str]; ok {
-							//do something here
-							var buf bytes.Buffer
-							wire.WriteMessageN(&buf, val, pinger.ProtocolNumber, magic)
-							conn.Write(buf.Bytes())
-						}
-					}
-				}
+	if (msg.Command() == "spork") {
+		spork := msg.(*wire.MsgSpork)
+		if pinger.SporkChannel != nil {
+			pinger.log.With("spork", SporkName(spork.SporkID)).Debugf("Spork received.")
+			select {
+			case pinger.SporkChannel <- *spork:
+			default:
+				pinger.drop("spork")
 			}
 		}
+	}
 
-		//we've disconnected, so try again
-		connectionAttempts++
-		log.Printf("%s : There's been an error, attempting to reconnect.\n", pinger.IpAddress)
-		time.Sleep(1 * time.Minute)
+	if (msg.Command() == "mnw") {
+		mnw := msg.(*wire.MsgMNW)
+		if pinger.PaymentChannel != nil {
+			select {
+			case pinger.PaymentChannel <- *mnw:
+			default:
+				pinger.drop("mnw")
+			}
+		}
+	}
+
+	if (msg.Command() == "mnv") {
+		mnv := msg.(*wire.MsgMNV)
+		if pinger.VerificationChannel != nil {
+			select {
+			case pinger.VerificationChannel <- Verification{*mnv, pinger}:
+			default:
+				pinger.drop("mnv")
+			}
+		}
+	}
+
+	if (msg.Command() == "tx") {
+		tx := msg.(*wire.MsgTx)
+		if pinger.TxChannel != nil {
+			select {
+			case pinger.TxChannel <- *tx:
+			default:
+				pinger.drop("tx")
+			}
+		}
+	}
+
+	if (msg.Command() == "govobj") {
+		govObj := msg.(*wire.MsgGovObj)
+		if pinger.GovernanceChannel != nil {
+			select {
+			case pinger.GovernanceChannel <- *govObj:
+			default:
+				pinger.drop("govobj")
+			}
+		}
+	}
+
+	//this should really be a hashMap with expiring entries
+	if (msg.Command() == "getdata") {
+
+		getData := msg.(*wire.MsgGetData)
+
+		for _, inv := range getData.InvList {
+			//check the map
+			str := inv.Hash.String()
+			if val, ok := messageMap[str]; ok {
+				pinger.log.With("inv", inv.Type.String()).Debugf("Serving %s.", str)
+				session.send(val)
+			}
+		}
+	}
+
+	return true
+}
+
+// expireMessages drops relayed pings and broadcasts whose ping is more than
+// 5 minutes old, peers won't ask for them anymore.
+func expireMessages(messageMap map[string]wire.Message, now time.Time) {
+	for hash, message := range messageMap {
+		var sigTime uint64
+
+		switch message := message.(type) {
+		case *wire.MsgMNP:
+			sigTime = message.SigTime
+		case *wire.MsgMNB:
+			sigTime = message.LastPing.SigTime
+		default:
+			continue
+		}
+
+		pingTime := time.Unix(int64(sigTime), 0)
+		if pingTime.Add(time.Minute * 5).Before(now) {
+			delete(messageMap, hash)
+		}
 	}
 }
 
+// handleReject logs a reject and hands it over on RejectChannel. It's only
+// tied to an alias when it names the hash of a message relayed for it,
+// daemons rarely include the hash for masternode messages and blaming the
+// wrong alias could get a healthy masternode suspended.
+func (pinger *PingerConnection) handleReject(reject *wire.MsgReject, messageMap map[string]wire.Message) {
+	rejection := Rejection{Reject: *reject, Peer: pinger.IpAddress}
+
+	if reject.Hash != (chainhash.Hash{}) {
+		rejection.Hash = reject.Hash.String()
+	}
+
+	rejection.Alias = pinger.relayedAliases[rejection.Hash]
+	switch message := messageMap[rejection.Hash].(type) {
+	case *wire.MsgMNP:
+		rejection.Outpoint = message.Vin.PreviousOutPoint.String()
+	case *wire.MsgMNB:
+		rejection.Outpoint = message.Vin.PreviousOutPoint.String()
+	}
+
+	log := pinger.log.With("command", reject.Cmd).With("code", reject.Code.String())
+	if rejection.Alias != "" {
+		log = log.With("alias", rejection.Alias)
+	}
+	log.Warnf("Message rejected: %s", reject.Reason)
+
+	if pinger.RejectChannel != nil {
+		select {
+		case pinger.RejectChannel <- rejection:
+		default:
+			pinger.drop("reject")
+		}
+	}
+}
+
+func (pinger *PingerConnection) relayPing(session *peerSession, ping MasternodePing, messageMap map[string]wire.Message) {
+	pinger.log.With("alias", ping.Name).Infof("Relaying ping.")
+
+	mnp := ping.GenerateMasternodePing(pinger.SentinelVersion, pinger.DaemonVersion)
+
+	//check to see if this is a broadcast relay
+	if ping.BroadcastTemplate != nil {
+		//USING BROADCAST TEMPLATE
+
+		mnb := *ping.BroadcastTemplate
+		mnb.LastPing = mnp
+
+		inv := wire.MsgInv{}
+		invVec := wire.InvVect{}
+		invVec.Type = wire.InvTypeMasternodeAnnounce
+		invVec.Hash = mnb.GetHash()
+		inv.AddInvVect(&invVec)
+
+		session.send(&inv)
+
+		messageMap[invVec.Hash.String()] = &mnb
+		pinger.relayedAlias(invVec.Hash.String(), ping.Name)
+	}
+
+	//ALWAYS SEND THE PINGS
+	//serialize to a []byte
+	w := new(bytes.Buffer)
+	mnp.Serialize(w)
+	mnpBytes := w.Bytes()
+
+	inv := wire.MsgInv{}
+	invVec := wire.InvVect{}
+	invVec.Type = wire.InvTypeMasternodePing
+	invVec.Hash = chainhash.DoubleHashH(mnpBytes)
+	inv.AddInvVect(&invVec)
+
+	//send the ping inv
+	session.send(&inv)
+
+	//store the ping
+	messageMap[invVec.Hash.String()] = &mnp
+	pinger.relayedAlias(invVec.Hash.String(), ping.Name)
+}
+
+// drop counts a message its consumer had no room for. Handing messages over
+// never blocks, one slow consumer mustn't stall the peer's reader and the
+// pings relayed through it.
+func (pinger *PingerConnection) drop(command string) {
+	pinger.Mutex.Lock()
+	if pinger.dropped == nil {
+		pinger.dropped = make(map[string]uint64)
+	}
+	pinger.dropped[command]++
+	count := pinger.dropped[command]
+	pinger.Mutex.Unlock()
+
+	if count == 1 || count%100 == 0 {
+		pinger.log.With("command", command).Warnf("Consumer falling behind, %d message(s) dropped so far.", count)
+	}
+}
+
+// Dropped returns how many messages of each command were dropped because
+// their consumer fell behind.
+func (pinger *PingerConnection) Dropped() map[string]uint64 {
+	pinger.Mutex.Lock()
+	defer pinger.Mutex.Unlock()
+
+	dropped := make(map[string]uint64, len(pinger.dropped))
+	for command, count := range pinger.dropped {
+		dropped[command] = count
+	}
+	return dropped
+}
+
+func (pinger *PingerConnection) relayedAlias(hash string, alias string) {
+	if pinger.relayedAliases == nil {
+		pinger.relayedAliases = make(map[string]string)
+	}
+	pinger.relayedAliases[hash] = alias
+}
+
+// Send queues msg on the current connection. It returns false when the
+// pinger isn't connected or its outbound queue is full.
+func (pinger *PingerConnection) Send(msg wire.Message) bool {
+	pinger.Mutex.Lock()
+	session := pinger.session
+	pinger.Mutex.Unlock()
+
+	if session == nil || pinger.GetStatus() < 1 {
+		return false
+	}
+	return session.send(msg)
+}
+
+func (pinger *PingerConnection) setSession(session *peerSession) {
+	pinger.Mutex.Lock()
+	defer pinger.Mutex.Unlock()
+
+	pinger.session = session
+}
+
 func (pinger *PingerConnection) SetStatus(status int8) {
 	pinger.Mutex.Lock()
 	defer pinger.Mutex.Unlock()
@@ -0,0 +1,66 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantom
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"testing"
+)
+
+func TestHandleRejectAttribution(t *testing.T) {
+	relayed := chainhash.Hash{1}
+
+	tests := []struct {
+		name  string
+		hash  chainhash.Hash
+		alias string
+	}{
+		{"relayed hash", relayed, "mn1"},
+		{"unknown hash", chainhash.Hash{2}, ""},
+		{"no hash", chainhash.Hash{}, ""},
+	}
+
+	for _, test := range tests {
+		pinger := &PingerConnection{IpAddress: "127.0.0.1", RejectChannel: make(chan Rejection, 1)}
+		pinger.log = peerLog.With("peer", pinger.IpAddress)
+		pinger.relayedAlias(relayed.String(), "mn1")
+
+		reject := wire.NewMsgReject(wire.CmdMNP, wire.RejectInvalid, "invalid signature")
+		reject.Hash = test.hash
+		pinger.handleReject(reject, map[string]wire.Message{})
+
+		rejection := <-pinger.RejectChannel
+		if rejection.Alias != test.alias {
+			t.Errorf("%s: blamed %q, want %q", test.name, rejection.Alias, test.alias)
+		}
+	}
+}
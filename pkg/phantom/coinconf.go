@@ -1,48 +1,239 @@
 package phantom
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
-	"log"
-	"os"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+)
+
+const (
+	DefaultPingInterval  = 10 * time.Minute
+	DefaultSigTimeOffset = 3 * time.Second
+	DefaultHashDepth     = 12
+
+	DefaultPaymentRankOffset = 101
 )
 
 type CoinConf struct {
 	Name                string `json:"name"`
+	Network             string `json:"network,omitempty"`
 	Magicbytes          string `json:"magicbytes"`
-	Port                uint    `json:"port"`
-	ProtocolNumber      uint    `json:"protocol_number"`
+	Port                uint   `json:"port"`
+	ProtocolNumber      uint   `json:"protocol_number"`
 	MagicMessage        string `json:"magic_message"`
 	MagicMessageNewline bool   `json:"magic_message_newline,omitempty"`
 	BootstrapURL        string `json:"bootstrap_url,omitempty"`
-	SentinelVersion     string `json:"sentinel_version,omitempty""`
-	DaemonVersion       string `json:"daemon_version,omitempty""`
-	BootstrapIPs        string `json:"bootstrap_ips,omitempty""`
-	UserAgent           string `json:"user_agent,omitempty""`
+	SentinelVersion     string `json:"sentinel_version,omitempty"`
+	DaemonVersion       string `json:"daemon_version,omitempty"`
+	BootstrapIPs        string `json:"bootstrap_ips,omitempty"`
+	UserAgent           string `json:"user_agent,omitempty"`
+	MaxConnections      uint   `json:"max_connections,omitempty"`
+	PingInterval        *uint  `json:"ping_interval,omitempty"`
+	SigTimeOffset       *uint  `json:"sigtime_offset,omitempty"`
+	HashDepth           *uint  `json:"hash_depth,omitempty"`
+	MessageProfile      string `json:"message_profile,omitempty"`
+	SignatureScheme     string `json:"signature_scheme,omitempty"`
+	SporkPubKey         string `json:"spork_pubkey,omitempty"`
+	PaymentRankOffset   uint   `json:"payment_rank_offset,omitempty"`
+	MinProtocol         uint   `json:"min_protocol,omitempty"`
+	DNSSeeds            string `json:"dns_seeds,omitempty"`
+	Collateral          uint   `json:"collateral,omitempty"`
+	PowAlgorithm        string `json:"pow_algorithm,omitempty"`
+	PowLimit            string `json:"pow_limit,omitempty"`
 }
 
+// LoadCoinConf reads and validates a coin configuration file.
 func LoadCoinConf(path string) (CoinConf, error) {
-	var coinConf CoinConf
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		configLog.Errorf("Unable to read the coin configuration: %s", err)
+		return CoinConf{}, err
+	}
 
-	coinConfJson, err := os.Open(path)
-	defer coinConfJson.Close()
+	coinConf, err := ParseCoinConf(data)
 	if err != nil {
-		log.Println(err)
+		configLog.Errorf("Unable to parse the coin configuration: %s", err)
 		return CoinConf{}, err
 	}
 
-	bytes, err := ioutil.ReadAll(coinConfJson)
+	err = coinConf.Validate()
 	if err != nil {
-		log.Println(err)
 		return CoinConf{}, err
 	}
 
+	return coinConf, nil
+}
+
+// ParseCoinConf decodes a coin configuration, rejecting unknown fields so
+// typos don't go unnoticed.
+func ParseCoinConf(data []byte) (CoinConf, error) {
+	var coinConf CoinConf
 
-	err = json.Unmarshal(bytes, &coinConf)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(&coinConf)
 	if err != nil {
-		log.Println(err)
 		return CoinConf{}, err
 	}
 
 	return coinConf, nil
 }
+
+// GetPingInterval returns the time between two pings of the same masternode
+// (MASTERNODE_MIN_MNP_SECONDS), falling back to the Dash default when unset.
+func (conf CoinConf) GetPingInterval() time.Duration {
+	if conf.PingInterval == nil {
+		return DefaultPingInterval
+	}
+	return time.Duration(*conf.PingInterval) * time.Second
+}
+
+// GetSigTimeOffset returns how far past its slot a ping's sigTime is placed,
+// an explicit 0 signs with the slot time itself.
+func (conf CoinConf) GetSigTimeOffset() time.Duration {
+	if conf.SigTimeOffset == nil {
+		return DefaultSigTimeOffset
+	}
+	return time.Duration(*conf.SigTimeOffset) * time.Second
+}
+
+// GetHashDepth returns how many blocks below the tip the pinged block hash
+// is. It's also the length of the hash queue, so it's at least 1.
+func (conf CoinConf) GetHashDepth() int {
+	if conf.HashDepth == nil {
+		return DefaultHashDepth
+	}
+	return int(*conf.HashDepth)
+}
+
+// UintSetting returns a pointer to value, for the optional settings that
+// tell an explicit 0 from unset.
+func UintSetting(value uint) *uint {
+	return &value
+}
+
+// GetPaymentRankOffset returns how many blocks before a payment the block
+// hash used to rank the voting masternodes is (101 on Dash, 100 on PIVX).
+func (conf CoinConf) GetPaymentRankOffset() int {
+	if conf.PaymentRankOffset == 0 {
+		return DefaultPaymentRankOffset
+	}
+	return int(conf.PaymentRankOffset)
+}
+
+var magicBytesPattern = regexp.MustCompile(`^[0-9A-Fa-f]{8}$`)
+
+// ValidationError lists every problem found in a coin configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (err *ValidationError) Error() string {
+	return "invalid coin configuration: " + strings.Join(err.Problems, "; ")
+}
+
+// Validate checks every field of the configuration and returns a
+// *ValidationError listing all the problems found. Unset optional fields
+// are fine.
+func (conf CoinConf) Validate() error {
+	var problems []string
+	problem := func(field string, format string, args ...interface{}) {
+		problems = append(problems, field+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch conf.Network {
+	case "", "main", "test", "regtest":
+	default:
+		problem("network", "unknown network %q, use main, test or regtest", conf.Network)
+	}
+
+	if !magicBytesPattern.MatchString(conf.Magicbytes) {
+		problem("magicbytes", "%q must be exactly 8 hex characters (i.e. BD6B0CBF)", conf.Magicbytes)
+	}
+	if conf.Port == 0 || conf.Port > 65535 {
+		problem("port", "%d must be between 1 and 65535", conf.Port)
+	}
+	if conf.ProtocolNumber == 0 {
+		problem("protocol_number", "must be set to the network's protocol version (i.e. 70208)")
+	}
+	if conf.MagicMessage == "" {
+		problem("magic_message", "must be set to the coin's signed message header (i.e. DarkCoin Signed Message:)")
+	}
+
+	if conf.PingInterval != nil && *conf.PingInterval == 0 {
+		problem("ping_interval", "must be at least 1 second, leave it out for the default")
+	}
+	if conf.HashDepth != nil && *conf.HashDepth == 0 {
+		problem("hash_depth", "must be at least 1 block, the pinged hash is taken from a queue that deep")
+	}
+
+	if conf.SentinelVersion != "" {
+		if _, err := ParseVersionString(conf.SentinelVersion); err != nil {
+			problem("sentinel_version", "%s", err)
+		}
+	}
+	if conf.DaemonVersion != "" {
+		if _, err := ParseVersionString(conf.DaemonVersion); err != nil {
+			problem("daemon_version", "%s", err)
+		}
+	}
+
+	if conf.BootstrapIPs != "" {
+		for _, address := range strings.Split(conf.BootstrapIPs, ",") {
+			if _, err := SplitAddress(strings.TrimSpace(address)); err != nil {
+				problem("bootstrap_ips", "%q: %s, expected ip:port", address, err)
+			}
+		}
+	}
+
+	if conf.BootstrapURL != "" {
+		parsed, err := url.Parse(conf.BootstrapURL)
+		if err != nil {
+			problem("bootstrap_url", "%s", err)
+		} else if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			problem("bootstrap_url", "%q must be an http:// or https:// url", conf.BootstrapURL)
+		}
+	}
+
+	if conf.DNSSeeds != "" {
+		for _, seed := range strings.Split(conf.DNSSeeds, ",") {
+			seed = strings.TrimSpace(seed)
+			if seed == "" || strings.ContainsAny(seed, ":/ ") {
+				problem("dns_seeds", "%q must be a host name", seed)
+			}
+		}
+	}
+
+	if _, err := wire.LookupMessageProfile(conf.MessageProfile); err != nil {
+		problem("message_profile", "%s", err)
+	}
+	if _, err := ParseSignatureScheme(conf.SignatureScheme); err != nil {
+		problem("signature_scheme", "%s", err)
+	}
+	if _, err := LookupPowHasher(conf.PowAlgorithm); err != nil {
+		problem("pow_algorithm", "%s", err)
+	}
+	if conf.PowLimit != "" {
+		if _, err := ParsePowLimit(conf.PowLimit); err != nil {
+			problem("pow_limit", "%s", err)
+		}
+	}
+	if conf.SporkPubKey != "" {
+		if _, err := ParseSporkPubKey(conf.SporkPubKey); err != nil {
+			problem("spork_pubkey", "%s", err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
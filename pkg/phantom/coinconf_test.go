@@ -0,0 +1,109 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCoinConfOptionalSettings(t *testing.T) {
+	tests := []struct {
+		name          string
+		json          string
+		pingInterval  time.Duration
+		sigTimeOffset time.Duration
+		hashDepth     int
+	}{
+		{"unset", `{}`, DefaultPingInterval, DefaultSigTimeOffset, DefaultHashDepth},
+		{"explicit zero", `{"sigtime_offset":0}`, DefaultPingInterval, 0, DefaultHashDepth},
+		{"shallowest", `{"hash_depth":1}`, DefaultPingInterval, DefaultSigTimeOffset, 1},
+		{"set", `{"ping_interval":300,"sigtime_offset":5,"hash_depth":6}`, 5 * time.Minute, 5 * time.Second, 6},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := ParseCoinConf([]byte(test.json))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := conf.GetPingInterval(); got != test.pingInterval {
+				t.Errorf("GetPingInterval() = %s, want %s", got, test.pingInterval)
+			}
+			if got := conf.GetSigTimeOffset(); got != test.sigTimeOffset {
+				t.Errorf("GetSigTimeOffset() = %s, want %s", got, test.sigTimeOffset)
+			}
+			if got := conf.GetHashDepth(); got != test.hashDepth {
+				t.Errorf("GetHashDepth() = %d, want %d", got, test.hashDepth)
+			}
+		})
+	}
+}
+
+func TestCoinConfExplicitZero(t *testing.T) {
+	valid := CoinConf{
+		Magicbytes:     "BD6B0CBF",
+		Port:           9999,
+		ProtocolNumber: 70208,
+		MagicMessage:   "DarkCoin Signed Message:",
+	}
+
+	tests := []struct {
+		name    string
+		set     func(conf *CoinConf)
+		problem string
+	}{
+		{"ping_interval", func(conf *CoinConf) { conf.PingInterval = UintSetting(0) }, "ping_interval"},
+		{"hash_depth", func(conf *CoinConf) { conf.HashDepth = UintSetting(0) }, "hash_depth"},
+		{"sigtime_offset", func(conf *CoinConf) { conf.SigTimeOffset = UintSetting(0) }, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf := valid
+			test.set(&conf)
+
+			err := conf.Validate()
+			if test.problem == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+
+			validationErr, ok := err.(*ValidationError)
+			if !ok || len(validationErr.Problems) != 1 || !strings.HasPrefix(validationErr.Problems[0], test.problem+":") {
+				t.Errorf("Validate() = %v, want only %s refused", err, test.problem)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const peerTransactionTimeout = 30 * time.Second
+
+// PeerTransactions requests transactions from the connected peers with
+// getdata. Peers only serve transactions they still relay, so confirmed
+// collateral is usually only found through an explorer.
+type PeerTransactions struct {
+	Pingers func() []*PingerConnection
+	Timeout time.Duration
+	Clock   Clock
+
+	waiting map[chainhash.Hash][]chan wire.MsgTx
+	mux     sync.Mutex
+}
+
+// LoadTransaction asks every connected peer for the transaction and returns
+// the first copy served.
+func (peers *PeerTransactions) LoadTransaction(hash chainhash.Hash) (*wire.MsgTx, error) {
+	result := make(chan wire.MsgTx, 1)
+
+	peers.mux.Lock()
+	if peers.waiting == nil {
+		peers.waiting = make(map[chainhash.Hash][]chan wire.MsgTx)
+	}
+	peers.waiting[hash] = append(peers.waiting[hash], result)
+	peers.mux.Unlock()
+	defer peers.forget(hash, result)
+
+	getdata := wire.MsgGetData{}
+	getdata.AddInvVect(wire.NewInvVect(wire.InvTypeTx, &hash))
+
+	asked := 0
+	for _, pinger := range peers.Pingers() {
+		if pinger.Send(&getdata) {
+			asked++
+		}
+	}
+	if asked == 0 {
+		return nil, errors.New("no connected peers to ask")
+	}
+
+	timeout := peers.Timeout
+	if timeout == 0 {
+		timeout = peerTransactionTimeout
+	}
+	timer := clockOrDefault(peers.Clock).NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case tx := <-result:
+		return &tx, nil
+	case <-timer.C():
+		return nil, fmt.Errorf("none of the %d peers asked served it", asked)
+	}
+}
+
+// Deliver hands a transaction received from a peer to the lookups waiting
+// for it.
+func (peers *PeerTransactions) Deliver(tx wire.MsgTx) {
+	hash := tx.TxHash()
+
+	peers.mux.Lock()
+	defer peers.mux.Unlock()
+
+	for _, result := range peers.waiting[hash] {
+		select {
+		case result <- tx:
+		default: //already served by another peer
+		}
+	}
+}
+
+func (peers *PeerTransactions) forget(hash chainhash.Hash, result chan wire.MsgTx) {
+	peers.mux.Lock()
+	defer peers.mux.Unlock()
+
+	waiting := peers.waiting[hash]
+	for i := range waiting {
+		if waiting[i] == result {
+			waiting = append(waiting[:i], waiting[i+1:]...)
+			break
+		}
+	}
+	if len(waiting) == 0 {
+		delete(peers.waiting, hash)
+	} else {
+		peers.waiting[hash] = waiting
+	}
+}
+
+// CollateralCheck is the outcome of checking a masternode's collateral.
+// Unverified is set when the transaction couldn't be looked up, Problems
+// lists what's wrong with the masternode file entry otherwise.
+type CollateralCheck struct {
+	Alias      string   `json:"alias"`
+	Outpoint   string   `json:"outpoint"`
+	Amount     int64    `json:"amount,omitempty"`
+	Source     string   `json:"source,omitempty"`
+	Unverified string   `json:"unverified,omitempty"`
+	Problems   []string `json:"problems,omitempty"`
+}
+
+// CollateralChecker verifies the collateral of our masternodes: the output
+// must exist, hold exactly the coin's collateral and pay to the collateral
+// key of the masternode's broadcast. The transaction is looked up on the
+// explorer first, then on the peers.
+type CollateralChecker struct {
+	Explorer   *Bootstrapper
+	Peers      *PeerTransactions
+	Broadcasts *BroadcastStore
+
+	//the collateral in base units, zero skips the amount check
+	Amount int64
+}
+
+// Check verifies a single masternode's collateral.
+func (checker *CollateralChecker) Check(masternode MasternodePing) CollateralCheck {
+	check := CollateralCheck{
+		Alias:    masternode.Name,
+		Outpoint: OutpointKey(masternode.OutpointHash, masternode.OutpointIndex),
+	}
+
+	hash, err := chainhash.NewHashFromStr(masternode.OutpointHash)
+	if err != nil {
+		check.Problems = append(check.Problems, fmt.Sprintf("the collateral txid %q isn't a transaction hash", masternode.OutpointHash))
+		return check
+	}
+
+	tx, source, err := checker.load(*hash)
+	if err != nil {
+		check.Unverified = err.Error()
+		return check
+	}
+	check.Source = source
+
+	if int(masternode.OutpointIndex) >= len(tx.TxOut) {
+		check.Problems = append(check.Problems, fmt.Sprintf("output %d doesn't exist, the transaction has %d output(s)",
+			masternode.OutpointIndex, len(tx.TxOut)))
+		return check
+	}
+	output := tx.TxOut[masternode.OutpointIndex]
+	check.Amount = output.Value
+
+	if checker.Amount != 0 && output.Value != checker.Amount {
+		check.Problems = append(check.Problems, fmt.Sprintf("output %d holds %s coins instead of the %s coin collateral",
+			masternode.OutpointIndex, formatCoins(output.Value), formatCoins(checker.Amount)))
+	}
+
+	if checker.Broadcasts != nil {
+		if mnb, ok := checker.Broadcasts.Get(check.Outpoint); ok && !paysTo(output.PkScript, mnb.PubKeyCollateralAddress) {
+			check.Problems = append(check.Problems, fmt.Sprintf("output %d doesn't pay to the collateral key of the "+
+				"masternode's broadcast (%s)", masternode.OutpointIndex, collateralAddress(mnb.PubKeyCollateralAddress)))
+		}
+	}
+
+	return check
+}
+
+// CheckAll verifies and logs the collateral of every masternode.
+func (checker *CollateralChecker) CheckAll(masternodes []MasternodePing) []CollateralCheck {
+	checks := make([]CollateralCheck, 0, len(masternodes))
+
+	for _, masternode := range masternodes {
+		check := checker.Check(masternode)
+		checks = append(checks, check)
+
+		log := collateralLog.With("alias", check.Alias).With("outpoint", check.Outpoint)
+		switch {
+		case check.Unverified != "":
+			log.Warnf("Unable to verify the collateral: %s", check.Unverified)
+		case len(check.Problems) > 0:
+			for _, problem := range check.Problems {
+				log.Errorf("Collateral misconfigured, %s. Check the collateral txid and index in the masternode file.", problem)
+			}
+		default:
+			log.With("source", check.Source).Infof("Collateral of %s coins verified.", formatCoins(check.Amount))
+		}
+	}
+
+	return checks
+}
+
+func (checker *CollateralChecker) load(hash chainhash.Hash) (*wire.MsgTx, string, error) {
+	var reasons []string
+
+	if checker.Explorer != nil {
+		tx, err := checker.Explorer.LoadTransaction(hash)
+		if err == nil {
+			return tx, "explorer", nil
+		}
+		reasons = append(reasons, "explorer: "+err.Error())
+	}
+
+	if checker.Peers != nil {
+		tx, err := checker.Peers.LoadTransaction(hash)
+		if err == nil {
+			return tx, "peers", nil
+		}
+		reasons = append(reasons, "peers: "+err.Error())
+	}
+
+	if len(reasons) == 0 {
+		return nil, "", errors.New("no explorer or peers to look the transaction up")
+	}
+	return nil, "", fmt.Errorf("transaction %s not found (%s)", hash, strings.Join(reasons, "; "))
+}
+
+// paysTo reports whether a pay-to-pubkey-hash or pay-to-pubkey script pays
+// to pubKey.
+func paysTo(pkScript []byte, pubKey []byte) bool {
+	p2pkh := append([]byte{0x76, 0xa9, 0x14}, btcutil.Hash160(pubKey)...)
+	p2pkh = append(p2pkh, 0x88, 0xac)
+	if bytes.Equal(pkScript, p2pkh) {
+		return true
+	}
+
+	p2pk := append([]byte{byte(len(pubKey))}, pubKey...)
+	p2pk = append(p2pk, 0xac)
+	return bytes.Equal(pkScript, p2pk)
+}
+
+// collateralAddress identifies a collateral key by its hash160, the address
+// encoding depends on the coin.
+func collateralAddress(pubKey []byte) string {
+	return fmt.Sprintf("hash160 %x", btcutil.Hash160(pubKey))
+}
+
+func formatCoins(amount int64) string {
+	return strconv.FormatFloat(btcutil.Amount(amount).ToBTC(), 'f', -1, 64)
+}
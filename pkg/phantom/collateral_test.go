@@ -0,0 +1,184 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// collateralTx pays 1000 coins to collateral by pubkey hash, 5 coins by
+// pubkey and 1000 coins to another key.
+func collateralTx(t *testing.T, collateral *btcec.PublicKey, other *btcec.PublicKey) *wire.MsgTx {
+	p2pkh := func(pubKey *btcec.PublicKey) []byte {
+		script := append([]byte{0x76, 0xa9, 0x14}, btcutil.Hash160(pubKey.SerializeCompressed())...)
+		return append(script, 0x88, 0xac)
+	}
+	p2pk := append([]byte{33}, collateral.SerializeCompressed()...)
+	p2pk = append(p2pk, 0xac)
+
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{3}, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1000*btcutil.SatoshiPerBitcoin, p2pkh(collateral)))
+	tx.AddTxOut(wire.NewTxOut(5*btcutil.SatoshiPerBitcoin, p2pk))
+	tx.AddTxOut(wire.NewTxOut(1000*btcutil.SatoshiPerBitcoin, p2pkh(other)))
+	return tx
+}
+
+// transactionExplorer serves the raw transactions, anything else as not found.
+func transactionExplorer(t *testing.T, txs ...*wire.MsgTx) *httptest.Server {
+	raw := make(map[string]string)
+	for _, tx := range txs {
+		var buf bytes.Buffer
+		if err := tx.Serialize(&buf); err != nil {
+			t.Fatal(err)
+		}
+		raw[tx.TxHash().String()] = hex.EncodeToString(buf.Bytes())
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tx, ok := raw[r.URL.Query().Get("txid")]
+		if r.URL.Path != "/api/getrawtransaction" || !ok {
+			http.Error(w, "There was an error. Check your console.", http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, tx)
+	}))
+}
+
+func TestCollateralCheck(t *testing.T) {
+	collateral, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := collateralTx(t, collateral.PubKey(), other.PubKey())
+	explorer := transactionExplorer(t, tx)
+	defer explorer.Close()
+
+	//every output is announced by a broadcast signed with the collateral key
+	broadcasts := NewBroadcastStore("")
+	for i := range tx.TxOut {
+		outpoint := OutpointKey(tx.TxHash().String(), uint32(i))
+		broadcasts.broadcasts[outpoint] = wire.MsgMNB{PubKeyCollateralAddress: collateral.PubKey().SerializeCompressed()}
+	}
+
+	checker := &CollateralChecker{
+		Explorer:   &Bootstrapper{BaseURL: explorer.URL},
+		Broadcasts: broadcasts,
+		Amount:     1000 * btcutil.SatoshiPerBitcoin,
+	}
+
+	txid := tx.TxHash().String()
+	for _, test := range []struct {
+		name       string
+		txid       string
+		index      uint32
+		amount     int64
+		problem    string
+		unverified bool
+	}{
+		{"valid", txid, 0, 1000 * btcutil.SatoshiPerBitcoin, "", false},
+		{"wrong amount", txid, 1, 5 * btcutil.SatoshiPerBitcoin, "holds 5 coins instead of the 1000 coin collateral",
+			false},
+		{"wrong key", txid, 2, 1000 * btcutil.SatoshiPerBitcoin, "doesn't pay to the collateral key", false},
+		{"missing output", txid, 3, 0, "output 3 doesn't exist, the transaction has 3 output(s)", false},
+		{"invalid txid", "not a txid", 0, 0, "isn't a transaction hash", false},
+		{"unknown txid", chainhash.Hash{4}.String(), 0, 0, "", true},
+	} {
+		check := checker.Check(MasternodePing{Name: "mn1", OutpointHash: test.txid, OutpointIndex: test.index})
+
+		if check.Alias != "mn1" || check.Outpoint != OutpointKey(test.txid, test.index) || check.Amount != test.amount {
+			t.Errorf("%s: check %+v", test.name, check)
+		}
+		if (check.Unverified != "") != test.unverified {
+			t.Errorf("%s: unverified %q", test.name, check.Unverified)
+		}
+		if !test.unverified && test.txid == txid && check.Source != "explorer" {
+			t.Errorf("%s: looked up on %q", test.name, check.Source)
+		}
+
+		switch {
+		case test.problem == "" && len(check.Problems) != 0:
+			t.Errorf("%s: problems %q", test.name, check.Problems)
+		case test.problem != "" && (len(check.Problems) != 1 || !strings.Contains(check.Problems[0], test.problem)):
+			t.Errorf("%s: problems %q, want %q", test.name, check.Problems, test.problem)
+		}
+	}
+
+	//without a collateral amount or broadcasts only the output is checked
+	checker = &CollateralChecker{Explorer: &Bootstrapper{BaseURL: explorer.URL}}
+	for index := uint32(0); index < 3; index++ {
+		check := checker.Check(MasternodePing{Name: "mn1", OutpointHash: txid, OutpointIndex: index})
+		if len(check.Problems) != 0 || check.Unverified != "" {
+			t.Errorf("output %d: check %+v", index, check)
+		}
+	}
+
+	checker = &CollateralChecker{}
+	if check := checker.Check(MasternodePing{OutpointHash: txid}); !strings.Contains(check.Unverified, "no explorer or peers") {
+		t.Errorf("checking without a source: %+v", check)
+	}
+}
+
+func TestPaysTo(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := collateralTx(t, key.PubKey(), other.PubKey())
+	for _, pubKey := range [][]byte{key.PubKey().SerializeCompressed(), key.PubKey().SerializeUncompressed()} {
+		compressed := len(pubKey) == 33
+		if paysTo(tx.TxOut[0].PkScript, pubKey) != compressed || paysTo(tx.TxOut[1].PkScript, pubKey) != compressed {
+			t.Errorf("paysTo() of a %d byte key", len(pubKey))
+		}
+		if paysTo(tx.TxOut[2].PkScript, pubKey) {
+			t.Errorf("paysTo() of another key's output with a %d byte key", len(pubKey))
+		}
+	}
+}
@@ -0,0 +1,236 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantom
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const controlTimeout = 1 * time.Minute
+
+// ControlRequest is a command sent to a running phantom over its control
+// socket, i.e. {"command":"masternode","args":["pause","mn1"]}.
+type ControlRequest struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// ControlResponse carries either the JSON result of a command or why it
+// failed.
+type ControlResponse struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// ControlHandler runs a command with its arguments, the result is sent back
+// JSON encoded.
+type ControlHandler func(args []string) (interface{}, error)
+
+// ControlServer answers control requests on a Unix socket. Every connection
+// carries a single request line and its response line.
+type ControlServer struct {
+	Path     string
+	Handlers map[string]ControlHandler
+
+	listener net.Listener
+	mux      sync.Mutex
+}
+
+// Listen opens the socket, replacing one left behind by a phantom that
+// didn't shut down cleanly, and serves requests in the background. Only
+// the user running the phantom may connect.
+func (server *ControlServer) Listen() error {
+	if info, err := os.Stat(server.Path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("%s exists and isn't a socket", server.Path)
+		}
+
+		conn, err := net.DialTimeout("unix", server.Path, time.Second)
+		if err == nil {
+			conn.Close()
+			return fmt.Errorf("another phantom is listening on %s", server.Path)
+		}
+
+		err = os.Remove(server.Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	listener, err := listenControl(server.Path)
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(server.Path, 0600)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	server.mux.Lock()
+	server.listener = listener
+	server.mux.Unlock()
+
+	go server.serve(listener)
+	return nil
+}
+
+// Close stops accepting requests and removes the socket.
+func (server *ControlServer) Close() error {
+	server.mux.Lock()
+	defer server.mux.Unlock()
+
+	if server.listener == nil {
+		return nil
+	}
+	err := server.listener.Close()
+	server.listener = nil
+	return err
+}
+
+func (server *ControlServer) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			server.mux.Lock()
+			closed := server.listener != listener
+			server.mux.Unlock()
+
+			if !closed {
+				controlLog.Errorf("Unable to accept control connections: %s", err)
+			}
+			return
+		}
+
+		go server.handle(conn)
+	}
+}
+
+func (server *ControlServer) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	var request ControlRequest
+	var response ControlResponse
+
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil {
+		err = json.Unmarshal(line, &request)
+	}
+
+	if err != nil {
+		response.Error = fmt.Sprintf("invalid request: %s", err)
+	} else {
+		response = server.run(request)
+	}
+
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		controlLog.With("command", request.Command).Errorf("Unable to encode the response: %s", err)
+		return
+	}
+	conn.Write(append(encoded, '\n'))
+}
+
+func (server *ControlServer) run(request ControlRequest) (response ControlResponse) {
+	//only the subcommand is logged, arguments can hold masternode keys
+	command := request.Command
+	if len(request.Args) > 0 {
+		command += " " + request.Args[0]
+	}
+	log := controlLog.With("command", command)
+
+	handler, ok := server.Handlers[request.Command]
+	if !ok {
+		response.Error = fmt.Sprintf("unknown command %q", request.Command)
+		return response
+	}
+
+	result, err := handler(request.Args)
+	if err != nil {
+		log.Warnf("Command failed: %s", err)
+		response.Error = err.Error()
+		return response
+	}
+	log.Debugf("Command run.")
+
+	response.Result, err = json.Marshal(result)
+	if err != nil {
+		response.Result = nil
+		response.Error = fmt.Sprintf("unable to encode the result: %s", err)
+	}
+	return response
+}
+
+// CallControl runs a command on the phantom listening on the socket at
+// path and returns its JSON encoded result.
+func CallControl(path string, command string, args []string) (json.RawMessage, error) {
+	conn, err := net.DialTimeout("unix", path, 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach the phantom, is it running with -control_socket=%s? %s", path, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	request, err := json.Marshal(ControlRequest{Command: command, Args: args})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.Write(append(request, '\n'))
+	if err != nil {
+		return nil, err
+	}
+
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("no response from the phantom: %s", err)
+	}
+
+	var response ControlResponse
+	err = json.Unmarshal(line, &response)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response from the phantom: %s", err)
+	}
+
+	if response.Error != "" {
+		return nil, errors.New(response.Error)
+	}
+	return response.Result, nil
+}
@@ -0,0 +1,84 @@
+//go:build !windows
+// +build !windows
+
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestControlSocketPrivate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "phantom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	//a permissive umask must not leak through to the socket
+	umask := syscall.Umask(0)
+	defer syscall.Umask(umask)
+
+	server := &ControlServer{
+		Path: filepath.Join(dir, "phantom.sock"),
+		Handlers: map[string]ControlHandler{
+			"echo": func(args []string) (interface{}, error) { return args, nil },
+		},
+	}
+	err = server.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	info, err := os.Stat(server.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("socket mode = %o, want 600", mode)
+	}
+	if restored := syscall.Umask(0); restored != 0 {
+		t.Errorf("umask left at %o", restored)
+	}
+
+	result, err := CallControl(server.Path, "echo", []string{"mn1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != `["mn1"]` {
+		t.Errorf("echo result = %s", result)
+	}
+}
@@ -2,6 +2,7 @@ package phantom
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"strconv"
 	"strings"
@@ -9,15 +10,23 @@ import (
 	"github.com/breakcrypto/phantom/pkg/socket/wire"
 )
 
-func ConvertVersionStringToInt(str string) uint32 {
-	version := 0
+// ParseVersionString parses a dotted version such as 1.0.1 into one byte
+// per part, the way sentinel and daemon versions are sent.
+func ParseVersionString(str string) (uint32, error) {
 	parts := strings.Split(str, ".")
+	if len(parts) < 2 || len(parts) > 4 {
+		return 0, fmt.Errorf("invalid version %q, expected a dotted version such as 1.0.1", str)
+	}
+
+	var version uint32
 	for _, part := range parts {
-		version <<= 8
-		value, _ := strconv.Atoi(part)
-		version |= value
+		value, err := strconv.ParseUint(part, 10, 8)
+		if err != nil {
+			return 0, fmt.Errorf("invalid version %q, each part must be between 0 and 255", str)
+		}
+		version = version<<8 | uint32(value)
 	}
-	return uint32(version)
+	return version, nil
 }
 
 func SplitAddress(pair string) (wire.NetAddress, error) {
@@ -25,11 +34,17 @@ func SplitAddress(pair string) (wire.NetAddress, error) {
 	if len(ipPort) != 2 {
 		return wire.NetAddress{}, errors.New("invalid ip:port pair")
 	}
-	ip := ipPort[0]
-	port, _ := strconv.Atoi(ipPort[1])
+	ip := net.ParseIP(ipPort[0])
+	if ip == nil {
+		return wire.NetAddress{}, fmt.Errorf("invalid ip address %q", ipPort[0])
+	}
+	port, err := strconv.ParseUint(ipPort[1], 10, 16)
+	if err != nil || port == 0 {
+		return wire.NetAddress{}, fmt.Errorf("invalid port %q", ipPort[1])
+	}
 	return wire.NetAddress{time.Now(),
 		0,
-		net.ParseIP(ip),
+		ip,
 		uint16(port)}, nil
 }
 
@@ -42,3 +57,27 @@ func SplitAddressList(bootstraps string) (addresses []wire.NetAddress) {
 	}
 	return addresses
 }
+
+// ResolveSeeds looks up the IPv4 addresses of comma separated DNS seeds,
+// seeds that fail to resolve are skipped.
+func ResolveSeeds(seeds string, port uint16) (addresses []wire.NetAddress) {
+	for _, seed := range strings.Split(seeds, ",") {
+		seed = strings.TrimSpace(seed)
+		if seed == "" {
+			continue
+		}
+
+		ips, err := net.LookupIP(seed)
+		if err != nil {
+			configLog.With("seed", seed).Warnf("Unable to resolve the DNS seed: %s", err)
+			continue
+		}
+
+		for _, ip := range ips {
+			if ip.To4() != nil {
+				addresses = append(addresses, *wire.NewNetAddressIPPort(ip.To4(), port, 0))
+			}
+		}
+	}
+	return addresses
+}
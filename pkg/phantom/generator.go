@@ -31,16 +31,11 @@ package phantom
 
 import (
 	"bufio"
-	"bytes"
-	"encoding/hex"
-	"fmt"
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcutil"
-	"log"
 	"os"
 	"github.com/breakcrypto/phantom/pkg/socket/wire"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -57,51 +52,47 @@ type MasternodePing struct {
 	DaemonVersion uint32
 	HashQueue *Queue
 	BroadcastTemplate *wire.MsgMNB
+	SigTimeOffset time.Duration
+	Profile *wire.MessageProfile
+	SignatureScheme SignatureScheme
 }
 
-type pingSlice []MasternodePing
-
-func (p pingSlice) Len() int {
-	return len(p)
-}
-
-func (p pingSlice) Less(i, j int) bool {
-	return p[i].PingTime.Before(p[j].PingTime)
-}
-
-func (p pingSlice) Swap(i, j int) {
-	p[i], p[j] = p[j], p[i]
-}
-
-func determinePingTime(unixTime string) (time.Time) {
+// determinePingTime returns the first slot after now on the grid that starts
+// at the epoch and repeats every pingInterval.
+func determinePingTime(unixTime string, pingInterval time.Duration, now time.Time) (time.Time, error) {
 
 	i, err := strconv.ParseInt(unixTime, 10, 64)
 	if err != nil {
-		panic(err)
+		return time.Time{}, err
 	}
 	base := time.Unix(i, 0)
 
-	difference := time.Now().UTC().Sub(base)
+	if now.Before(base) {
+		return base, nil
+	}
+
+	difference := now.UTC().Sub(base)
 
 	//var bump uint32
-	bump := uint32(difference.Minutes() / 10) + 1
-	result := time.Minute * time.Duration(bump) * 10
+	bump := uint32(difference / pingInterval) + 1
+	result := pingInterval * time.Duration(bump)
 
-	return base.Add(result)
+	return base.Add(result), nil
 }
 
-func GeneratePingsFromMasternodeFile(filePath string, pingChannel chan MasternodePing, queue *Queue,
-	magicMessage string, sentinelVersion uint32, daemonVersion uint32, broadcastSet map[string]wire.MsgMNB) {
+func LoadPingsFromMasternodeFile(filePath string, queue *Queue, magicMessage string, sentinelVersion uint32,
+	daemonVersion uint32, pingInterval time.Duration, sigTimeOffset time.Duration, profile *wire.MessageProfile,
+	now time.Time) ([]MasternodePing, error) {
 
-	currentTime := time.Now().UTC()
+	currentTime := now.UTC()
 
 	file, err := os.Open(filePath)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	defer file.Close()
 
-	pings := make(pingSlice, 0)
+	pings := make([]MasternodePing, 0)
 
 	scanner := bufio.NewScanner(file)
 
@@ -116,72 +107,73 @@ func GeneratePingsFromMasternodeFile(filePath string, pingChannel chan Masternod
 
 		//add an epoch if missing and alert
 		if len(fields) == 5 {
-			log.Println("No epoch time found for: ", fields[0], " assuming one.")
-			fields = append(fields, strconv.FormatInt(currentTime.Add(time.Duration(i*5) * time.Second).Unix() - 540, 10))
+			schedulerLog.With("alias", fields[0]).Warnf("No epoch time found, assuming one.")
+			fields = append(fields, strconv.FormatInt(assumedEpoch(currentTime, i, pingInterval), 10))
 			i++
 		}
 
 		if len(fields) != 6 {
-			log.Println("Error processing: ", line)
+			schedulerLog.Warnf("Error processing masternode line: %s", line)
 			continue
 		}
 
 		outputIndex, err := strconv.Atoi(fields[4])
 		if err != nil {
-			log.Println("Error reading masternode index value.")
+			schedulerLog.With("alias", fields[0]).Warnf("Error reading masternode index value: %s", err)
+		}
+
+		pingTime, err := determinePingTime(fields[5], pingInterval, currentTime)
+		if err != nil {
+			schedulerLog.With("alias", fields[0]).Warnf("Error reading the epoch time: %s", err)
+			continue
 		}
 
 		ping := MasternodePing{fields[0],
 			fields[3],
 			uint32(outputIndex),
 			fields[2],
-			determinePingTime(fields[5]),
+			pingTime,
 			magicMessage,
 			sentinelVersion,
 			daemonVersion,
 			queue,
 			nil,
-		}
-
-		if broadcastSet != nil {
-			//check for a broadcast template
-			broadcast, ok := broadcastSet[ping.OutpointHash +
-				":" + strconv.Itoa(int(ping.OutpointIndex))]
-
-			//provide the template
-			if ok {
-				ping.BroadcastTemplate = &broadcast
-
-				//remove the broadcast after 24 hours
-				sigTime := time.Unix(int64(broadcast.SigTime), 0)
-				if sigTime.Add(time.Hour * 24).Before(time.Now().UTC()) {
-					delete(broadcastSet, ping.OutpointHash +
-						":" + strconv.Itoa(int(ping.OutpointIndex)))
-				}
-			}
+			sigTimeOffset,
+			profile,
+			SignatureLegacy,
 		}
 
 		pings = append(pings, ping)
 	}
 
-	//sort the pings by time
-	sort.Sort(pings)
+	return pings, scanner.Err()
+}
+
+// AttachBroadcastTemplate hands the ping a stored broadcast to relay with it
+// when its masternode needs to be re-announced.
+func (ping *MasternodePing) AttachBroadcastTemplate(broadcasts *BroadcastStore) {
+	if broadcasts == nil {
+		return
+	}
 
-	//we have a sorted list of pings -- add them to the channel
-	for _, ping := range pings {
-		//fmt.Println("Enabling: ", ping.Name)
-		log.Printf("%s : Enabling.\n", ping.Name)
-		pingChannel <- ping
+	outpoint := OutpointKey(ping.OutpointHash, ping.OutpointIndex)
+	if !broadcasts.NeedsAnnounce(outpoint) {
+		return
 	}
 
+	//provide the template
+	if broadcast, ok := broadcasts.Get(outpoint); ok {
+		ping.BroadcastTemplate = &broadcast
+	}
 }
 
 func (ping *MasternodePing) GenerateMasternodePing(sentinelVersion uint32, daemonVersion uint32) (wire.MsgMNP){
-	mnp := wire.MsgMNP{}
+	mnp := wire.MsgMNP{Profile: ping.Profile}
 
 	//add sentinel support
 	if sentinelVersion > 0 {
 		mnp.SentinelEnabled = true
+		mnp.SentinelIsCurrent = true
 		mnp.SentinelVersion = sentinelVersion
 	}
 
@@ -202,32 +194,35 @@ func (ping *MasternodePing) GenerateMasternodePing(sentinelVersion uint32, daemo
 	mnp.Vin = *txIn
 
 	//setup the time
-	mnp.SigTime = uint64(ping.PingTime.Add(time.Second * 3).UTC().Unix()) //generate a deterministic time
+	mnp.SigTime = uint64(ping.PingTime.Add(ping.SigTimeOffset).UTC().Unix()) //generate a deterministic time
 
 	//sign the ping
 	wif, err := btcutil.DecodeWIF(ping.PrivateKey)
 	if err != nil {
-		log.Println(err)
-	}
-
-	signatureHash := GenerateMNPSignature(ping.MagicMessage, mnp.Vin.PreviousOutPoint.Hash.String(), mnp.Vin.PreviousOutPoint.Index, mnp.Vin.SignatureScript, mnp.BlockHash.String(), mnp.SigTime, *wif.PrivKey)
-	if err != nil {
-		log.Println(err)
+		schedulerLog.With("alias", ping.Name).Errorf("Unable to decode the masternode private key: %s", err)
 	}
 
 	//push the bytes to the mnp
-	mnp.VchSig = signatureHash
+	switch ping.SignatureScheme {
+	case SignatureHash:
+		mnp.VchSig = GenerateMNPHashSignature(&mnp, *wif.PrivKey)
+	default:
+		mnp.VchSig = GenerateMNPSignature(ping.Profile, ping.MagicMessage, mnp.Vin.PreviousOutPoint.Hash.String(), mnp.Vin.PreviousOutPoint.Index, mnp.Vin.SignatureScript, mnp.BlockHash.String(), mnp.SigTime, *wif.PrivKey)
+	}
 
 	return mnp
 }
 
-func GenerateMNPSignature(magicMessage string, hash string, n uint32, scriptSig []byte, blockHash string, sigTime uint64, privKey btcec.PrivateKey) []byte {
-	var buf bytes.Buffer
-	wire.WriteVarString(&buf, 0, magicMessage) //"DarkCoin Signed Message:\n" - $PAC || "ProtonCoin Signed Message:\n" - ANDS
-	wire.WriteVarString(&buf, 0, fmt.Sprintf("CTxIn(COutPoint(%s, %d), scriptSig=%s)%s%s", hash, n, hex.EncodeToString(scriptSig), blockHash, strconv.FormatInt(int64(sigTime), 10)))
-	expectedMessageHash := chainhash.DoubleHashB(buf.Bytes())
+func GenerateMNPSignature(profile *wire.MessageProfile, magicMessage string, hash string, n uint32, scriptSig []byte, blockHash string, sigTime uint64, privKey btcec.PrivateKey) []byte {
+	expectedMessageHash := GenerateMNPSignatureHash(profile, magicMessage, hash, n, scriptSig, blockHash, sigTime)
 
 	sig, _ := btcec.SignCompact(btcec.S256(), &privKey, expectedMessageHash, false)
 
 	return sig
 }
+
+// GenerateMNPSignatureHash returns the digest a legacy (string message) ping
+// signature commits to, using the message format of profile.
+func GenerateMNPSignatureHash(profile *wire.MessageProfile, magicMessage string, hash string, n uint32, scriptSig []byte, blockHash string, sigTime uint64) []byte {
+	return signedMessageHash(magicMessage, profile.PingSignatureMessage(hash, n, scriptSig, blockHash, sigTime))
+}
@@ -0,0 +1,184 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom
+
+import (
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"testing"
+	"time"
+)
+
+func TestDeterminePingTime(t *testing.T) {
+	epoch := time.Unix(1555555555, 0)
+	interval := 10 * time.Minute
+
+	tests := []struct {
+		name  string
+		epoch string
+		now   time.Time
+		want  time.Time
+	}{
+		{"before the epoch", "1555555555", epoch.Add(-time.Hour), epoch},
+		{"on the epoch", "1555555555", epoch, epoch.Add(interval)},
+		{"mid slot", "1555555555", epoch.Add(25 * time.Minute), epoch.Add(30 * time.Minute)},
+		{"on a later slot", "1555555555", epoch.Add(30 * time.Minute), epoch.Add(40 * time.Minute)},
+		{"just before a slot", "1555555555", epoch.Add(40*time.Minute - time.Second), epoch.Add(40 * time.Minute)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := determinePingTime(test.epoch, interval, test.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("determinePingTime() = %s, want %s", got.UTC(), test.want.UTC())
+			}
+		})
+	}
+
+	_, err := determinePingTime("soon", interval, epoch)
+	if err == nil {
+		t.Error("determinePingTime() accepted an invalid epoch")
+	}
+}
+
+func TestGenerateMasternodePingSigTime(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wif, err := btcutil.NewWIF(key, &chaincfg.MainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err := wire.LookupMessageProfile("dash-12.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queue := NewQueue(1)
+	queue.Push(&chainhash.Hash{2})
+
+	pingTime := time.Unix(1555556155, 0)
+	for _, offset := range []time.Duration{0, -30 * time.Second, 45 * time.Second} {
+		ping := MasternodePing{
+			OutpointHash:  chainhash.Hash{1}.String(),
+			PrivateKey:    wif.String(),
+			PingTime:      pingTime,
+			MagicMessage:  testMagicMessage,
+			HashQueue:     queue,
+			SigTimeOffset: offset,
+			Profile:       profile,
+		}
+
+		mnp := ping.GenerateMasternodePing(0, 0)
+		if want := uint64(pingTime.Add(offset).Unix()); mnp.SigTime != want {
+			t.Errorf("offset %s: SigTime = %d, want %d", offset, mnp.SigTime, want)
+		}
+	}
+}
+
+func TestExpireMessages(t *testing.T) {
+	now := time.Unix(1555555555, 0)
+	sigTime := func(age time.Duration) uint64 {
+		return uint64(now.Add(-age).Unix())
+	}
+
+	messageMap := map[string]wire.Message{
+		"fresh mnp":   &wire.MsgMNP{SigTime: sigTime(time.Minute)},
+		"edge mnp":    &wire.MsgMNP{SigTime: sigTime(5 * time.Minute)},
+		"stale mnp":   &wire.MsgMNP{SigTime: sigTime(5*time.Minute + time.Second)},
+		"fresh mnb":   &wire.MsgMNB{SigTime: sigTime(time.Hour), LastPing: wire.MsgMNP{SigTime: sigTime(time.Minute)}},
+		"stale mnb":   &wire.MsgMNB{SigTime: sigTime(time.Minute), LastPing: wire.MsgMNP{SigTime: sigTime(6 * time.Minute)}},
+		"other types": &wire.MsgPing{},
+	}
+
+	expireMessages(messageMap, now)
+
+	for _, kept := range []string{"fresh mnp", "edge mnp", "fresh mnb", "other types"} {
+		if _, ok := messageMap[kept]; !ok {
+			t.Errorf("%s expired", kept)
+		}
+	}
+	for _, expired := range []string{"stale mnp", "stale mnb"} {
+		if _, ok := messageMap[expired]; ok {
+			t.Errorf("%s kept", expired)
+		}
+	}
+}
+
+func TestBroadcastAnnounceExpiry(t *testing.T) {
+	sigTime := time.Unix(1555555555, 0)
+
+	collateral, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mnb := signedBroadcast(t, collateral, 0, sigTime, SignatureHash)
+	outpoint := mnb.Vin.PreviousOutPoint.String()
+
+	store := NewBroadcastStore("")
+	store.Clock = fixedClock(sigTime)
+	store.MagicMessage = testMagicMessage
+	if _, err := store.Add(mnb); err != nil {
+		t.Fatal(err)
+	}
+
+	ping := MasternodePing{OutpointHash: chainhash.Hash{1}.String()}
+	ping.AttachBroadcastTemplate(store)
+	if ping.BroadcastTemplate == nil {
+		t.Fatal("new broadcast not attached")
+	}
+
+	store.Clock = fixedClock(sigTime.Add(24*time.Hour - time.Second))
+	if !store.NeedsAnnounce(outpoint) {
+		t.Error("broadcast expired before 24h")
+	}
+
+	store.Clock = fixedClock(sigTime.Add(24 * time.Hour))
+	ping = MasternodePing{OutpointHash: chainhash.Hash{1}.String()}
+	ping.AttachBroadcastTemplate(store)
+	if ping.BroadcastTemplate != nil {
+		t.Error("broadcast still attached after 24h")
+	}
+
+	//a list sync missing the masternode announces it again, without expiry
+	store.SetAnnounce(outpoint, true)
+	store.Clock = fixedClock(sigTime.Add(48 * time.Hour))
+	if !store.NeedsAnnounce(outpoint) {
+		t.Error("broadcast flagged by a list sync not announced")
+	}
+}
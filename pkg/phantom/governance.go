@@ -0,0 +1,123 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantom
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"strings"
+	"time"
+)
+
+var voteOutcomes = map[string]int32{
+	"yes":     wire.VoteOutcomeYes,
+	"no":      wire.VoteOutcomeNo,
+	"abstain": wire.VoteOutcomeAbstain,
+}
+
+// ParseVoteOutcome returns the vote outcome for yes, no or abstain.
+func ParseVoteOutcome(outcome string) (int32, error) {
+	if value, ok := voteOutcomes[strings.ToLower(outcome)]; ok {
+		return value, nil
+	}
+	return wire.VoteOutcomeNone, fmt.Errorf("unknown vote outcome %q (expected yes, no or abstain)", outcome)
+}
+
+// GenerateGovernanceVote builds a funding vote on parent from the masternode
+// of ping, signed with its key using the ping's signature scheme.
+func (ping *MasternodePing) GenerateGovernanceVote(parent chainhash.Hash, outcome int32, now time.Time) (wire.MsgGovObjVote, error) {
+	var outpointHash chainhash.Hash
+	err := chainhash.Decode(&outpointHash, ping.OutpointHash)
+	if err != nil {
+		return wire.MsgGovObjVote{}, err
+	}
+
+	vote := wire.MsgGovObjVote{
+		Vin:        *wire.NewTxIn(wire.NewOutPoint(&outpointHash, ping.OutpointIndex), nil, nil),
+		ParentHash: parent,
+		Outcome:    outcome,
+		Signal:     wire.VoteSignalFunding,
+		Time:       now.UTC().Unix(),
+		Profile:    ping.Profile,
+	}
+
+	wif, err := btcutil.DecodeWIF(ping.PrivateKey)
+	if err != nil {
+		return wire.MsgGovObjVote{}, err
+	}
+
+	var hash []byte
+	switch ping.SignatureScheme {
+	case SignatureHash:
+		signatureHash := vote.SignatureHash()
+		hash = signatureHash[:]
+	default:
+		hash = signedMessageHash(ping.MagicMessage, vote.SignatureMessage())
+	}
+
+	vote.Sig, err = btcec.SignCompact(btcec.S256(), wif.PrivKey, hash, false)
+	if err != nil {
+		return wire.MsgGovObjVote{}, err
+	}
+
+	return vote, nil
+}
+
+// GovernanceObjectName returns the name of a proposal, or an empty string
+// when the object data can't be read. Older daemons wrap the fields as
+// [["proposal", {...}]], newer ones store the bare object.
+func GovernanceObjectName(obj *wire.MsgGovObj) string {
+	raw, err := hex.DecodeString(obj.Data)
+	if err != nil {
+		return ""
+	}
+
+	var fields struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(raw, &fields) == nil {
+		return fields.Name
+	}
+
+	var wrapped [][]json.RawMessage
+	if json.Unmarshal(raw, &wrapped) == nil && len(wrapped) > 0 && len(wrapped[0]) > 1 {
+		if json.Unmarshal(wrapped[0][1], &fields) == nil {
+			return fields.Name
+		}
+	}
+
+	return ""
+}
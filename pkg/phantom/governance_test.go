@@ -0,0 +1,128 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom
+
+import (
+	"encoding/hex"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"testing"
+	"time"
+)
+
+func TestGenerateGovernanceVote(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wif, err := btcutil.NewWIF(key, &chaincfg.MainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proposal := chainhash.Hash{9}
+	now := time.Unix(1555555555, 0)
+
+	for _, scheme := range []SignatureScheme{SignatureLegacy, SignatureHash} {
+		ping := MasternodePing{
+			OutpointHash:    testCollateral,
+			OutpointIndex:   1,
+			PrivateKey:      wif.String(),
+			MagicMessage:    testMagicMessage,
+			SignatureScheme: scheme,
+		}
+
+		vote, err := ping.GenerateGovernanceVote(proposal, wire.VoteOutcomeNo, now)
+		if err != nil {
+			t.Fatalf("%s: %s", scheme, err)
+		}
+		if vote.ParentHash != proposal || vote.Outcome != wire.VoteOutcomeNo || vote.Signal != wire.VoteSignalFunding ||
+			vote.Time != now.Unix() || vote.Vin.PreviousOutPoint.String() != testCollateral+":1" {
+			t.Errorf("%s: vote %+v", scheme, vote)
+		}
+
+		signatureHash := vote.SignatureHash()
+		hash, other := signedMessageHash(testMagicMessage, vote.SignatureMessage()), signatureHash[:]
+		if scheme == SignatureHash {
+			hash, other = other, hash
+		}
+		if !signedBy(vote.Sig, key.PubKey(), hash) {
+			t.Errorf("%s: the vote isn't signed by the masternode key", scheme)
+		}
+		if signedBy(vote.Sig, key.PubKey(), other) {
+			t.Errorf("%s: the vote is signed with the other scheme", scheme)
+		}
+	}
+
+	for _, ping := range []MasternodePing{
+		{OutpointHash: "not a txid", PrivateKey: wif.String()},
+		{OutpointHash: testCollateral, PrivateKey: "not a key"},
+	} {
+		if _, err := ping.GenerateGovernanceVote(proposal, wire.VoteOutcomeYes, now); err == nil {
+			t.Errorf("GenerateGovernanceVote() of %+v succeeded", ping)
+		}
+	}
+}
+
+func TestParseVoteOutcome(t *testing.T) {
+	for outcome, want := range map[string]int32{
+		"yes":     wire.VoteOutcomeYes,
+		"No":      wire.VoteOutcomeNo,
+		"ABSTAIN": wire.VoteOutcomeAbstain,
+	} {
+		if got, err := ParseVoteOutcome(outcome); err != nil || got != want {
+			t.Errorf("ParseVoteOutcome(%q) = %d, %v", outcome, got, err)
+		}
+	}
+	if _, err := ParseVoteOutcome("maybe"); err == nil {
+		t.Error("ParseVoteOutcome(maybe) succeeded")
+	}
+}
+
+func TestGovernanceObjectName(t *testing.T) {
+	for data, want := range map[string]string{
+		`{"name":"fund-the-devs","type":1}`:                "fund-the-devs",
+		`[["proposal",{"name":"fund-the-devs","type":1}]]`: "fund-the-devs",
+		`[["trigger"]]`: "",
+		`not json`:      "",
+	} {
+		obj := wire.MsgGovObj{Data: hex.EncodeToString([]byte(data))}
+		if got := GovernanceObjectName(&obj); got != want {
+			t.Errorf("GovernanceObjectName(%s) = %q, want %q", data, got, want)
+		}
+	}
+
+	if got := GovernanceObjectName(&wire.MsgGovObj{Data: "zz"}); got != "" {
+		t.Errorf("GovernanceObjectName() of data that isn't hex = %q", got)
+	}
+}
@@ -0,0 +1,166 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	headerRequestTimeout = 1 * time.Minute //ask another peer after this
+	headerMemory         = 1 * time.Hour   //how long announced blocks are remembered
+
+	//how much easier than the hardest recent header a target may be, the
+	//daemons' retargeting moves well within this over an hour of blocks
+	maxTargetDrift = 4
+)
+
+// announcedBlock is a block hash seen in an inv.
+type announcedBlock struct {
+	requested time.Time
+	validated bool
+	target    *big.Int //set once validated
+}
+
+// HeaderTracker checks the proof of work of announced blocks before their
+// hashes are used to sign pings. A block's header is requested when its hash
+// is announced, and the hash is only released once a header meeting its
+// stated difficulty is served.
+//
+// A header states its own difficulty, so its target is bounded by PowLimit
+// and by the hardest header validated in the last hour. Otherwise a peer
+// could grind a header with next to no work for any hash it announces.
+type HeaderTracker struct {
+	Hasher   PowHasher
+	PowLimit *big.Int
+	Clock    Clock
+
+	blocks map[chainhash.Hash]*announcedBlock
+	mux    sync.Mutex
+}
+
+// Announced records a block inv and returns the getheaders to send for it,
+// or nil when its header was requested recently or already validated.
+func (tracker *HeaderTracker) Announced(hash chainhash.Hash) *wire.MsgGetHeaders {
+	tracker.mux.Lock()
+	defer tracker.mux.Unlock()
+
+	now := clockOrDefault(tracker.Clock).Now()
+	tracker.forget(now)
+
+	block, ok := tracker.blocks[hash]
+	if ok && (block.validated || now.Sub(block.requested) < headerRequestTimeout) {
+		return nil
+	}
+	tracker.blocks[hash] = &announcedBlock{requested: now}
+
+	//peers answer a getheaders without locator with the stop header alone
+	getheaders := wire.NewMsgGetHeaders()
+	getheaders.HashStop = hash
+	return getheaders
+}
+
+// Headers checks the headers a peer served and returns the announced hashes
+// whose proof of work is valid. Rejected counts the headers that failed, a
+// peer serving any is feeding us fake blocks.
+func (tracker *HeaderTracker) Headers(headers []*wire.BlockHeader, peer string) (valid []chainhash.Hash, rejected int) {
+	tracker.mux.Lock()
+	defer tracker.mux.Unlock()
+
+	log := powLog.With("peer", peer)
+	unknown := 0
+
+	for _, header := range headers {
+		hash, target, err := CheckProofOfWork(header, tracker.Hasher, tracker.maxTarget())
+		if err != nil {
+			log.With("block", hash.String()).Warnf("Rejecting block header: %s", err)
+			rejected++
+			continue
+		}
+
+		block, ok := tracker.blocks[hash]
+		if !ok {
+			unknown++
+			continue
+		}
+		if block.validated {
+			continue
+		}
+
+		block.validated = true
+		block.target = target
+		valid = append(valid, hash)
+		log.With("block", hash.String()).Debugf("Block header proof of work verified.")
+	}
+
+	if unknown > 0 && len(valid) == 0 && rejected == 0 {
+		log.Warnf("Received %d block header(s) matching no announced block, check the coin's pow_algorithm.", unknown)
+	}
+
+	return valid, rejected
+}
+
+// maxTarget returns the easiest target a header may have, nil when neither
+// a limit is configured nor a header was validated yet.
+func (tracker *HeaderTracker) maxTarget() *big.Int {
+	var hardest *big.Int
+	for _, block := range tracker.blocks {
+		if block.target != nil && (hardest == nil || block.target.Cmp(hardest) < 0) {
+			hardest = block.target
+		}
+	}
+
+	if hardest == nil {
+		return tracker.PowLimit
+	}
+
+	maxTarget := new(big.Int).Mul(hardest, big.NewInt(maxTargetDrift))
+	if tracker.PowLimit != nil && maxTarget.Cmp(tracker.PowLimit) > 0 {
+		return tracker.PowLimit
+	}
+	return maxTarget
+}
+
+// forget drops blocks announced too long ago to still matter.
+func (tracker *HeaderTracker) forget(now time.Time) {
+	if tracker.blocks == nil {
+		tracker.blocks = make(map[chainhash.Hash]*announcedBlock)
+	}
+
+	for hash, block := range tracker.blocks {
+		if now.Sub(block.requested) > headerMemory {
+			delete(tracker.blocks, hash)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testWIF returns a new key encoded for params.
+func testWIF(t *testing.T, params *chaincfg.Params) string {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wif, err := btcutil.NewWIF(key, params, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wif.String()
+}
+
+func TestReadMasternodeConf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "phantom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	wif := testWIF(t, &chaincfg.MainNetParams)
+	path := filepath.Join(dir, "masternode.conf")
+	conf := "# Masternode config file\n" +
+		"# Format: alias IP:port masternodeprivkey collateral_output_txid collateral_output_index\n" +
+		"\n" +
+		fmt.Sprintf("mn1 45.50.22.125:9999 %s %s 1\n", wif, testCollateral) +
+		fmt.Sprintf("  mn2 45.50.22.126:9999 %s %s 0 1555555555  \r\n", wif, testCollateral) +
+		fmt.Sprintf("mn3 45.50.22.127:9999 %s %s one\n", wif, testCollateral) +
+		fmt.Sprintf("mn4 45.50.22.128:9999 %s\n", wif)
+	if err := ioutil.WriteFile(path, []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := ReadMasternodeConf(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		alias   string
+		line    int
+		problem string
+		warns   bool
+	}{
+		{"mn1", 4, "", false},
+		{"mn2", 5, "", true},
+		{"mn3", 6, `invalid collateral output index "one"`, false},
+		{"mn4", 7, "expected 5 or 6 fields, found 3", false},
+	}
+	if len(imported) != len(want) {
+		t.Fatalf("read %d masternodes, want %d", len(imported), len(want))
+	}
+	for i, masternode := range imported {
+		if masternode.Alias != want[i].alias || masternode.Source != fmt.Sprintf("%s:%d", path, want[i].line) ||
+			masternode.Problem != want[i].problem || (len(masternode.Warnings) > 0) != want[i].warns {
+			t.Errorf("masternode %d: %+v, want %+v", i, masternode, want[i])
+		}
+	}
+	if imported[0].OutpointIndex != 1 || imported[0].PrivateKey != wif || imported[1].Epoch != 1555555555 {
+		t.Errorf("read %+v", imported[:2])
+	}
+
+	if _, err := ReadMasternodeConf(filepath.Join(dir, "missing.conf")); err == nil {
+		t.Error("reading a missing masternode.conf succeeded")
+	}
+}
+
+func TestCheckImports(t *testing.T) {
+	mainnet := testWIF(t, &chaincfg.MainNetParams)
+	other := testWIF(t, &chaincfg.MainNetParams)
+	testnet := testWIF(t, &chaincfg.TestNet3Params)
+
+	//another last digit keeps the length but breaks the checksum
+	corrupted := []byte(other)
+	if corrupted[len(corrupted)-1] == '2' {
+		corrupted[len(corrupted)-1] = '3'
+	} else {
+		corrupted[len(corrupted)-1] = '2'
+	}
+
+	existing := []MasternodeEntry{
+		{Alias: "mn1", Address: "45.50.22.125:9999", PrivateKey: mainnet, OutpointHash: testCollateral, OutpointIndex: 1},
+	}
+	newTxid := strings.Repeat("ab", 32)
+
+	for _, test := range []struct {
+		name     string
+		existing []MasternodeEntry
+		lines    []string
+		problems []string
+		warnings []string
+	}{
+		{"valid", existing,
+			[]string{"mn2 45.50.22.126:9999 " + other + " " + newTxid + " 0"},
+			[]string{""}, []string{""}},
+		{"malformed WIF", existing,
+			[]string{"mn2 45.50.22.126:9999 5notakey " + newTxid + " 0"},
+			[]string{"isn't a valid WIF private key"}, []string{""}},
+		{"WIF with a bad checksum", existing,
+			[]string{"mn2 45.50.22.126:9999 " + string(corrupted) + " " + newTxid + " 0"},
+			[]string{"isn't a valid WIF private key: checksum mismatch"}, []string{""}},
+		{"wrong network WIF", existing,
+			[]string{"mn2 45.50.22.126:9999 " + testnet + " " + newTxid + " 0"},
+			[]string{"another network, its version byte is 0xef where mn1 in the masternode file's is 0x80"},
+			[]string{""}},
+		{"wrong network WIF within the import", nil,
+			[]string{"mn2 45.50.22.126:9999 " + other + " " + newTxid + " 0",
+				"mn3 45.50.22.127:9999 " + testnet + " " + newTxid + " 1"},
+			[]string{"", "another network, its version byte is 0xef where mn2 (conf:1)'s is 0x80"},
+			[]string{"", ""}},
+		{"short txid", existing,
+			[]string{"mn2 45.50.22.126:9999 " + other + " abcd 0"},
+			[]string{`collateral txid "abcd" isn't 64 hex characters`}, []string{""}},
+		{"txid that isn't hex", existing,
+			[]string{"mn2 45.50.22.126:9999 " + other + " " + strings.Repeat("zz", 32) + " 0"},
+			[]string{"collateral txid"}, []string{""}},
+		{"invalid address", existing,
+			[]string{"mn2 45.50.22.126 " + other + " " + newTxid + " 0"},
+			[]string{`address "45.50.22.126"`}, []string{""}},
+		{"every problem", existing,
+			[]string{"mn2 nowhere 5notakey abcd 0"},
+			[]string{`address "nowhere": invalid ip:port pair; the masternode key isn't a valid WIF private key: ` +
+				`malformed private key; collateral txid "abcd" isn't 64 hex characters`}, []string{""}},
+		{"alias in the file", existing,
+			[]string{"mn1 45.50.22.126:9999 " + other + " " + newTxid + " 0"},
+			[]string{"duplicate alias, already used by mn1 in the masternode file"}, []string{""}},
+		{"collateral in the file", existing,
+			[]string{"mn2 45.50.22.126:9999 " + other + " " + testCollateral + " 1"},
+			[]string{"duplicate collateral " + testCollateral + ":1, already used by mn1 in the masternode file"},
+			[]string{""}},
+		{"same collateral, other output", existing,
+			[]string{"mn2 45.50.22.126:9999 " + other + " " + testCollateral + " 0"},
+			[]string{""}, []string{""}},
+		{"duplicates within the import", existing,
+			[]string{"mn2 45.50.22.126:9999 " + other + " " + newTxid + " 0",
+				"mn2 45.50.22.127:9999 " + other + " " + newTxid + " 1",
+				"mn3 45.50.22.128:9999 " + other + " " + newTxid + " 0"},
+			[]string{"", "duplicate alias, already used by mn2 (conf:1)",
+				"duplicate collateral " + newTxid + ":0, already used by mn2 (conf:1)"},
+			[]string{"", "", ""}},
+		{"shared address and key", existing,
+			[]string{"mn2 45.50.22.125:9999 " + mainnet + " " + newTxid + " 0"},
+			[]string{""},
+			[]string{"45.50.22.125:9999 is also the address of mn1 in the masternode file; " +
+				"the masternode key is also used by mn1 in the masternode file"}},
+	} {
+		imported := make([]ImportedMasternode, 0, len(test.lines))
+		for i, line := range test.lines {
+			entry, err := ParseMasternodeEntry(line)
+			if err != nil {
+				t.Fatalf("%s: %s", test.name, err)
+			}
+			imported = append(imported, ImportedMasternode{MasternodeEntry: entry, Source: fmt.Sprintf("conf:%d", i+1)})
+		}
+
+		CheckImports(test.existing, imported)
+
+		for i, masternode := range imported {
+			problem, warnings := test.problems[i], test.warnings[i]
+			if (problem == "") != (masternode.Problem == "") || !strings.Contains(masternode.Problem, problem) {
+				t.Errorf("%s: %s problem %q, want %q", test.name, masternode.Source, masternode.Problem, problem)
+			}
+			if got := strings.Join(masternode.Warnings, "; "); got != warnings {
+				t.Errorf("%s: %s warnings %q, want %q", test.name, masternode.Source, got, warnings)
+			}
+		}
+	}
+
+	//lines that didn't parse keep their problem
+	imported := []ImportedMasternode{{MasternodeEntry: MasternodeEntry{Alias: "mn2"}, Problem: "expected 5 or 6 fields"}}
+	CheckImports(existing, imported)
+	if imported[0].Problem != "expected 5 or 6 fields" {
+		t.Errorf("problem replaced with %q", imported[0].Problem)
+	}
+}
+
+func TestSpreadEpochs(t *testing.T) {
+	now := time.Unix(1555555500, 0)
+	interval := 10 * time.Minute
+
+	for _, test := range []struct {
+		name     string
+		existing []int64
+		count    int
+		want     []int64
+	}{
+		//a minute from now, then halving the gaps
+		{"empty file", nil, 3, []int64{1555555560, 1555555860, 1555555710}},
+		{"epochless entries", []int64{0, 0}, 1, []int64{1555555560}},
+		//offsets 0 and 120, the widest gap is 120-600, then 360-600
+		{"existing slots", []int64{1555555200, 1555554720}, 2, []int64{1555555560, 1555555680}},
+		{"one slot", []int64{1555555200}, 1, []int64{1555556100}},
+	} {
+		epochs := SpreadEpochs(test.existing, test.count, interval, now)
+		if fmt.Sprint(epochs) != fmt.Sprint(test.want) {
+			t.Errorf("%s: SpreadEpochs() = %v, want %v", test.name, epochs, test.want)
+		}
+		for _, epoch := range epochs {
+			if epoch < now.Add(time.Minute).Unix() || epoch >= now.Add(time.Minute+interval).Unix() {
+				t.Errorf("%s: epoch %d isn't within an interval after a minute from now", test.name, epoch)
+			}
+		}
+	}
+}
+
+// testWallet answers wallet RPCs with the results by method, other methods
+// get the "Method not found" error wallets send.
+func testWallet(results map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		if user != "rpcuser" || password != "rpcpassword" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var request struct {
+			Method string   `json:"method"`
+			Params []string `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+
+		result, ok := results[strings.Join(append([]string{request.Method}, request.Params...), " ")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"result":null,"error":{"code":-32601,"message":"Method not found"},"id":"phantom"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"result":%s,"error":null,"id":"phantom"}`, result)
+	}))
+}
+
+func TestLoadWalletMasternodes(t *testing.T) {
+	wif := testWIF(t, &chaincfg.MainNetParams)
+
+	dash := testWallet(map[string]string{"masternode list-conf": `{
+		"masternode": {"alias": "mn1", "address": "45.50.22.125:9999", "privateKey": "` + wif + `",
+			"txHash": "` + testCollateral + `", "outputIndex": "1", "status": "ENABLED"},
+		"masternode": {"alias": "mn2", "address": "45.50.22.126:9999", "privateKey": "` + wif + `",
+			"txHash": "` + testCollateral + `", "outputIndex": "x", "status": "MISSING"}
+	}`})
+	defer dash.Close()
+
+	pivx := testWallet(map[string]string{"listmasternodeconf": `[
+		{"alias": "mn1", "address": "45.50.22.125:51472", "privateKey": "` + wif + `",
+			"txHash": "` + testCollateral + `", "outputIndex": 0, "status": "ENABLED"}
+	]`})
+	defer pivx.Close()
+
+	for _, test := range []struct {
+		url      string
+		aliases  []string
+		problems []string
+	}{
+		{strings.Replace(dash.URL, "http://", "http://rpcuser:rpcpassword@", 1), []string{"mn1", "mn2"},
+			[]string{"", `invalid collateral output index "x"`}},
+		{strings.Replace(pivx.URL, "http://", "http://rpcuser:rpcpassword@", 1), []string{"mn1"}, []string{""}},
+	} {
+		imported, err := LoadWalletMasternodes(test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(imported) != len(test.aliases) {
+			t.Fatalf("loaded %d masternodes, want %d", len(imported), len(test.aliases))
+		}
+		for i, masternode := range imported {
+			if masternode.Alias != test.aliases[i] || masternode.Problem != test.problems[i] ||
+				masternode.Source != fmt.Sprintf("rpc #%d", i+1) {
+				t.Errorf("masternode %d: %+v", i, masternode)
+			}
+		}
+		if imported[0].PrivateKey != wif || imported[0].OutpointHash != testCollateral {
+			t.Errorf("loaded %+v", imported[0])
+		}
+	}
+
+	if _, err := LoadWalletMasternodes(dash.URL); err == nil || !strings.Contains(err.Error(), "refused the RPC credentials") {
+		t.Errorf("loading without credentials: %v", err)
+	}
+
+	neither := testWallet(nil)
+	defer neither.Close()
+	_, err := LoadWalletMasternodes(strings.Replace(neither.URL, "http://", "http://rpcuser:rpcpassword@", 1))
+	if err == nil || !strings.Contains(err.Error(), "listmasternodeconf: Method not found (-32601)") {
+		t.Errorf("loading from a wallet without masternodes: %v", err)
+	}
+}
+
+func TestDecodeWalletMasternodes(t *testing.T) {
+	for _, test := range []struct {
+		result  string
+		aliases string
+		valid   bool
+	}{
+		{`[]`, "[]", true},
+		{`[{"alias":"mn1"},{"alias":"mn2"}]`, "[mn1 mn2]", true},
+		{`{"masternode":{"alias":"mn1"},"masternode":{"alias":"mn2"}}`, "[mn1 mn2]", true},
+		{`{}`, "[]", true},
+		{`"no masternodes"`, "", false},
+		{`{"masternode":"mn1"}`, "", false},
+		{`{"masternode":{"alias":"mn1"}`, "", false},
+	} {
+		masternodes, err := decodeWalletMasternodes(json.RawMessage(test.result))
+		if (err == nil) != test.valid {
+			t.Errorf("decodeWalletMasternodes(%s): %v", test.result, err)
+			continue
+		}
+
+		aliases := make([]string, 0, len(masternodes))
+		for _, masternode := range masternodes {
+			aliases = append(aliases, masternode.Alias)
+		}
+		if test.valid && fmt.Sprint(aliases) != test.aliases {
+			t.Errorf("decodeWalletMasternodes(%s) = %v, want %s", test.result, aliases, test.aliases)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// keccak256 is the original Keccak-256 with its 0x01 padding, as used by the
+// sph library coins link against, rather than the FIPS 202 SHA3-256.
+func keccak256(data []byte) [32]byte {
+	const rate = 136
+
+	var state [25]uint64
+	for len(data) >= rate {
+		keccakAbsorb(&state, data[:rate])
+		data = data[rate:]
+	}
+
+	var last [rate]byte
+	copy(last[:], data)
+	last[len(data)] ^= 0x01
+	last[rate-1] ^= 0x80
+	keccakAbsorb(&state, last[:])
+
+	var digest [32]byte
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint64(digest[i*8:], state[i])
+	}
+	return digest
+}
+
+func keccakAbsorb(state *[25]uint64, block []byte) {
+	for i := 0; i < len(block)/8; i++ {
+		state[i] ^= binary.LittleEndian.Uint64(block[i*8:])
+	}
+	keccakF1600(state)
+}
+
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000,
+	0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a,
+	0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+//rotation offsets and lane order of the combined rho and pi steps
+var keccakRotations = [24]int{1, 3, 6, 10, 15, 21, 28, 36, 45, 55, 2, 14, 27, 41, 56, 8, 25, 43, 62, 18, 39, 61, 20, 44}
+var keccakLanes = [24]int{10, 7, 11, 17, 18, 3, 5, 16, 8, 21, 24, 4, 15, 23, 19, 13, 12, 2, 20, 14, 22, 9, 6, 1}
+
+func keccakF1600(a *[25]uint64) {
+	var c [5]uint64
+
+	for round := 0; round < 24; round++ {
+		//theta
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d := c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+			for y := 0; y < 25; y += 5 {
+				a[y+x] ^= d
+			}
+		}
+
+		//rho and pi
+		current := a[1]
+		for i := 0; i < 24; i++ {
+			lane := keccakLanes[i]
+			current, a[lane] = a[lane], bits.RotateLeft64(current, keccakRotations[i])
+		}
+
+		//chi
+		for y := 0; y < 25; y += 5 {
+			copy(c[:], a[y:y+5])
+			for x := 0; x < 5; x++ {
+				a[y+x] = c[x] ^ (^c[(x+1)%5] & c[(x+2)%5])
+			}
+		}
+
+		//iota
+		a[0] ^= keccakRoundConstants[round]
+	}
+}
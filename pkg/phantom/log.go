@@ -0,0 +1,19 @@
+package phantom
+
+import "github.com/breakcrypto/phantom/pkg/logging"
+
+// Subsystem loggers, levels can be tuned individually (e.g. wire=debug).
+var (
+	peerLog       = logging.New("peer")
+	wireLog       = logging.New("wire")
+	schedulerLog  = logging.New("scheduler")
+	broadcastLog  = logging.New("broadcast")
+	bootstrapLog  = logging.New("bootstrap")
+	configLog     = logging.New("config")
+	paymentLog    = logging.New("payment")
+	verifyLog     = logging.New("verify")
+	collateralLog = logging.New("collateral")
+	powLog        = logging.New("pow")
+	notifyLog     = logging.New("notify")
+	controlLog    = logging.New("control")
+)
@@ -0,0 +1,274 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantom
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MasternodeEntry is a line of the masternode file: the wallet's
+// masternode.conf line followed by the epoch its ping slots derive from.
+type MasternodeEntry struct {
+	Alias         string
+	Address       string
+	PrivateKey    string
+	OutpointHash  string
+	OutpointIndex uint32
+	Epoch         int64
+}
+
+// ParseMasternodeEntry parses a masternode file line, the epoch is left at
+// zero when the line doesn't have one.
+func ParseMasternodeEntry(line string) (MasternodeEntry, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 5 && len(fields) != 6 {
+		return MasternodeEntry{}, fmt.Errorf("expected 5 or 6 fields, found %d", len(fields))
+	}
+
+	entry := MasternodeEntry{
+		Alias:        fields[0],
+		Address:      fields[1],
+		PrivateKey:   fields[2],
+		OutpointHash: fields[3],
+	}
+
+	index, err := strconv.ParseUint(fields[4], 10, 32)
+	if err != nil {
+		return entry, fmt.Errorf("invalid collateral output index %q", fields[4])
+	}
+	entry.OutpointIndex = uint32(index)
+
+	if len(fields) == 6 {
+		entry.Epoch, err = strconv.ParseInt(fields[5], 10, 64)
+		if err != nil || entry.Epoch < 0 {
+			return entry, fmt.Errorf("invalid epoch %q", fields[5])
+		}
+	}
+
+	return entry, nil
+}
+
+// Validate checks the address, masternode key and collateral outpoint of
+// the entry, listing every problem found.
+func (entry MasternodeEntry) Validate() error {
+	var problems []string
+
+	if entry.Alias == "" || strings.HasPrefix(entry.Alias, "#") {
+		problems = append(problems, fmt.Sprintf("invalid alias %q", entry.Alias))
+	}
+
+	_, err := SplitAddress(entry.Address)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("address %q: %s", entry.Address, err))
+	}
+
+	_, err = btcutil.DecodeWIF(entry.PrivateKey)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("the masternode key isn't a valid WIF private key: %s", err))
+	}
+
+	if len(entry.OutpointHash) != chainhash.MaxHashStringSize {
+		problems = append(problems, fmt.Sprintf("collateral txid %q isn't %d hex characters",
+			entry.OutpointHash, chainhash.MaxHashStringSize))
+	} else if _, err := chainhash.NewHashFromStr(entry.OutpointHash); err != nil {
+		problems = append(problems, fmt.Sprintf("collateral txid %q: %s", entry.OutpointHash, err))
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Outpoint returns the entry's collateral outpoint as used to key
+// broadcasts.
+func (entry MasternodeEntry) Outpoint() string {
+	return OutpointKey(entry.OutpointHash, entry.OutpointIndex)
+}
+
+// String formats the entry as a masternode file line.
+func (entry MasternodeEntry) String() string {
+	return fmt.Sprintf("%s %s %s %s %d %d", entry.Alias, entry.Address, entry.PrivateKey, entry.OutpointHash,
+		entry.OutpointIndex, entry.Epoch)
+}
+
+// ReadMasternodeFile returns the entries of the masternode file, skipping
+// comments and lines that don't parse. A missing file has no entries.
+func ReadMasternodeFile(path string) ([]MasternodeEntry, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []MasternodeEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		if entry, ok := masternodeLine(line); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// AddMasternodeEntry validates entry and appends it to the masternode file,
+// refusing aliases and collateral outpoints the file already has.
+func AddMasternodeEntry(path string, entry MasternodeEntry) error {
+	err := entry.Validate()
+	if err != nil {
+		return err
+	}
+
+	existing, err := ReadMasternodeFile(path)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range existing {
+		if other.Alias == entry.Alias {
+			return fmt.Errorf("the masternode file already has an entry for %s", entry.Alias)
+		}
+		if other.Outpoint() == entry.Outpoint() {
+			return fmt.Errorf("%s already uses the collateral %s", other.Alias, entry.Outpoint())
+		}
+	}
+
+	return AppendMasternodeEntries(path, []MasternodeEntry{entry})
+}
+
+// AppendMasternodeEntries adds entries to the end of the masternode file,
+// creating it when needed. Entries aren't checked.
+func AppendMasternodeEntries(path string, entries []MasternodeEntry) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	for _, entry := range entries {
+		data = append(data, entry.String()+"\n"...)
+	}
+
+	return writeMasternodeFile(path, data)
+}
+
+// RemoveMasternodeEntry removes the alias's line from the masternode file,
+// comments and other entries are kept as they are.
+func RemoveMasternodeEntry(path string, alias string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == alias {
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	if len(kept) == len(lines) {
+		return fmt.Errorf("the masternode file has no entry for %s", alias)
+	}
+	return writeMasternodeFile(path, []byte(strings.Join(kept, "")))
+}
+
+// assumedEpoch is the epoch given to the i-th masternode file line without
+// one, its first slot is a minute after now.
+func assumedEpoch(now time.Time, i int, pingInterval time.Duration) int64 {
+	return now.Add(time.Duration(i*5) * time.Second).Add(time.Minute - pingInterval).Unix()
+}
+
+// StoreMissingEpochs assumes an epoch for every masternode file line without
+// one and writes it to the line, so the alias keeps its slots across reloads
+// and restarts. It returns the epochs assigned, by alias.
+func StoreMissingEpochs(path string, now time.Time, pingInterval time.Duration) (map[string]int64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	assigned := make(map[string]int64)
+	lines := strings.SplitAfter(string(data), "\n")
+	for i, line := range lines {
+		body := strings.TrimRight(line, "\r\n")
+		fields := strings.Fields(body)
+		if len(fields) != 5 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		epoch := assumedEpoch(now, len(assigned), pingInterval)
+		assigned[fields[0]] = epoch
+		lines[i] = strings.TrimRight(body, " \t") + " " + strconv.FormatInt(epoch, 10) + line[len(body):]
+	}
+
+	if len(assigned) == 0 {
+		return nil, nil
+	}
+	return assigned, writeMasternodeFile(path, []byte(strings.Join(lines, "")))
+}
+
+// masternodeLine parses a line of the masternode file, skipping blank
+// lines, comments and lines that don't parse.
+func masternodeLine(line string) (MasternodeEntry, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' {
+		return MasternodeEntry{}, false
+	}
+
+	entry, err := ParseMasternodeEntry(line)
+	return entry, err == nil
+}
+
+//writeMasternodeFile replaces the file in one go, the scheduler may reload
+//it at any time
+func writeMasternodeFile(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	err := ioutil.WriteFile(tmpPath, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,358 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/breakcrypto/phantom/pkg/logging"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notification events.
+const (
+	EventLowConnections  = "low_connections"
+	EventNoBlocks        = "no_blocks"
+	EventMissedPing      = "missed_ping"
+	EventReject          = "reject"
+	EventMissingFromList = "missing_from_list"
+)
+
+const (
+	hookTimeout      = 30 * time.Second
+	hookQueueDepth   = 100
+	defaultRateLimit = 1 * time.Hour
+)
+
+// Notification is an alert about the phantom's health, or about it having
+// recovered. Subject is what the alert is about, i.e. an alias, and is empty
+// for alerts about the phantom as a whole.
+type Notification struct {
+	Event     string    `json:"event"`
+	Subject   string    `json:"subject,omitempty"`
+	Message   string    `json:"message"`
+	Recovered bool      `json:"recovered"`
+	Host      string    `json:"host,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// Title is a one line summary of the notification.
+func (notification Notification) Title() string {
+	state := "ALERT"
+	if notification.Recovered {
+		state = "RECOVERED"
+	}
+
+	title := "[phantom] " + state + " " + notification.Event
+	if notification.Subject != "" {
+		title += " " + notification.Subject
+	}
+	return title
+}
+
+// Hook delivers notifications somewhere.
+type Hook interface {
+	Notify(notification Notification) error
+	String() string
+}
+
+// WebhookHook POSTs every notification as JSON.
+type WebhookHook struct {
+	URL    string
+	Client *http.Client
+}
+
+func (hook *WebhookHook) Notify(notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	client := hook.Client
+	if client == nil {
+		client = &http.Client{Timeout: hookTimeout}
+	}
+
+	response, err := client.Post(hook.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("%s answered %s", hook.URL, response.Status)
+	}
+	return nil
+}
+
+func (hook *WebhookHook) String() string {
+	return "webhook " + hook.URL
+}
+
+// EmailHook mails every notification through an SMTP server, logging in
+// when a username is set.
+type EmailHook struct {
+	Server   string //host:port
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (hook *EmailHook) Notify(notification Notification) error {
+	var auth smtp.Auth
+	if hook.Username != "" {
+		host, _, err := net.SplitHostPort(hook.Server)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", hook.Username, hook.Password, host)
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", hook.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(hook.To, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", notification.Title())
+	fmt.Fprintf(&message, "Date: %s\r\n", notification.Time.Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&message, "%s\r\n\r\nHost: %s\r\nTime: %s\r\n", notification.Message, notification.Host,
+		notification.Time.UTC().Format(time.RFC3339))
+
+	return smtp.SendMail(hook.Server, auth, hook.From, hook.To, message.Bytes())
+}
+
+func (hook *EmailHook) String() string {
+	return "email to " + strings.Join(hook.To, ", ")
+}
+
+// CommandHook runs a program for every notification, with the notification
+// as JSON on stdin and in PHANTOM_* environment variables.
+type CommandHook struct {
+	Path string
+}
+
+func (hook *CommandHook) Notify(notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	command := exec.CommandContext(ctx, hook.Path)
+	command.Stdin = bytes.NewReader(body)
+	command.Env = append(os.Environ(),
+		"PHANTOM_EVENT="+notification.Event,
+		"PHANTOM_SUBJECT="+notification.Subject,
+		"PHANTOM_MESSAGE="+notification.Message,
+		fmt.Sprintf("PHANTOM_RECOVERED=%t", notification.Recovered),
+	)
+
+	output, err := command.CombinedOutput()
+	if err != nil && len(bytes.TrimSpace(output)) > 0 {
+		return fmt.Errorf("%s: %s", err, bytes.TrimSpace(output))
+	}
+	return err
+}
+
+func (hook *CommandHook) String() string {
+	return "command " + hook.Path
+}
+
+// alert is a raised notification waiting to recover.
+type alert struct {
+	raised     time.Time
+	lastSent   time.Time
+	suppressed int
+}
+
+// Notifier raises alerts and their recoveries on every hook. An alert that
+// is raised again while active is repeated at most once per RateLimit, and
+// clearing an active alert sends a recovery. A nil notifier ignores
+// everything.
+type Notifier struct {
+	Hooks     []Hook
+	RateLimit time.Duration
+	Host      string
+	Clock     Clock
+
+	alerts map[string]*alert
+	queues []chan Notification
+	start  sync.Once
+	mux    sync.Mutex
+	wg     sync.WaitGroup
+}
+
+func alertKey(event string, subject string) string {
+	return event + "/" + subject
+}
+
+// Raise sends an alert unless the same one was sent within the rate limit.
+func (notifier *Notifier) Raise(event string, subject string, format string, args ...interface{}) {
+	if notifier == nil {
+		return
+	}
+
+	notifier.mux.Lock()
+	now := clockOrDefault(notifier.Clock).Now()
+	if notifier.alerts == nil {
+		notifier.alerts = make(map[string]*alert)
+	}
+
+	key := alertKey(event, subject)
+	active, ok := notifier.alerts[key]
+	if ok && now.Sub(active.lastSent) < notifier.rateLimit() {
+		active.suppressed++
+		notifier.mux.Unlock()
+		return
+	}
+
+	message := fmt.Sprintf(format, args...)
+	if !ok {
+		active = &alert{raised: now}
+		notifier.alerts[key] = active
+	} else if active.suppressed > 0 {
+		message += fmt.Sprintf(" (raised %d more times since %s)", active.suppressed, active.lastSent.UTC().Format(time.RFC3339))
+	}
+	active.lastSent = now
+	active.suppressed = 0
+	notifier.mux.Unlock()
+
+	notifier.send(Notification{Event: event, Subject: subject, Message: message, Time: now})
+}
+
+// Clear sends a recovery for an active alert, nothing otherwise.
+func (notifier *Notifier) Clear(event string, subject string, format string, args ...interface{}) {
+	if notifier == nil {
+		return
+	}
+
+	notifier.mux.Lock()
+	now := clockOrDefault(notifier.Clock).Now()
+	key := alertKey(event, subject)
+	active, ok := notifier.alerts[key]
+	if ok {
+		delete(notifier.alerts, key)
+	}
+	notifier.mux.Unlock()
+
+	if !ok {
+		return
+	}
+
+	message := fmt.Sprintf(format, args...) +
+		fmt.Sprintf(" (alert raised %s ago)", now.Sub(active.raised).Round(time.Second))
+	notifier.send(Notification{Event: event, Subject: subject, Message: message, Recovered: true, Time: now})
+}
+
+// Active returns the event and subject of every raised alert.
+func (notifier *Notifier) Active() []string {
+	if notifier == nil {
+		return nil
+	}
+
+	notifier.mux.Lock()
+	defer notifier.mux.Unlock()
+
+	active := make([]string, 0, len(notifier.alerts))
+	for key := range notifier.alerts {
+		active = append(active, key)
+	}
+	return active
+}
+
+// Wait blocks until every notification sent so far has been delivered.
+func (notifier *Notifier) Wait() {
+	if notifier != nil {
+		notifier.wg.Wait()
+	}
+}
+
+func (notifier *Notifier) rateLimit() time.Duration {
+	if notifier.RateLimit == 0 {
+		return defaultRateLimit
+	}
+	return notifier.RateLimit
+}
+
+// send queues a notification on every hook. Each hook delivers in the
+// background and in order, a slow mail server mustn't hold up pinging or
+// deliver a recovery before its alert.
+func (notifier *Notifier) send(notification Notification) {
+	notification.Host = notifier.Host
+
+	notifier.start.Do(func() {
+		for _, hook := range notifier.Hooks {
+			queue := make(chan Notification, hookQueueDepth)
+			notifier.queues = append(notifier.queues, queue)
+			go notifier.deliver(hook, queue)
+		}
+	})
+
+	notificationLog(notification).Infof("%s: %s", notification.Title(), notification.Message)
+
+	for i, queue := range notifier.queues {
+		notifier.wg.Add(1)
+		select {
+		case queue <- notification:
+		default:
+			notifier.wg.Done()
+			notificationLog(notification).Warnf("Dropping the notification, %s is falling behind.", notifier.Hooks[i])
+		}
+	}
+}
+
+func (notifier *Notifier) deliver(hook Hook, queue chan Notification) {
+	for notification := range queue {
+		err := hook.Notify(notification)
+		if err != nil {
+			notificationLog(notification).Warnf("Unable to notify through %s: %s", hook, err)
+		}
+		notifier.wg.Done()
+	}
+}
+
+func notificationLog(notification Notification) *logging.Logger {
+	log := notifyLog.With("event", notification.Event)
+	if notification.Subject != "" {
+		log = log.With("subject", notification.Subject)
+	}
+	return log
+}
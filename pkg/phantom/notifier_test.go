@@ -0,0 +1,160 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom_test
+
+import (
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"github.com/breakcrypto/phantom/pkg/phantom/phantomtest"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testNotifier(clock phantom.Clock) (*phantom.Notifier, *phantomtest.WebhookServer) {
+	webhook := phantomtest.NewWebhookServer()
+	notifier := &phantom.Notifier{
+		Hooks:     []phantom.Hook{webhook.Hook()},
+		RateLimit: time.Hour,
+		Host:      "phantom-1",
+		Clock:     clock,
+	}
+	return notifier, webhook
+}
+
+func TestNotifierWebhook(t *testing.T) {
+	clock := phantomtest.NewFakeClock(time.Unix(1555555555, 0))
+	notifier, webhook := testNotifier(clock)
+	defer webhook.Close()
+
+	notifier.Raise(phantom.EventMissedPing, "mn1", "The ping for slot %d wasn't sent.", 1)
+	notification, err := webhook.WaitForNotification(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notification.Event != phantom.EventMissedPing || notification.Subject != "mn1" || notification.Recovered ||
+		notification.Host != "phantom-1" || !notification.Time.Equal(clock.Now()) {
+		t.Errorf("alert = %+v", notification)
+	}
+	if notification.Message != "The ping for slot 1 wasn't sent." {
+		t.Errorf("alert message = %q", notification.Message)
+	}
+
+	//repeats within the rate limit are counted, not sent
+	clock.Advance(time.Minute)
+	notifier.Raise(phantom.EventMissedPing, "mn1", "The ping for slot %d wasn't sent.", 2)
+	clock.Advance(time.Minute)
+	notifier.Raise(phantom.EventMissedPing, "mn1", "The ping for slot %d wasn't sent.", 3)
+	notifier.Wait()
+	if got := len(webhook.Notifications()); got != 1 {
+		t.Fatalf("%d notifications sent within the rate limit, want 1", got)
+	}
+
+	clock.Advance(time.Hour)
+	notifier.Raise(phantom.EventMissedPing, "mn1", "The ping for slot %d wasn't sent.", 4)
+	notification, err = webhook.WaitForNotification(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(notification.Message, "The ping for slot 4 wasn't sent. (raised 2 more times since ") {
+		t.Errorf("repeated alert message = %q", notification.Message)
+	}
+
+	clock.Advance(time.Minute)
+	notifier.Clear(phantom.EventMissedPing, "mn1", "Pings are sent again.")
+	notification, err = webhook.WaitForNotification(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !notification.Recovered || notification.Subject != "mn1" {
+		t.Errorf("recovery = %+v", notification)
+	}
+	if want := "Pings are sent again. (alert raised 1h3m0s ago)"; notification.Message != want {
+		t.Errorf("recovery message = %q, want %q", notification.Message, want)
+	}
+	if active := notifier.Active(); len(active) != 0 {
+		t.Errorf("Active() after Clear = %v", active)
+	}
+
+	//clearing an alert that isn't raised sends nothing
+	notifier.Clear(phantom.EventMissedPing, "mn1", "Pings are sent again.")
+	notifier.Clear(phantom.EventNoBlocks, "", "New blocks are announced again.")
+	notifier.Wait()
+	if got := len(webhook.Notifications()); got != 3 {
+		t.Errorf("%d notifications sent, want 3", got)
+	}
+
+	//once recovered, the next alert goes out right away
+	notifier.Raise(phantom.EventMissedPing, "mn1", "The ping for slot %d wasn't sent.", 5)
+	notification, err = webhook.WaitForNotification(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notification.Recovered || notification.Message != "The ping for slot 5 wasn't sent." {
+		t.Errorf("alert after recovery = %+v", notification)
+	}
+}
+
+func TestNotifierAlertsAreIndependent(t *testing.T) {
+	clock := phantomtest.NewFakeClock(time.Unix(1555555555, 0))
+	notifier, webhook := testNotifier(clock)
+	defer webhook.Close()
+
+	notifier.Raise(phantom.EventMissedPing, "mn1", "mn1 missed a ping.")
+	notifier.Raise(phantom.EventMissedPing, "mn2", "mn2 missed a ping.")
+	notifier.Raise(phantom.EventLowConnections, "", "Only 1 peer(s) connected, 3 expected.")
+	notifier.Wait()
+
+	if got := len(webhook.Notifications()); got != 3 {
+		t.Errorf("%d notifications sent, want one per alert", got)
+	}
+	if got := len(notifier.Active()); got != 3 {
+		t.Errorf("%d active alerts, want 3", got)
+	}
+}
+
+func TestNotifierWebhookFailure(t *testing.T) {
+	clock := phantomtest.NewFakeClock(time.Unix(1555555555, 0))
+	notifier, webhook := testNotifier(clock)
+	defer webhook.Close()
+	webhook.Status = http.StatusInternalServerError
+
+	if err := webhook.Hook().Notify(phantom.Notification{Event: phantom.EventNoBlocks}); err == nil {
+		t.Error("Notify() ignored a 500 answer")
+	}
+
+	//a failing hook doesn't stop later notifications
+	notifier.Raise(phantom.EventNoBlocks, "", "No new block announced for 30m0s.")
+	notifier.Clear(phantom.EventNoBlocks, "", "New blocks are announced again.")
+	notifier.Wait()
+	if got := len(webhook.Notifications()); got != 3 {
+		t.Errorf("%d notifications posted, want 3", got)
+	}
+}
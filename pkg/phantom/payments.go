@@ -0,0 +1,426 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantom
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// PaymentSignaturesTotal is how many of the top ranked masternodes vote
+	// on the payee of a block.
+	PaymentSignaturesTotal = 10
+
+	// paymentVoteDelay is how long votes for a height are collected before
+	// ours are cast for the leading payee.
+	paymentVoteDelay = 30 * time.Second
+
+	// paymentHeightsKept bounds the number of heights being tracked.
+	paymentHeightsKept = 20
+
+	// paymentHeightsAhead is how far past the chain tip votes are accepted,
+	// masternodes vote for the block 10 blocks ahead.
+	paymentHeightsAhead = 20
+
+	// paymentTipRefresh is how long a looked up chain tip is used.
+	paymentTipRefresh = 1 * time.Minute
+)
+
+// unscoredProfiles are message profiles whose masternode scoring isn't
+// implemented. Dash 12.2 hashes the block the collateral got its minimum
+// confirmations in into the score, which isn't known here.
+var unscoredProfiles = map[string]bool{
+	"dash-12.2": true,
+}
+
+// CheckPaymentScoring returns an error when masternodes can't be ranked for
+// payments with profile, voting then would sign votes from masternodes that
+// aren't in the top ranks.
+func CheckPaymentScoring(profile *wire.MessageProfile) error {
+	if profile != nil && unscoredProfiles[profile.Name] {
+		return fmt.Errorf("the masternode scoring of the %s message profile isn't implemented", profile.Name)
+	}
+	return nil
+}
+
+// RankedMasternode is a masternode's position in the payment vote ranking of
+// a block, rank 1 being the highest score.
+type RankedMasternode struct {
+	Outpoint  string
+	Rank      int
+	Score     *big.Int
+	Broadcast wire.MsgMNB
+}
+
+// hashToBig interprets a hash as the little endian 256 bit number the
+// daemon's arith_uint256 uses.
+func hashToBig(hash chainhash.Hash) *big.Int {
+	var reversed [chainhash.HashSize]byte
+	for i, b := range hash {
+		reversed[chainhash.HashSize-1-i] = b
+	}
+	return new(big.Int).SetBytes(reversed[:])
+}
+
+func bigToHash(n *big.Int) chainhash.Hash {
+	var hash chainhash.Hash
+	raw := n.Bytes()
+	for i := 0; i < len(raw) && i < chainhash.HashSize; i++ {
+		hash[i] = raw[len(raw)-1-i]
+	}
+	return hash
+}
+
+// MasternodeScore returns the payment score of a collateral for blockHash,
+// using the scoring of PIVX and Dash up to 12.1.
+func MasternodeScore(outpoint wire.OutPoint, blockHash chainhash.Hash) *big.Int {
+	modulus := new(big.Int).Lsh(big.NewInt(1), 256)
+	aux := new(big.Int).Add(hashToBig(outpoint.Hash), big.NewInt(int64(outpoint.Index)))
+	aux.Mod(aux, modulus)
+	auxHash := bigToHash(aux)
+
+	hash2 := hashToBig(chainhash.DoubleHashH(blockHash[:]))
+	hash3 := hashToBig(chainhash.DoubleHashH(append(blockHash[:], auxHash[:]...)))
+
+	return new(big.Int).Abs(new(big.Int).Sub(hash3, hash2))
+}
+
+// RankMasternodes orders the broadcasts at or above minProtocol by their
+// score for blockHash.
+func RankMasternodes(broadcasts []wire.MsgMNB, blockHash chainhash.Hash, minProtocol uint32) []RankedMasternode {
+	ranked := make([]RankedMasternode, 0, len(broadcasts))
+	for _, mnb := range broadcasts {
+		if mnb.ProtocolVersion < minProtocol {
+			continue
+		}
+
+		outpoint := mnb.Vin.PreviousOutPoint
+		ranked = append(ranked, RankedMasternode{
+			Outpoint:  OutpointKey(outpoint.Hash.String(), outpoint.Index),
+			Score:     MasternodeScore(outpoint, blockHash),
+			Broadcast: mnb,
+		})
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranked[i].Score.Cmp(ranked[j].Score) > 0
+	})
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+	}
+
+	return ranked
+}
+
+// GeneratePaymentVote builds a vote for payee at height from the masternode
+// of ping, signed with its key using the ping's signature scheme.
+func (ping *MasternodePing) GeneratePaymentVote(height int32, payee []byte) (wire.MsgMNW, error) {
+	var outpointHash chainhash.Hash
+	err := chainhash.Decode(&outpointHash, ping.OutpointHash)
+	if err != nil {
+		return wire.MsgMNW{}, err
+	}
+
+	vote := wire.MsgMNW{
+		Vin:         *wire.NewTxIn(wire.NewOutPoint(&outpointHash, ping.OutpointIndex), nil, nil),
+		BlockHeight: height,
+		Payee:       payee,
+		Profile:     ping.Profile,
+	}
+
+	wif, err := btcutil.DecodeWIF(ping.PrivateKey)
+	if err != nil {
+		return wire.MsgMNW{}, err
+	}
+
+	var hash []byte
+	switch ping.SignatureScheme {
+	case SignatureHash:
+		signatureHash := vote.SignatureHash()
+		hash = signatureHash[:]
+	default:
+		hash = signedMessageHash(ping.MagicMessage, vote.SignatureMessage())
+	}
+
+	vote.Sig, err = btcec.SignCompact(btcec.S256(), wif.PrivKey, hash, false)
+	if err != nil {
+		return wire.MsgMNW{}, err
+	}
+
+	return vote, nil
+}
+
+// paymentHeight is the vote tally for one block height.
+type paymentHeight struct {
+	firstSeen time.Time
+	ranking   []RankedMasternode
+	tally     map[string]int
+	voters    map[string]bool
+	voted     bool
+}
+
+// PaymentVoter tallies the payment votes of the network and, for heights
+// where our masternodes rank high enough to vote, casts their votes for the
+// payee the other top ranked masternodes agree on.
+type PaymentVoter struct {
+	Broadcasts      *BroadcastStore
+	Scheduler       *PingScheduler
+	Bootstrapper    Bootstrapper
+	MinProtocol     uint32
+	RankOffset      int
+	MagicMessage    string
+	SignatureScheme SignatureScheme
+	Profile         *wire.MessageProfile
+	Sporks          *SporkTable
+	Clock           Clock
+
+	heights map[int32]*paymentHeight
+	mux     sync.Mutex
+
+	//chain tip from the explorer, heights are only tracked close to it
+	tip        int32
+	tipKnown   bool
+	tipChecked time.Time
+	tipMux     sync.Mutex
+}
+
+// Ranking returns the payment ranking for height, the score is taken from
+// the block RankOffset blocks before it.
+func (voter *PaymentVoter) Ranking(height int32) ([]RankedMasternode, error) {
+	err := CheckPaymentScoring(voter.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	blockHash, err := voter.Bootstrapper.LoadBlockHashAt(int(height) - voter.RankOffset)
+	if err != nil {
+		return nil, err
+	}
+	return RankMasternodes(voter.Broadcasts.All(), blockHash, voter.MinProtocol), nil
+}
+
+// Observe verifies a vote from the network and counts it when it comes from
+// a masternode allowed to vote at its height.
+func (voter *PaymentVoter) Observe(vote wire.MsgMNW) error {
+	entry, err := voter.height(vote.BlockHeight)
+	if err != nil {
+		return err
+	}
+
+	outpoint := OutpointKey(vote.Vin.PreviousOutPoint.Hash.String(), vote.Vin.PreviousOutPoint.Index)
+
+	var voting *RankedMasternode
+	for i := range entry.ranking {
+		if entry.ranking[i].Outpoint == outpoint {
+			voting = &entry.ranking[i]
+			break
+		}
+	}
+	if voting == nil {
+		return errors.New("vote from an unknown masternode")
+	}
+	if voting.Rank > PaymentSignaturesTotal {
+		return errors.New("vote from a masternode ranked too low")
+	}
+
+	pubKey, err := btcec.ParsePubKey(voting.Broadcast.PubKeyMasternode, btcec.S256())
+	if err != nil {
+		return err
+	}
+	hash := vote.SignatureHash()
+	if !signedBy(vote.Sig, pubKey, hash[:], signedMessageHash(voter.MagicMessage, vote.SignatureMessage())) {
+		return errors.New("vote is not signed by the masternode key")
+	}
+
+	voter.mux.Lock()
+	defer voter.mux.Unlock()
+
+	if !entry.voters[outpoint] {
+		entry.voters[outpoint] = true
+		entry.tally[hex.EncodeToString(vote.Payee)]++
+	}
+	return nil
+}
+
+// height returns the tally for height, loading its ranking the first time.
+// Heights must be close to the chain tip, peers mustn't be able to push the
+// tracked heights around or make us look up any block they like.
+func (voter *PaymentVoter) height(height int32) (*paymentHeight, error) {
+	voter.mux.Lock()
+	entry, ok := voter.heights[height]
+	voter.mux.Unlock()
+	if ok {
+		return entry, nil
+	}
+
+	tip, err := voter.chainTip()
+	if err != nil {
+		return nil, err
+	}
+	if height <= tip-paymentHeightsKept {
+		return nil, errors.New("vote for a stale height")
+	}
+	if height > tip+paymentHeightsAhead {
+		return nil, fmt.Errorf("vote for a height too far past the chain tip (%d)", tip)
+	}
+
+	ranking, err := voter.Ranking(height)
+	if err != nil {
+		return nil, err
+	}
+
+	voter.mux.Lock()
+	defer voter.mux.Unlock()
+
+	if voter.heights == nil {
+		voter.heights = make(map[int32]*paymentHeight)
+	}
+	if entry, ok := voter.heights[height]; ok {
+		return entry, nil
+	}
+
+	entry = &paymentHeight{
+		firstSeen: clockOrDefault(voter.Clock).Now(),
+		ranking:   ranking,
+		tally:     make(map[string]int),
+		voters:    make(map[string]bool),
+	}
+	voter.heights[height] = entry
+	voter.prune(tip)
+
+	return entry, nil
+}
+
+// chainTip returns the explorer's chain tip, looked up once a minute at
+// most however many votes arrive.
+func (voter *PaymentVoter) chainTip() (int32, error) {
+	voter.tipMux.Lock()
+	defer voter.tipMux.Unlock()
+
+	now := clockOrDefault(voter.Clock).Now()
+	if now.Sub(voter.tipChecked) >= paymentTipRefresh {
+		voter.tipChecked = now
+
+		tip, err := voter.Bootstrapper.LoadBlockCount()
+		if err == nil {
+			voter.tip = int32(tip)
+			voter.tipKnown = true
+		}
+	}
+
+	if !voter.tipKnown {
+		return 0, errors.New("the chain tip isn't known")
+	}
+	return voter.tip, nil
+}
+
+// prune drops the heights that fell behind the tip. Must be called with the
+// mutex held.
+func (voter *PaymentVoter) prune(tip int32) {
+	for height := range voter.heights {
+		if height <= tip-paymentHeightsKept {
+			delete(voter.heights, height)
+		}
+	}
+}
+
+// Due signs the votes of our top ranked masternodes for every height whose
+// collection window has closed.
+func (voter *PaymentVoter) Due() []wire.MsgMNW {
+	now := clockOrDefault(voter.Clock).Now()
+
+	scheme := voter.SignatureScheme
+	if voter.Sporks.IsActive(SporkNewSigs) {
+		scheme = SignatureHash
+	}
+
+	voter.mux.Lock()
+	defer voter.mux.Unlock()
+
+	var votes []wire.MsgMNW
+	for height, entry := range voter.heights {
+		if entry.voted || now.Before(entry.firstSeen.Add(paymentVoteDelay)) {
+			continue
+		}
+		entry.voted = true
+
+		payee := leadingPayee(entry.tally)
+		if payee == nil {
+			continue
+		}
+
+		for _, masternode := range voter.Scheduler.Masternodes() {
+			outpoint := OutpointKey(masternode.OutpointHash, masternode.OutpointIndex)
+			for _, ranked := range entry.ranking {
+				if ranked.Outpoint != outpoint || ranked.Rank > PaymentSignaturesTotal {
+					continue
+				}
+
+				masternode.SignatureScheme = scheme
+				vote, err := masternode.GeneratePaymentVote(height, payee)
+				if err != nil {
+					paymentLog.With("alias", masternode.Name).Errorf("Unable to sign the payment vote: %s", err)
+					continue
+				}
+				votes = append(votes, vote)
+			}
+		}
+	}
+
+	return votes
+}
+
+// leadingPayee returns the payee with the most votes.
+func leadingPayee(tally map[string]int) []byte {
+	var leader string
+	best := 0
+	for payee, count := range tally {
+		if count > best || (count == best && payee < leader) {
+			leader, best = payee, count
+		}
+	}
+	if best == 0 {
+		return nil
+	}
+
+	payee, _ := hex.DecodeString(leader)
+	return payee
+}
@@ -0,0 +1,145 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantom
+
+import (
+	"fmt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testExplorer serves a chain with its tip at height 1000 and counts the
+// requests per API call.
+func testExplorer() (*httptest.Server, map[string]int, *sync.Mutex) {
+	requests := make(map[string]int)
+	var mux sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		requests[r.URL.Path]++
+		mux.Unlock()
+
+		switch r.URL.Path {
+		case "/api/getblockcount":
+			fmt.Fprint(w, "1000\n")
+		case "/api/getblockhash":
+			fmt.Fprint(w, chainhash.Hash{7}.String())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server, requests, &mux
+}
+
+func testVote(height int32) wire.MsgMNW {
+	return wire.MsgMNW{
+		Vin:         *wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil),
+		BlockHeight: height,
+	}
+}
+
+func TestPaymentVoterBoundsHeights(t *testing.T) {
+	explorer, requests, mux := testExplorer()
+	defer explorer.Close()
+
+	voter := &PaymentVoter{
+		Broadcasts:   NewBroadcastStore(""),
+		Bootstrapper: Bootstrapper{BaseURL: explorer.URL},
+		RankOffset:   101,
+		Clock:        fixedClock(time.Unix(1555555555, 0)),
+	}
+
+	tests := []struct {
+		height int32
+		err    string
+	}{
+		{5000, "too far past the chain tip"},
+		{1021, "too far past the chain tip"},
+		{980, "stale height"},
+		{1010, "unknown masternode"},
+		{981, "unknown masternode"},
+	}
+	for _, test := range tests {
+		err := voter.Observe(testVote(test.height))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("height %d: error %v, want %q", test.height, err, test.err)
+		}
+	}
+
+	//the tip is only looked up once a minute, known heights aren't loaded again
+	for i := 0; i < 10; i++ {
+		voter.Observe(testVote(6000 + int32(i)))
+		voter.Observe(testVote(1010))
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+	if requests["/api/getblockcount"] != 1 {
+		t.Errorf("the chain tip was looked up %d times, want once", requests["/api/getblockcount"])
+	}
+	if requests["/api/getblockhash"] != 2 {
+		t.Errorf("%d block hashes were looked up, want 2", requests["/api/getblockhash"])
+	}
+}
+
+func TestPaymentVoterUnscoredProfile(t *testing.T) {
+	profile, err := wire.LookupMessageProfile("dash-12.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CheckPaymentScoring(profile) == nil {
+		t.Fatalf("the dash-12.2 scoring was accepted")
+	}
+
+	explorer, requests, mux := testExplorer()
+	defer explorer.Close()
+
+	voter := &PaymentVoter{
+		Broadcasts:   NewBroadcastStore(""),
+		Bootstrapper: Bootstrapper{BaseURL: explorer.URL},
+		Profile:      profile,
+	}
+	if err := voter.Observe(testVote(1005)); err == nil {
+		t.Errorf("a vote was ranked with the dash-12.2 profile")
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+	if requests["/api/getblockhash"] != 0 {
+		t.Errorf("a block hash was looked up for an unranked profile")
+	}
+}
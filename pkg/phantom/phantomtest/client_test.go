@@ -0,0 +1,343 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantomtest
+
+import (
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testOutpoint = "2bcd3c84c84f87eaa86e4e56834c92927a07f9e18718810b92e0d0324456a67c"
+
+// testPinger is a PingerConnection started against a fake peer.
+type testPinger struct {
+	*phantom.PingerConnection
+	pings     chan phantom.MasternodePing
+	hashes    chan chainhash.Hash
+	waitGroup sync.WaitGroup
+	stopOnce  sync.Once
+}
+
+// newTestPinger sets up a pinger speaking the peer's profile, it's
+// configured further before start.
+func newTestPinger(peer *FakePeer, clock phantom.Clock, hashDepth int) *testPinger {
+	pinger := &testPinger{
+		pings:  make(chan phantom.MasternodePing, 10),
+		hashes: make(chan chainhash.Hash, hashDepth),
+	}
+	pinger.PingerConnection = peer.NewPingerConnection(pinger.pings, pinger.hashes, &pinger.waitGroup)
+	pinger.Clock = clock
+	pinger.Profile = peer.Profile
+	return pinger
+}
+
+func (pinger *testPinger) start() {
+	pinger.waitGroup.Add(1)
+	go pinger.Start("/phantomtest:0.0.1/")
+}
+
+func startPinger(t *testing.T, peer *FakePeer, clock phantom.Clock, hashDepth int) *testPinger {
+	pinger := newTestPinger(peer, clock, hashDepth)
+	pinger.start()
+	return pinger
+}
+
+// stop closes the ping channel, which shuts the pinger down, and waits for
+// it to return.
+func (pinger *testPinger) stop(t *testing.T) {
+	pinger.stopOnce.Do(func() { close(pinger.pings) })
+
+	done := make(chan struct{})
+	go func() {
+		pinger.waitGroup.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the pinger didn't shut down")
+	}
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// advanceWhenWaiting moves clock on by d once something is waiting on it.
+func advanceWhenWaiting(t *testing.T, clock *FakeClock, d time.Duration) {
+	t.Helper()
+
+	waitFor(t, "a timer", func() bool { return clock.PendingTimers() > 0 })
+	clock.Advance(d)
+}
+
+func testKey(t *testing.T) (*btcec.PrivateKey, string) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wif, err := btcutil.NewWIF(key, &chaincfg.MainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, wif.String()
+}
+
+func testPing(wif string, hashes *phantom.Queue, sigTime time.Time) phantom.MasternodePing {
+	return phantom.MasternodePing{
+		Name:            "mn1",
+		OutpointHash:    testOutpoint,
+		OutpointIndex:   1,
+		PrivateKey:      wif,
+		PingTime:        sigTime,
+		MagicMessage:    testMagicMessage,
+		HashQueue:       hashes,
+		SignatureScheme: phantom.SignatureHash,
+	}
+}
+
+func newTestPeer(t *testing.T) *FakePeer {
+	peer, err := NewFakePeer(wire.BitcoinNet(0xBD6B0CBF), 70208)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return peer
+}
+
+func TestPingerStatusTransitions(t *testing.T) {
+	peer := newTestPeer(t)
+	peer.Start()
+	defer peer.Close()
+
+	clock := NewFakeClock(time.Unix(1555555555, 0))
+	pinger := startPinger(t, peer, clock, 10)
+	defer pinger.stop(t)
+
+	waitFor(t, "the handshake", func() bool { return pinger.GetStatus() == 1 })
+
+	//a dropped connection stops pings until the pinger has reconnected
+	peer.Disconnect()
+	waitFor(t, "the disconnect", func() bool { return pinger.GetStatus() == 0 })
+	advanceWhenWaiting(t, clock, time.Minute)
+	waitFor(t, "the reconnect", func() bool { return pinger.GetStatus() == 1 })
+
+	//a peer that's gone for good is given up on
+	peer.Close()
+	for pinger.GetStatus() == 1 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	for pinger.GetStatus() != -1 {
+		waitFor(t, "a redial", func() bool {
+			return clock.PendingTimers() > 0 || pinger.GetStatus() == -1
+		})
+		clock.Advance(time.Minute)
+	}
+	pinger.stop(t)
+}
+
+func TestPingerShutdown(t *testing.T) {
+	peer := newTestPeer(t)
+	peer.Start()
+	defer peer.Close()
+
+	pinger := startPinger(t, peer, nil, 10)
+	waitFor(t, "the handshake", func() bool { return pinger.GetStatus() == 1 })
+	waitFor(t, "the connection", func() bool { return peer.Connected() == 1 })
+
+	//closing the ping channel stops the dispatcher, which closes the
+	//socket and with it the reader and the writer
+	pinger.stop(t)
+	waitFor(t, "the connection to close", func() bool { return peer.Connected() == 0 })
+
+	if pinger.Send(&wire.MsgPing{}) {
+		t.Error("Send() succeeded after the shutdown")
+	}
+}
+
+func TestPingerRelaysBroadcastTemplate(t *testing.T) {
+	peer := newTestPeer(t)
+	profile, err := wire.LookupMessageProfile("dash-12.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer.Profile = profile
+	peer.Start()
+	defer peer.Close()
+
+	pinger := startPinger(t, peer, nil, 10)
+	defer pinger.stop(t)
+	waitFor(t, "the handshake", func() bool { return pinger.GetStatus() == 1 })
+
+	key, wif := testKey(t)
+	hashes := phantom.NewQueue(1)
+	hashes.Push(&chainhash.Hash{7})
+
+	var outpointHash chainhash.Hash
+	chainhash.Decode(&outpointHash, testOutpoint)
+	template := wire.MsgMNB{
+		Profile:                 profile,
+		Vin:                     *wire.NewTxIn(wire.NewOutPoint(&outpointHash, 1), nil, nil),
+		PubKeyCollateralAddress: key.PubKey().SerializeCompressed(),
+		PubKeyMasternode:        key.PubKey().SerializeCompressed(),
+		Sig:                     []byte{1},
+		SigTime:                 1555555000,
+		ProtocolVersion:         70208,
+	}
+	copy(template.Addr.IpAddress[:], net.ParseIP("45.50.22.125").To16())
+	template.Addr.Port = 9999
+
+	ping := testPing(wif, hashes, time.Unix(1555555555, 0))
+	ping.Profile = profile
+	ping.BroadcastTemplate = &template
+	pinger.pings <- ping
+
+	received, err := peer.WaitForPing(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the broadcast", func() bool { return len(peer.Broadcasts()) == 1 })
+
+	mnb := peer.Broadcasts()[0]
+	if mnb.SigTime != template.SigTime || mnb.Vin.PreviousOutPoint != template.Vin.PreviousOutPoint {
+		t.Errorf("relayed broadcast %+v, want the template", mnb)
+	}
+	if mnb.LastPing.SigTime != received.Ping.SigTime || mnb.LastPing.BlockHash != received.Ping.BlockHash {
+		t.Errorf("broadcast carries ping %+v, want the relayed %+v", mnb.LastPing, received.Ping)
+	}
+	if err := VerifyPing(&mnb.LastPing, phantom.SignatureHash, testMagicMessage, key.PubKey()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPingerSlowConsumer(t *testing.T) {
+	peer := newTestPeer(t)
+	peer.Start()
+	defer peer.Close()
+
+	//nobody reads the hashes, the one slot fills up
+	pinger := startPinger(t, peer, nil, 1)
+	defer pinger.stop(t)
+	waitFor(t, "the handshake", func() bool { return pinger.GetStatus() == 1 })
+
+	for i := byte(1); i <= 3; i++ {
+		peer.AnnounceBlock(chainhash.Hash{i})
+	}
+	waitFor(t, "the dropped hashes", func() bool { return pinger.Dropped()["block"] == 2 })
+
+	//pings still go out
+	_, wif := testKey(t)
+	hashes := phantom.NewQueue(1)
+	hashes.Push(&chainhash.Hash{7})
+	pinger.pings <- testPing(wif, hashes, time.Unix(1555555555, 0))
+
+	if _, err := peer.WaitForPing(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if hash := <-pinger.hashes; hash != (chainhash.Hash{1}) {
+		t.Errorf("kept block %s, want the first announced", hash)
+	}
+}
+
+// powHeader returns a header that meets its own target when valid is set
+// and misses it otherwise.
+func powHeader(t *testing.T, hasher phantom.PowHasher, valid bool) wire.BlockHeader {
+	header := wire.BlockHeader{Version: 1, MerkleRoot: chainhash.Hash{1}, Timestamp: time.Unix(1555555555, 0), Bits: 0x207fffff}
+	for ; header.Nonce < 1<<16; header.Nonce++ {
+		if _, _, err := phantom.CheckProofOfWork(&header, hasher, nil); (err == nil) == valid {
+			return header
+		}
+	}
+	t.Fatalf("unable to find a header with valid=%t", valid)
+	return header
+}
+
+func TestPingerDropsPeerServingBadProofOfWork(t *testing.T) {
+	hasher, err := phantom.LookupPowHasher("sha256d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := powHeader(t, hasher, true)
+	bad := powHeader(t, hasher, false)
+
+	peer := newTestPeer(t)
+	peer.BlockHeaders = []wire.BlockHeader{good, bad}
+	peer.Start()
+	defer peer.Close()
+
+	pinger := newTestPinger(peer, nil, 10)
+	pinger.Headers = &phantom.HeaderTracker{Hasher: hasher}
+	pinger.start()
+	defer pinger.stop(t)
+	waitFor(t, "the handshake", func() bool { return pinger.GetStatus() == 1 })
+
+	peer.AnnounceBlock(good.BlockHash())
+	select {
+	case hash := <-pinger.hashes:
+		if hash != good.BlockHash() {
+			t.Errorf("received block %s, want %s", hash, good.BlockHash())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the valid header's block never arrived")
+	}
+
+	peer.AnnounceBlock(bad.BlockHash())
+	waitFor(t, "the disconnect", func() bool { return peer.Connected() == 0 })
+	if status := pinger.GetStatus(); status != -1 {
+		t.Errorf("status %d after the bad header, want -1", status)
+	}
+	select {
+	case hash := <-pinger.hashes:
+		t.Errorf("block %s with invalid proof of work was passed on", hash)
+	default:
+	}
+
+	//the pinger doesn't come back to the peer
+	pinger.stop(t)
+	if connected := peer.Connected(); connected != 0 {
+		t.Errorf("%d connection(s) after the bad header, want none", connected)
+	}
+}
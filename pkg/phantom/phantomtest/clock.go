@@ -0,0 +1,166 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantomtest
+
+import (
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"sync"
+	"time"
+)
+
+// FakeClock is a phantom.Clock that only moves when Advance or Set is
+// called, firing any timers that have come due.
+type FakeClock struct {
+	now    time.Time
+	timers []*fakeTimer //armed timers only
+	mux    sync.Mutex
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (clock *FakeClock) Now() time.Time {
+	clock.mux.Lock()
+	defer clock.mux.Unlock()
+
+	return clock.now
+}
+
+func (clock *FakeClock) NewTimer(d time.Duration) phantom.Timer {
+	clock.mux.Lock()
+	defer clock.mux.Unlock()
+
+	timer := &fakeTimer{
+		clock:    clock,
+		deadline: clock.now.Add(d),
+		active:   true,
+		c:        make(chan time.Time, 1),
+	}
+	clock.timers = append(clock.timers, timer)
+	clock.fireLocked()
+
+	return timer
+}
+
+// Advance moves the clock forward by d.
+func (clock *FakeClock) Advance(d time.Duration) {
+	clock.mux.Lock()
+	defer clock.mux.Unlock()
+
+	clock.now = clock.now.Add(d)
+	clock.fireLocked()
+}
+
+// Set moves the clock to t, which must not be in its past.
+func (clock *FakeClock) Set(t time.Time) {
+	clock.mux.Lock()
+	defer clock.mux.Unlock()
+
+	clock.now = t
+	clock.fireLocked()
+}
+
+// PendingTimers returns how many timers are armed, which lets a test wait
+// until a goroutine has gone back to sleep before advancing again.
+func (clock *FakeClock) PendingTimers() int {
+	clock.mux.Lock()
+	defer clock.mux.Unlock()
+
+	return len(clock.timers)
+}
+
+// fireLocked fires the timers that have come due and drops them.
+func (clock *FakeClock) fireLocked() {
+	armed := clock.timers[:0]
+	for _, timer := range clock.timers {
+		if timer.deadline.After(clock.now) {
+			armed = append(armed, timer)
+			continue
+		}
+
+		timer.active = false
+		select {
+		case timer.c <- clock.now:
+		default:
+		}
+	}
+	for i := len(armed); i < len(clock.timers); i++ {
+		clock.timers[i] = nil
+	}
+	clock.timers = armed
+}
+
+func (clock *FakeClock) removeLocked(timer *fakeTimer) {
+	for i, armed := range clock.timers {
+		if armed == timer {
+			copy(clock.timers[i:], clock.timers[i+1:])
+			clock.timers[len(clock.timers)-1] = nil
+			clock.timers = clock.timers[:len(clock.timers)-1]
+			return
+		}
+	}
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	active   bool
+	c        chan time.Time
+}
+
+func (timer *fakeTimer) C() <-chan time.Time {
+	return timer.c
+}
+
+func (timer *fakeTimer) Stop() bool {
+	timer.clock.mux.Lock()
+	defer timer.clock.mux.Unlock()
+
+	wasActive := timer.active
+	timer.active = false
+	timer.clock.removeLocked(timer)
+	return wasActive
+}
+
+func (timer *fakeTimer) Reset(d time.Duration) bool {
+	timer.clock.mux.Lock()
+	defer timer.clock.mux.Unlock()
+
+	wasActive := timer.active
+	timer.deadline = timer.clock.now.Add(d)
+	if !wasActive {
+		timer.active = true
+		timer.clock.timers = append(timer.clock.timers, timer)
+	}
+	timer.clock.fireLocked()
+	return wasActive
+}
@@ -0,0 +1,95 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantomtest
+
+import (
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"testing"
+	"time"
+)
+
+func TestFakeClockTimers(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1555555555, 0))
+
+	early := clock.NewTimer(time.Minute)
+	late := clock.NewTimer(time.Hour)
+	stopped := clock.NewTimer(time.Minute)
+	if got := clock.PendingTimers(); got != 3 {
+		t.Fatalf("PendingTimers() = %d, want 3", got)
+	}
+
+	if !stopped.Stop() {
+		t.Error("Stop() on an armed timer returned false")
+	}
+	if got := clock.PendingTimers(); got != 2 {
+		t.Errorf("PendingTimers() after Stop = %d, want 2", got)
+	}
+
+	clock.Advance(time.Minute)
+	select {
+	case <-early.C():
+	default:
+		t.Error("due timer didn't fire")
+	}
+	select {
+	case <-stopped.C():
+		t.Error("stopped timer fired")
+	default:
+	}
+	if got := clock.PendingTimers(); got != 1 {
+		t.Errorf("PendingTimers() after firing = %d, want 1", got)
+	}
+	if early.Stop() {
+		t.Error("Stop() on a fired timer returned true")
+	}
+
+	//a fired timer is armed again by Reset
+	if early.Reset(time.Minute) {
+		t.Error("Reset() on a fired timer returned true")
+	}
+	if !late.Reset(2 * time.Minute) {
+		t.Error("Reset() on an armed timer returned false")
+	}
+	if got := clock.PendingTimers(); got != 2 {
+		t.Errorf("PendingTimers() after Reset = %d, want 2", got)
+	}
+
+	clock.Set(clock.Now().Add(2 * time.Minute))
+	for name, timer := range map[string]phantom.Timer{"reset": early, "rescheduled": late} {
+		select {
+		case <-timer.C():
+		default:
+			t.Errorf("%s timer didn't fire", name)
+		}
+	}
+	if got := clock.PendingTimers(); got != 0 {
+		t.Errorf("PendingTimers() = %d, want 0", got)
+	}
+}
@@ -0,0 +1,461 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+// Package phantomtest provides a simulated masternode-daemon peer that
+// listens on localhost, so PingerConnection and the ping generator can be
+// exercised end to end without a real network.
+package phantomtest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"net"
+	"sync"
+	"time"
+)
+
+// ReceivedPing is an mnp the fake peer was sent, with the time it arrived.
+type ReceivedPing struct {
+	Ping       wire.MsgMNP
+	ReceivedAt time.Time
+}
+
+// FakePeer speaks just enough of the masternode-daemon protocol to hand
+// out block hashes and collect pings. Set the exported fields between
+// NewFakePeer and Start, they aren't guarded once connections are served.
+type FakePeer struct {
+	Magic           wire.BitcoinNet
+	ProtocolVersion uint32
+	UserAgent       string
+	BestHeight      int32
+
+	// Profile selects the masternode message layout, nil is the legacy one.
+	Profile *wire.MessageProfile
+
+	// Addresses is the list returned in response to getaddr.
+	Addresses []*wire.NetAddress
+
+	// GovernanceObjects are announced in response to govsync.
+	GovernanceObjects []wire.MsgGovObj
+
+	// Sporks are sent in response to getsporks.
+	Sporks []wire.MsgSpork
+
+	// Transactions are served in response to getdata.
+	Transactions []wire.MsgTx
+
+	// BlockHeaders are served in response to getheaders for their hash.
+	BlockHeaders []wire.BlockHeader
+
+	// MinProtocolVersion, when set, rejects older peers the way daemons do.
+	MinProtocolVersion uint32
+
+	// RejectPings, when set, is sent back for every ping received. It names
+	// the ping's hash unless it carries one.
+	RejectPings *wire.MsgReject
+
+	listener   net.Listener
+	conns      map[net.Conn]*sync.Mutex //each with its write lock
+	pings      []ReceivedPing
+	broadcasts []wire.MsgMNB
+	votes      []wire.MsgGovObjVote
+	pingEvents chan ReceivedPing
+	mux        sync.Mutex
+	wg         sync.WaitGroup
+}
+
+// NewFakePeer returns a peer listening on an ephemeral localhost port.
+// Connections are accepted once Start is called.
+func NewFakePeer(magic wire.BitcoinNet, protocolVersion uint32) (*FakePeer, error) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	peer := &FakePeer{
+		Magic:           magic,
+		ProtocolVersion: protocolVersion,
+		UserAgent:       "/phantomtest:0.0.1/",
+		listener:        listener,
+		conns:           make(map[net.Conn]*sync.Mutex),
+		pingEvents:      make(chan ReceivedPing, 100),
+	}
+
+	return peer, nil
+}
+
+// Start serves connections with the peer's configuration.
+func (peer *FakePeer) Start() {
+	peer.wg.Add(1)
+	go peer.accept()
+}
+
+func (peer *FakePeer) IpAddress() string {
+	return peer.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+func (peer *FakePeer) Port() uint16 {
+	return uint16(peer.listener.Addr().(*net.TCPAddr).Port)
+}
+
+// Close stops listening and drops every connection.
+func (peer *FakePeer) Close() {
+	peer.listener.Close()
+
+	peer.mux.Lock()
+	for conn := range peer.conns {
+		conn.Close()
+	}
+	peer.mux.Unlock()
+
+	peer.wg.Wait()
+}
+
+// Disconnect drops every connection but keeps listening, so clients can
+// reconnect.
+func (peer *FakePeer) Disconnect() {
+	peer.mux.Lock()
+	defer peer.mux.Unlock()
+
+	for conn := range peer.conns {
+		conn.Close()
+	}
+}
+
+// AnnounceBlock sends a block inv to every connected client.
+func (peer *FakePeer) AnnounceBlock(hash chainhash.Hash) {
+	inv := wire.NewMsgInv()
+	inv.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, &hash))
+	peer.broadcast(inv)
+}
+
+// Pings returns every ping received so far, in arrival order.
+func (peer *FakePeer) Pings() []ReceivedPing {
+	peer.mux.Lock()
+	defer peer.mux.Unlock()
+
+	return append([]ReceivedPing(nil), peer.pings...)
+}
+
+// Broadcasts returns every mnb received so far, in arrival order.
+func (peer *FakePeer) Broadcasts() []wire.MsgMNB {
+	peer.mux.Lock()
+	defer peer.mux.Unlock()
+
+	return append([]wire.MsgMNB(nil), peer.broadcasts...)
+}
+
+// Votes returns the governance votes received so far.
+func (peer *FakePeer) Votes() []wire.MsgGovObjVote {
+	peer.mux.Lock()
+	defer peer.mux.Unlock()
+
+	return append([]wire.MsgGovObjVote(nil), peer.votes...)
+}
+
+// WaitForPing blocks until the next ping arrives or timeout passes.
+func (peer *FakePeer) WaitForPing(timeout time.Duration) (ReceivedPing, error) {
+	select {
+	case ping := <-peer.pingEvents:
+		return ping, nil
+	case <-time.After(timeout):
+		return ReceivedPing{}, errors.New("timed out waiting for a ping")
+	}
+}
+
+// Connected returns the number of clients currently connected.
+func (peer *FakePeer) Connected() int {
+	peer.mux.Lock()
+	defer peer.mux.Unlock()
+
+	return len(peer.conns)
+}
+
+// NewPingerConnection returns a PingerConnection pointed at the fake peer.
+// The caller owns the channels and must Add(1) to waitGroup before Start.
+func (peer *FakePeer) NewPingerConnection(pingChannel chan phantom.MasternodePing,
+	hashChannel chan chainhash.Hash, waitGroup *sync.WaitGroup) *phantom.PingerConnection {
+
+	return &phantom.PingerConnection{
+		MagicBytes:     uint32(peer.Magic),
+		IpAddress:      peer.IpAddress(),
+		Port:           peer.Port(),
+		ProtocolNumber: peer.ProtocolVersion,
+		Profile:        peer.Profile,
+		PingChannel:    pingChannel,
+		AddrChannel:    make(chan wire.NetAddress, 1500),
+		HashChannel:    hashChannel,
+		WaitGroup:      waitGroup,
+	}
+}
+
+// VerifyPing checks that ping carries a signature from pubKey made with
+// scheme. The magic message is only used by the legacy scheme.
+func VerifyPing(ping *wire.MsgMNP, scheme phantom.SignatureScheme, magicMessage string, pubKey *btcec.PublicKey) error {
+	var hash []byte
+	switch scheme {
+	case phantom.SignatureHash:
+		signatureHash := ping.SignatureHash()
+		hash = signatureHash[:]
+	default:
+		hash = phantom.GenerateMNPSignatureHash(ping.Profile, magicMessage,
+			ping.Vin.PreviousOutPoint.Hash.String(),
+			ping.Vin.PreviousOutPoint.Index,
+			ping.Vin.SignatureScript,
+			ping.BlockHash.String(),
+			ping.SigTime)
+	}
+
+	recovered, _, err := btcec.RecoverCompact(btcec.S256(), ping.VchSig, hash)
+	if err != nil {
+		return err
+	}
+
+	if !recovered.IsEqual(pubKey) {
+		return errors.New("ping was signed by a different key")
+	}
+	return nil
+}
+
+// VerifyVote checks that vote carries a signature from pubKey made with
+// scheme. The magic message is only used by the legacy scheme.
+func VerifyVote(vote *wire.MsgGovObjVote, scheme phantom.SignatureScheme, magicMessage string,
+	pubKey *btcec.PublicKey) error {
+
+	var hash []byte
+	switch scheme {
+	case phantom.SignatureHash:
+		signatureHash := vote.SignatureHash()
+		hash = signatureHash[:]
+	default:
+		var buf bytes.Buffer
+		wire.WriteVarString(&buf, 0, magicMessage)
+		wire.WriteVarString(&buf, 0, vote.SignatureMessage())
+		hash = chainhash.DoubleHashB(buf.Bytes())
+	}
+
+	recovered, _, err := btcec.RecoverCompact(btcec.S256(), vote.Sig, hash)
+	if err != nil {
+		return err
+	}
+
+	if !recovered.IsEqual(pubKey) {
+		return errors.New("vote was signed by a different key")
+	}
+	return nil
+}
+
+func (peer *FakePeer) accept() {
+	defer peer.wg.Done()
+
+	for {
+		conn, err := peer.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		peer.mux.Lock()
+		peer.conns[conn] = &sync.Mutex{}
+		peer.mux.Unlock()
+
+		peer.wg.Add(1)
+		go peer.serve(conn)
+	}
+}
+
+// broadcast writes msg to every connection. The writes happen outside of
+// the peer's lock, so a client that doesn't read only stalls its own.
+func (peer *FakePeer) broadcast(msg wire.Message) {
+	peer.mux.Lock()
+	conns := make(map[net.Conn]*sync.Mutex, len(peer.conns))
+	for conn, writeMux := range peer.conns {
+		conns[conn] = writeMux
+	}
+	peer.mux.Unlock()
+
+	for conn, writeMux := range conns {
+		peer.write(conn, writeMux, msg)
+	}
+}
+
+func (peer *FakePeer) send(conn net.Conn, msg wire.Message) {
+	peer.mux.Lock()
+	writeMux, ok := peer.conns[conn]
+	peer.mux.Unlock()
+
+	if ok {
+		peer.write(conn, writeMux, msg)
+	}
+}
+
+func (peer *FakePeer) write(conn net.Conn, writeMux *sync.Mutex, msg wire.Message) {
+	//serialize with broadcasts so messages never interleave on the socket
+	writeMux.Lock()
+	defer writeMux.Unlock()
+
+	wire.WriteMessageN(conn, msg, peer.ProtocolVersion, peer.Magic)
+}
+
+func (peer *FakePeer) serve(conn net.Conn) {
+	defer peer.wg.Done()
+	defer func() {
+		peer.mux.Lock()
+		delete(peer.conns, conn)
+		peer.mux.Unlock()
+		conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+
+	for {
+		_, msg, _, err := wire.ReadMessageWithProfileN(reader, peer.ProtocolVersion, peer.Magic, peer.Profile)
+		if err != nil {
+			if _, ok := err.(*wire.MessageError); ok {
+				continue
+			}
+			return
+		}
+
+		switch msg := msg.(type) {
+		case *wire.MsgVersion:
+			if uint32(msg.ProtocolVersion) < peer.MinProtocolVersion {
+				peer.send(conn, wire.NewMsgReject(wire.CmdVersion, wire.RejectObsolete,
+					fmt.Sprintf("Version must be %d or greater", peer.MinProtocolVersion)))
+				return
+			}
+
+			me := wire.NewNetAddressIPPort(net.ParseIP(peer.IpAddress()), peer.Port(), 0)
+			you := wire.NewNetAddressIPPort(net.ParseIP("127.0.0.1"), 0, 0)
+			version := wire.NewMsgVersion(me, you, 0xC0FFEE, peer.BestHeight)
+			version.ProtocolVersion = int32(peer.ProtocolVersion)
+			version.UserAgent = peer.UserAgent
+			peer.send(conn, version)
+			peer.send(conn, &wire.MsgVerAck{})
+
+		case *wire.MsgGetAddr:
+			addr := wire.NewMsgAddr()
+			addr.AddAddresses(peer.Addresses...)
+			peer.send(conn, addr)
+
+		case *wire.MsgPing:
+			peer.send(conn, &wire.MsgPong{Nonce: msg.Nonce})
+
+		case *wire.MsgGetSporks:
+			for i := range peer.Sporks {
+				peer.send(conn, &peer.Sporks[i])
+			}
+
+		case *wire.MsgInv:
+			getdata := wire.NewMsgGetData()
+			for _, inv := range msg.InvList {
+				if inv.Type == wire.InvTypeMasternodePing || inv.Type == wire.InvTypeMasternodeAnnounce ||
+					inv.Type == wire.InvTypeGovernanceVote {
+					getdata.AddInvVect(inv)
+				}
+			}
+			if len(getdata.InvList) > 0 {
+				peer.send(conn, getdata)
+			}
+
+		case *wire.MsgMNP:
+			received := ReceivedPing{Ping: *msg, ReceivedAt: time.Now()}
+
+			peer.mux.Lock()
+			peer.pings = append(peer.pings, received)
+			peer.mux.Unlock()
+
+			select {
+			case peer.pingEvents <- received:
+			default: //nobody is waiting, Pings() still has it
+			}
+
+			if peer.RejectPings != nil {
+				reject := *peer.RejectPings
+				if reject.Hash == (chainhash.Hash{}) {
+					var buf bytes.Buffer
+					msg.Serialize(&buf)
+					reject.Hash = chainhash.DoubleHashH(buf.Bytes())
+				}
+				peer.send(conn, &reject)
+			}
+
+		case *wire.MsgMNB:
+			peer.mux.Lock()
+			peer.broadcasts = append(peer.broadcasts, *msg)
+			peer.mux.Unlock()
+
+		case *wire.MsgGovSync:
+			inv := wire.NewMsgInv()
+			for _, obj := range peer.GovernanceObjects {
+				hash := obj.GetHash()
+				if msg.Hash == (chainhash.Hash{}) || msg.Hash == hash {
+					inv.AddInvVect(wire.NewInvVect(wire.InvTypeGovernanceObject, &hash))
+				}
+			}
+			if len(inv.InvList) > 0 {
+				peer.send(conn, inv)
+			}
+
+		case *wire.MsgGetHeaders:
+			headers := wire.NewMsgHeaders()
+			for i := range peer.BlockHeaders {
+				if peer.BlockHeaders[i].BlockHash() == msg.HashStop {
+					headers.AddBlockHeader(&peer.BlockHeaders[i])
+				}
+			}
+			peer.send(conn, headers)
+
+		case *wire.MsgGetData:
+			for _, inv := range msg.InvList {
+				for i := range peer.GovernanceObjects {
+					if peer.GovernanceObjects[i].GetHash() == inv.Hash {
+						peer.send(conn, &peer.GovernanceObjects[i])
+					}
+				}
+				for i := range peer.Transactions {
+					if inv.Type == wire.InvTypeTx && peer.Transactions[i].TxHash() == inv.Hash {
+						peer.send(conn, &peer.Transactions[i])
+					}
+				}
+			}
+
+		case *wire.MsgGovObjVote:
+			peer.mux.Lock()
+			peer.votes = append(peer.votes, *msg)
+			peer.mux.Unlock()
+		}
+	}
+}
@@ -0,0 +1,357 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantomtest
+
+import (
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testMagicMessage = "DarkCoin Signed Message:\n"
+
+// TestSignedPingsReachPeer relays pings signed with either scheme through a
+// PingerConnection and checks they arrive at the fake peer intact.
+func TestSignedPingsReachPeer(t *testing.T) {
+	profile, err := wire.LookupMessageProfile("dash-12.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peer, err := NewFakePeer(wire.BitcoinNet(0xBD6B0CBF), 70208)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer.Profile = profile
+	peer.Start()
+	defer peer.Close()
+
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wif, err := btcutil.NewWIF(key, &chaincfg.MainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pingChannel := make(chan phantom.MasternodePing)
+	hashChannel := make(chan chainhash.Hash, 100)
+	var waitGroup sync.WaitGroup
+
+	pinger := peer.NewPingerConnection(pingChannel, hashChannel, &waitGroup)
+	pinger.SentinelVersion = 0x010001
+
+	waitGroup.Add(1)
+	go pinger.Start("/phantomtest:0.0.1/")
+	defer func() {
+		close(pingChannel)
+		waitGroup.Wait()
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for pinger.GetStatus() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("the pinger didn't connect to the fake peer")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	hashes := phantom.NewQueue(1)
+	hashes.Push(&chainhash.Hash{7})
+
+	for _, scheme := range []phantom.SignatureScheme{phantom.SignatureLegacy, phantom.SignatureHash} {
+		pingChannel <- phantom.MasternodePing{
+			Name:            "mn1",
+			OutpointHash:    "2bcd3c84c84f87eaa86e4e56834c92927a07f9e18718810b92e0d0324456a67c",
+			OutpointIndex:   1,
+			PrivateKey:      wif.String(),
+			PingTime:        time.Unix(1555555555, 0),
+			MagicMessage:    testMagicMessage,
+			HashQueue:       hashes,
+			SigTimeOffset:   3 * time.Second,
+			Profile:         profile,
+			SignatureScheme: scheme,
+		}
+
+		received, err := peer.WaitForPing(5 * time.Second)
+		if err != nil {
+			t.Fatalf("%s: %s", scheme, err)
+		}
+
+		ping := received.Ping
+		if ping.BlockHash != (chainhash.Hash{7}) || ping.SigTime != 1555555558 || ping.SentinelVersion != 0x010001 {
+			t.Errorf("%s: received %+v", scheme, ping)
+		}
+		if err := VerifyPing(&ping, scheme, testMagicMessage, key.PubKey()); err != nil {
+			t.Errorf("%s: %s", scheme, err)
+		}
+	}
+}
+
+// TestScheduledPingsReachPeer drives a PingScheduler on a fake clock through
+// a PingerConnection, the pings have to arrive signed for their slots.
+func TestScheduledPingsReachPeer(t *testing.T) {
+	peer := newTestPeer(t)
+	peer.Start()
+	defer peer.Close()
+
+	pinger := startPinger(t, peer, nil, 10)
+	defer pinger.stop(t)
+	waitFor(t, "the handshake", func() bool { return pinger.GetStatus() == 1 })
+
+	dir, err := ioutil.TempDir("", "phantomtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, wif := testKey(t)
+	epoch := time.Unix(1555555555, 0)
+	conf := filepath.Join(dir, "masternode.txt")
+	line := fmt.Sprintf("mn1 %s:%d %s %s 1 %d\n", peer.IpAddress(), peer.Port(), wif, testOutpoint, epoch.Unix())
+	if err := ioutil.WriteFile(conf, []byte(line), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := phantom.NewQueue(1)
+	hashes.Push(&chainhash.Hash{7})
+
+	clock := NewFakeClock(epoch.Add(-time.Minute))
+	scheduler := &phantom.PingScheduler{
+		MasternodeConf:  conf,
+		HashQueue:       hashes,
+		MagicMessage:    testMagicMessage,
+		PingInterval:    10 * time.Minute,
+		SigTimeOffset:   -3 * time.Second,
+		SignatureScheme: phantom.SignatureHash,
+		Clock:           clock,
+	}
+	if err := scheduler.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	//the scheduler's channel isn't closed, pings are handed on to the pinger
+	scheduled := make(chan phantom.MasternodePing)
+	go scheduler.Run(scheduled)
+	go func() {
+		for ping := range scheduled {
+			pinger.pings <- ping
+		}
+	}()
+
+	//Run waits on its slot and its reload timer
+	armed := func() bool { return clock.PendingTimers() == 2 }
+
+	waitFor(t, "the scheduler", armed)
+	clock.Advance(time.Minute - time.Second)
+	if received, err := peer.WaitForPing(100 * time.Millisecond); err == nil {
+		t.Fatalf("ping %+v arrived before its slot", received.Ping)
+	}
+
+	for slot := epoch; slot.Before(epoch.Add(30 * time.Minute)); slot = slot.Add(scheduler.PingInterval) {
+		waitFor(t, "the scheduler", armed)
+		clock.Set(slot)
+
+		received, err := peer.WaitForPing(5 * time.Second)
+		if err != nil {
+			t.Fatalf("slot %s: %s", slot.UTC(), err)
+		}
+		if want := uint64(slot.Add(scheduler.SigTimeOffset).Unix()); received.Ping.SigTime != want {
+			t.Errorf("slot %s: ping signed for %d, want %d", slot.UTC(), received.Ping.SigTime, want)
+		}
+		if err := VerifyPing(&received.Ping, phantom.SignatureHash, testMagicMessage, key.PubKey()); err != nil {
+			t.Errorf("slot %s: %s", slot.UTC(), err)
+		}
+	}
+}
+
+func TestFakePeerAnnounceBlock(t *testing.T) {
+	peer := newTestPeer(t)
+	peer.Start()
+	defer peer.Close()
+
+	var pingers []*testPinger
+	for i := 0; i < 2; i++ {
+		pinger := startPinger(t, peer, nil, 10)
+		defer pinger.stop(t)
+		pingers = append(pingers, pinger)
+	}
+	waitFor(t, "both clients", func() bool { return peer.Connected() == 2 })
+	for _, pinger := range pingers {
+		waitFor(t, "the handshake", func() bool { return pinger.GetStatus() == 1 })
+	}
+
+	peer.AnnounceBlock(chainhash.Hash{9})
+
+	for i, pinger := range pingers {
+		select {
+		case hash := <-pinger.hashes:
+			if hash != (chainhash.Hash{9}) {
+				t.Errorf("client %d received block %s", i, hash)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("client %d never received the block", i)
+		}
+	}
+}
+
+func TestFakePeerServesAddresses(t *testing.T) {
+	peer := newTestPeer(t)
+	peer.Addresses = []*wire.NetAddress{
+		wire.NewNetAddressIPPort(net.ParseIP("45.50.22.125"), 9999, 0),
+		wire.NewNetAddressIPPort(net.ParseIP("45.50.22.126"), 9999, 0),
+	}
+	peer.Start()
+	defer peer.Close()
+
+	//the pinger asks for addresses once connected
+	pinger := startPinger(t, peer, nil, 10)
+	defer pinger.stop(t)
+
+	for _, want := range peer.Addresses {
+		select {
+		case addr := <-pinger.AddrChannel:
+			if !addr.IP.Equal(want.IP) || addr.Port != want.Port {
+				t.Errorf("received address %s:%d, want %s:%d", addr.IP, addr.Port, want.IP, want.Port)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the addresses never arrived")
+		}
+	}
+}
+
+// TestFakePeerStalledClient checks a client that stops reading only blocks
+// writes to itself.
+func TestFakePeerStalledClient(t *testing.T) {
+	peer := newTestPeer(t)
+	peer.Start()
+	defer peer.Close()
+
+	stalled, err := net.Dial("tcp", peer.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stalled.Close()
+	stalled.(*net.TCPConn).SetReadBuffer(1024)
+	waitFor(t, "the connection", func() bool { return peer.Connected() == 1 })
+
+	var announced int64
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			peer.AnnounceBlock(chainhash.Hash{byte(i), byte(i >> 8), byte(i >> 16)})
+			atomic.AddInt64(&announced, 1)
+		}
+	}()
+
+	waitFor(t, "the socket buffers to fill up", func() bool {
+		before := atomic.LoadInt64(&announced)
+		time.Sleep(50 * time.Millisecond)
+		return atomic.LoadInt64(&announced) == before
+	})
+
+	//another client is still served meanwhile
+	pinger := startPinger(t, peer, nil, 10)
+	defer pinger.stop(t)
+	waitFor(t, "the handshake", func() bool { return pinger.GetStatus() == 1 })
+	if connected := peer.Connected(); connected != 2 {
+		t.Errorf("%d clients connected, want 2", connected)
+	}
+
+	close(stop)
+	stalled.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the announcements still block after the client went away")
+	}
+}
+
+// TestFakePeerServesTransactions looks collateral up with getdata, the only
+// source when there's no explorer.
+func TestFakePeerServesTransactions(t *testing.T) {
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{3}, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1000*btcutil.SatoshiPerBitcoin, []byte{0x51}))
+
+	peer := newTestPeer(t)
+	peer.Transactions = []wire.MsgTx{*tx}
+	peer.Start()
+	defer peer.Close()
+
+	pinger := newTestPinger(peer, nil, 10)
+	pinger.TxChannel = make(chan wire.MsgTx, 10)
+	pinger.start()
+	defer pinger.stop(t)
+	waitFor(t, "the handshake", func() bool { return pinger.GetStatus() == 1 })
+
+	peers := &phantom.PeerTransactions{
+		Pingers: func() []*phantom.PingerConnection { return []*phantom.PingerConnection{pinger.PingerConnection} },
+		Timeout: 200 * time.Millisecond,
+	}
+	go func() {
+		for tx := range pinger.TxChannel {
+			peers.Deliver(tx)
+		}
+	}()
+
+	checker := &phantom.CollateralChecker{Peers: peers, Amount: 1000 * btcutil.SatoshiPerBitcoin}
+	check := checker.Check(phantom.MasternodePing{Name: "mn1", OutpointHash: tx.TxHash().String()})
+	if check.Source != "peers" || check.Unverified != "" || len(check.Problems) != 0 {
+		t.Errorf("collateral served by the peer: %+v", check)
+	}
+
+	check = checker.Check(phantom.MasternodePing{Name: "mn1", OutpointHash: chainhash.Hash{4}.String()})
+	if !strings.Contains(check.Unverified, "none of the 1 peers asked served it") {
+		t.Errorf("collateral the peer doesn't know: %+v", check)
+	}
+}
@@ -0,0 +1,112 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantomtest
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// WebhookServer is a local HTTP stand-in for a notification webhook that
+// records what's posted to it.
+type WebhookServer struct {
+	URL string
+
+	// Status, when set, is answered instead of 200 OK.
+	Status int
+
+	server        *httptest.Server
+	notifications []phantom.Notification
+	events        chan phantom.Notification
+	mux           sync.Mutex
+}
+
+// NewWebhookServer starts a webhook listening on an ephemeral localhost
+// port.
+func NewWebhookServer() *WebhookServer {
+	webhook := &WebhookServer{events: make(chan phantom.Notification, 100)}
+	webhook.server = httptest.NewServer(http.HandlerFunc(webhook.serve))
+	webhook.URL = webhook.server.URL
+	return webhook
+}
+
+// Hook returns a webhook hook pointed at the stand-in.
+func (webhook *WebhookServer) Hook() *phantom.WebhookHook {
+	return &phantom.WebhookHook{URL: webhook.URL}
+}
+
+func (webhook *WebhookServer) Close() {
+	webhook.server.Close()
+}
+
+// Notifications returns everything posted so far.
+func (webhook *WebhookServer) Notifications() []phantom.Notification {
+	webhook.mux.Lock()
+	defer webhook.mux.Unlock()
+
+	return append([]phantom.Notification(nil), webhook.notifications...)
+}
+
+func (webhook *WebhookServer) WaitForNotification(timeout time.Duration) (phantom.Notification, error) {
+	select {
+	case notification := <-webhook.events:
+		return notification, nil
+	case <-time.After(timeout):
+		return phantom.Notification{}, errors.New("timed out waiting for a notification")
+	}
+}
+
+func (webhook *WebhookServer) serve(w http.ResponseWriter, r *http.Request) {
+	var notification phantom.Notification
+	err := json.NewDecoder(r.Body).Decode(&notification)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	webhook.mux.Lock()
+	webhook.notifications = append(webhook.notifications, notification)
+	status := webhook.Status
+	webhook.mux.Unlock()
+
+	select {
+	case webhook.events <- notification:
+	default: //nobody is waiting, Notifications() still has it
+	}
+
+	if status != 0 {
+		w.WriteHeader(status)
+	}
+}
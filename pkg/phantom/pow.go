@@ -0,0 +1,269 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/bitbandi/go-x11"
+	"github.com/bitbandi/go-x11/blake"
+	"github.com/bitbandi/go-x11/bmw"
+	"github.com/bitbandi/go-x11/groest"
+	"github.com/bitbandi/go-x11/hash"
+	"github.com/bitbandi/go-x11/jhash"
+	"github.com/bitbandi/go-x11/keccak"
+	"github.com/bitbandi/go-x11/skein"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"golang.org/x/crypto/scrypt"
+	"math/big"
+	"sort"
+)
+
+// PowHasher hashes serialized 80 byte block headers the way a coin does.
+type PowHasher interface {
+	// BlockHash identifies the block, it's the hash announced in invs.
+	BlockHash(header []byte) chainhash.Hash
+
+	// PowHash is the hash compared against the header's target.
+	PowHash(header []byte) chainhash.Hash
+}
+
+// PowHashers are the supported proof of work algorithms by name.
+var PowHashers = map[string]PowHasher{
+	"sha256d": sha256dHasher{},
+	"scrypt":  scryptHasher{},
+	"keccak":  keccakHasher{},
+	"x11":     x11Hasher{},
+	"quark":   quarkHasher{},
+}
+
+// unsupportedPowAlgorithms are common masternode coin algorithms that have
+// no pure Go implementation here yet.
+var unsupportedPowAlgorithms = map[string]bool{
+	"neoscrypt": true,
+	"lyra2z":    true,
+	"x16r":      true,
+}
+
+// LookupPowHasher returns the named algorithm. An empty name returns nil,
+// block headers aren't checked then.
+func LookupPowHasher(name string) (PowHasher, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	hasher, ok := PowHashers[name]
+	if ok {
+		return hasher, nil
+	}
+
+	names := make([]string, 0, len(PowHashers))
+	for known := range PowHashers {
+		names = append(names, known)
+	}
+	sort.Strings(names)
+
+	if unsupportedPowAlgorithms[name] {
+		return nil, fmt.Errorf("the %s proof of work algorithm isn't supported yet (supported: %v), "+
+			"leave it unset to skip the block header checks", name, names)
+	}
+	return nil, fmt.Errorf("unknown proof of work algorithm %q (known: %v)", name, names)
+}
+
+// sha256dHasher is Bitcoin's double SHA256.
+type sha256dHasher struct{}
+
+func (sha256dHasher) BlockHash(header []byte) chainhash.Hash {
+	return chainhash.DoubleHashH(header)
+}
+
+func (sha256dHasher) PowHash(header []byte) chainhash.Hash {
+	return chainhash.DoubleHashH(header)
+}
+
+// scryptHasher is Litecoin's scrypt (N=1024, r=1, p=1), blocks are still
+// identified by their double SHA256.
+type scryptHasher struct{}
+
+func (scryptHasher) BlockHash(header []byte) chainhash.Hash {
+	return chainhash.DoubleHashH(header)
+}
+
+func (scryptHasher) PowHash(header []byte) chainhash.Hash {
+	var hash chainhash.Hash
+	key, err := scrypt.Key(header, header, 1024, 1, 1, chainhash.HashSize)
+	if err != nil {
+		//only fails on invalid parameters, which are constant
+		panic(err)
+	}
+	copy(hash[:], key)
+	return hash
+}
+
+// keccakHasher is a single Keccak-256, used for both hashes.
+type keccakHasher struct{}
+
+func (keccakHasher) BlockHash(header []byte) chainhash.Hash {
+	return chainhash.Hash(keccak256(header))
+}
+
+func (hasher keccakHasher) PowHash(header []byte) chainhash.Hash {
+	return hasher.BlockHash(header)
+}
+
+// x11Hasher is Dash's X11, the chain of eleven 512 bit hashes, used for both
+// hashes.
+type x11Hasher struct{}
+
+func (x11Hasher) BlockHash(header []byte) chainhash.Hash {
+	var hash chainhash.Hash
+	x11.New().Hash(header, hash[:])
+	return hash
+}
+
+func (hasher x11Hasher) PowHash(header []byte) chainhash.Hash {
+	return hasher.BlockHash(header)
+}
+
+// quarkHasher is Quark, nine rounds of 512 bit hashes where bit 3 of the
+// previous result picks the function of three of them. Used for both hashes.
+type quarkHasher struct{}
+
+func (quarkHasher) BlockHash(header []byte) chainhash.Hash {
+	digest := quarkRound(blake.New(), header)
+	digest = quarkRound(bmw.New(), digest)
+	if digest[0]&8 != 0 {
+		digest = quarkRound(groest.New(), digest)
+	} else {
+		digest = quarkRound(skein.New(), digest)
+	}
+	digest = quarkRound(groest.New(), digest)
+	digest = quarkRound(jhash.New(), digest)
+	if digest[0]&8 != 0 {
+		digest = quarkRound(blake.New(), digest)
+	} else {
+		digest = quarkRound(bmw.New(), digest)
+	}
+	digest = quarkRound(keccak.New(), digest)
+	digest = quarkRound(skein.New(), digest)
+	if digest[0]&8 != 0 {
+		digest = quarkRound(keccak.New(), digest)
+	} else {
+		digest = quarkRound(jhash.New(), digest)
+	}
+
+	var hash chainhash.Hash
+	copy(hash[:], digest)
+	return hash
+}
+
+func (hasher quarkHasher) PowHash(header []byte) chainhash.Hash {
+	return hasher.BlockHash(header)
+}
+
+func quarkRound(digest hash.Digest, data []byte) []byte {
+	out := make([]byte, 64)
+	digest.Write(data)
+	digest.Close(out, 0, 0)
+	return out
+}
+
+// serializeHeader returns the 80 bytes a header's hashes are computed over.
+func serializeHeader(header *wire.BlockHeader) []byte {
+	var buf bytes.Buffer
+	header.Serialize(&buf)
+	return buf.Bytes()
+}
+
+// ParsePowLimit decodes a coin's proof of work limit, the easiest target its
+// chain accepts, written as the 64 hex digits of chainparams' powLimit.
+func ParsePowLimit(limit string) (*big.Int, error) {
+	if len(limit) != 64 {
+		return nil, fmt.Errorf("%q must be 64 hex digits", limit)
+	}
+	if _, err := hex.DecodeString(limit); err != nil {
+		return nil, fmt.Errorf("%q must be 64 hex digits", limit)
+	}
+
+	target, _ := new(big.Int).SetString(limit, 16)
+	if target.Sign() == 0 {
+		return nil, fmt.Errorf("%q must be above zero", limit)
+	}
+	return target, nil
+}
+
+// CheckProofOfWork returns the header's block hash and target, and an error
+// when its target is above maxTarget or its proof of work hash doesn't meet
+// the target. A nil maxTarget doesn't bound the target, any bits a peer made
+// up would then do.
+func CheckProofOfWork(header *wire.BlockHeader, hasher PowHasher, maxTarget *big.Int) (chainhash.Hash, *big.Int, error) {
+	serialized := serializeHeader(header)
+	blockHash := hasher.BlockHash(serialized)
+
+	target, err := compactToTarget(header.Bits)
+	if err != nil {
+		return blockHash, nil, err
+	}
+	if maxTarget != nil && target.Cmp(maxTarget) > 0 {
+		return blockHash, target, fmt.Errorf("the target %064x is easier than allowed (%064x)", target, maxTarget)
+	}
+
+	powHash := hasher.PowHash(serialized)
+	if hashToBig(powHash).Cmp(target) > 0 {
+		return blockHash, target, fmt.Errorf("proof of work hash %s is above the target %064x", powHash, target)
+	}
+	return blockHash, target, nil
+}
+
+// compactToTarget decodes the compact target of a header's bits, a 3 byte
+// mantissa with a sign bit and a 1 byte base 256 exponent.
+func compactToTarget(bits uint32) (*big.Int, error) {
+	mantissa := bits & 0x007fffff
+	exponent := uint(bits >> 24)
+
+	var target *big.Int
+	if exponent <= 3 {
+		mantissa >>= 8 * (3 - exponent)
+		target = big.NewInt(int64(mantissa))
+	} else {
+		target = big.NewInt(int64(mantissa))
+		target.Lsh(target, 8*(exponent-3))
+	}
+
+	if bits&0x00800000 != 0 || target.Sign() == 0 {
+		return nil, fmt.Errorf("bits %08x don't encode a positive target", bits)
+	}
+	if target.BitLen() > 256 {
+		return nil, fmt.Errorf("bits %08x encode a target above 256 bits", bits)
+	}
+	return target, nil
+}
@@ -0,0 +1,157 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantom
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"strings"
+	"testing"
+	"time"
+)
+
+const dashPowLimit = "00000fffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+
+// genesisHeader returns a version 1 genesis block header.
+func genesisHeader(t *testing.T, merkleRoot string, timestamp int64, bits uint32, nonce uint32) *wire.BlockHeader {
+	merkle, err := chainhash.NewHashFromStr(merkleRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &wire.BlockHeader{Version: 1, MerkleRoot: *merkle, Timestamp: time.Unix(timestamp, 0), Bits: bits, Nonce: nonce}
+}
+
+func TestPowHashers(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		header    *wire.BlockHeader
+		hash      string
+	}{
+		{
+			"dash genesis", "x11",
+			genesisHeader(t, "e0028eb9648db56b1ac77cf090b99048a8007e2bb64b68f092c03c7f56a662c7",
+				1390095618, 0x1e0ffff0, 28917698),
+			"00000ffd590b1485b3caadc19b22e6379c733355108f107a430458cdf3407ab6",
+		},
+		{
+			"pivx genesis", "quark",
+			genesisHeader(t, "1b2ef6e2f28be914103a277377ae7729dcd125dfeb8bf97bd5964ba72b6dc39b",
+				1454124731, 0x1e0ffff0, 2402015),
+			"0000041e482b9b9691d98eefb48473405c0b8ec31b76df3797c74a78680ef818",
+		},
+		{
+			"bitcoin genesis", "sha256d",
+			genesisHeader(t, "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+				1231006505, 0x1d00ffff, 2083236893),
+			"000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f",
+		},
+	}
+
+	limit, err := ParsePowLimit(dashPowLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		hasher, err := LookupPowHasher(test.algorithm)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+
+		hash, _, err := CheckProofOfWork(test.header, hasher, limit)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+		}
+		if hash.String() != test.hash {
+			t.Errorf("%s: block hash %s, want %s", test.name, hash, test.hash)
+		}
+	}
+}
+
+// minedHeader returns a header whose sha256d proof of work meets bits.
+func minedHeader(t *testing.T, bits uint32, timestamp int64) *wire.BlockHeader {
+	header := &wire.BlockHeader{Version: 1, MerkleRoot: chainhash.Hash{1}, Timestamp: time.Unix(timestamp, 0), Bits: bits}
+	for ; header.Nonce < 1<<24; header.Nonce++ {
+		if _, _, err := CheckProofOfWork(header, sha256dHasher{}, nil); err == nil {
+			return header
+		}
+	}
+	t.Fatalf("unable to mine a header for bits %08x", bits)
+	return nil
+}
+
+// checkHeader announces the header's hash and serves the header, returning
+// whether the tracker released the hash.
+func checkHeader(tracker *HeaderTracker, header *wire.BlockHeader) bool {
+	hash := sha256dHasher{}.BlockHash(serializeHeader(header))
+	tracker.Announced(hash)
+
+	valid, _ := tracker.Headers([]*wire.BlockHeader{header}, "127.0.0.1")
+	return len(valid) == 1 && valid[0] == hash
+}
+
+func TestHeaderTrackerPowLimit(t *testing.T) {
+	limit, err := ParsePowLimit("00ffff" + strings.Repeat("0", 58))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := &HeaderTracker{Hasher: sha256dHasher{}, PowLimit: limit}
+
+	if checkHeader(tracker, minedHeader(t, 0x207fffff, 1)) {
+		t.Errorf("a header easier than the pow limit was accepted")
+	}
+	if !checkHeader(tracker, minedHeader(t, 0x1f00ffff, 2)) {
+		t.Errorf("a header at the pow limit was rejected")
+	}
+}
+
+func TestHeaderTrackerRecentDifficulty(t *testing.T) {
+	tracker := &HeaderTracker{Hasher: sha256dHasher{}}
+
+	if !checkHeader(tracker, minedHeader(t, 0x1f00ffff, 1)) {
+		t.Fatalf("the first header was rejected")
+	}
+	if checkHeader(tracker, minedHeader(t, 0x207fffff, 2)) {
+		t.Errorf("a header far easier than the recent ones was accepted")
+	}
+	if !checkHeader(tracker, minedHeader(t, 0x1f03fffc, 3)) {
+		t.Errorf("a header within the allowed drift was rejected")
+	}
+}
+
+func TestParsePowLimit(t *testing.T) {
+	for _, limit := range []string{"", "00000fff", strings.Repeat("0", 64), strings.Repeat("g", 64)} {
+		if _, err := ParsePowLimit(limit); err == nil {
+			t.Errorf("ParsePowLimit(%q) succeeded", limit)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantom
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"net"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// protocolLag is how far behind a peer's protocol version we can be before
+// the configured one is considered out of date.
+const protocolLag = 10
+
+//daemons reject old peers with "Version must be 70208 or greater"
+var minVersionReason = regexp.MustCompile(`(\d+) or greater`)
+
+// ProbeResult is what a peer told us about itself during a handshake.
+type ProbeResult struct {
+	Address         string           `json:"address"`
+	ProtocolVersion int32            `json:"protocol_version,omitempty"`
+	UserAgent       string           `json:"user_agent,omitempty"`
+	Services        wire.ServiceFlag `json:"services,omitempty"`
+	BestHeight      int32            `json:"best_height,omitempty"`
+
+	// MinProtocol is the oldest version the peer accepts, only known once
+	// it has rejected ours.
+	MinProtocol  uint32 `json:"min_protocol,omitempty"`
+	RejectReason string `json:"reject_reason,omitempty"`
+}
+
+// Probe connects to a peer, announces protocolVersion and reads back the
+// peer's version. When our version is rejected as obsolete the peer is
+// probed again with the minimum it asked for.
+func Probe(address string, magic wire.BitcoinNet, protocolVersion uint32, userAgent string,
+	timeout time.Duration) (ProbeResult, error) {
+
+	result, err := probe(address, magic, protocolVersion, userAgent, timeout)
+	if err != nil || result.MinProtocol == 0 || result.ProtocolVersion != 0 {
+		return result, err
+	}
+
+	retry, err := probe(address, magic, result.MinProtocol, userAgent, timeout)
+	if err != nil {
+		return result, nil
+	}
+	retry.MinProtocol = result.MinProtocol
+	retry.RejectReason = result.RejectReason
+	return retry, nil
+}
+
+func probe(address string, magic wire.BitcoinNet, protocolVersion uint32, userAgent string,
+	timeout time.Duration) (ProbeResult, error) {
+
+	result := ProbeResult{Address: address}
+
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return result, err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return result, fmt.Errorf("invalid port %q", portString)
+	}
+
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return result, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	me := wire.NewNetAddressIPPort(net.ParseIP("8.8.8.8"), uint16(port), 0)
+	you := wire.NewNetAddressIPPort(net.ParseIP(host), uint16(port), 0)
+	version := wire.NewMsgVersion(me, you, 0xDEADBEEF, 0)
+	version.ProtocolVersion = int32(protocolVersion)
+	version.UserAgent = userAgent
+	version.DisableRelayTx = true
+
+	_, err = wire.WriteMessageN(conn, version, protocolVersion, magic)
+	if err != nil {
+		return result, err
+	}
+
+	reader := bufio.NewReader(conn)
+	for {
+		_, msg, _, err := wire.ReadMessageWithProfileN(reader, protocolVersion, magic, nil)
+		if err != nil {
+			if _, ok := err.(*wire.MessageError); ok {
+				continue
+			}
+			if result.RejectReason != "" {
+				return result, nil //disconnected after rejecting us
+			}
+			return result, err
+		}
+
+		switch msg := msg.(type) {
+		case *wire.MsgVersion:
+			result.ProtocolVersion = msg.ProtocolVersion
+			result.UserAgent = msg.UserAgent
+			result.Services = msg.Services
+			result.BestHeight = msg.LastBlock
+			return result, nil
+
+		case *wire.MsgReject:
+			if msg.Cmd != wire.CmdVersion {
+				continue
+			}
+			result.RejectReason = msg.Reason
+			if match := minVersionReason.FindStringSubmatch(msg.Reason); match != nil {
+				minProtocol, _ := strconv.ParseUint(match[1], 10, 32)
+				result.MinProtocol = uint32(minProtocol)
+			}
+		}
+	}
+}
+
+// CheckProtocol compares our configured protocol version with a probe. It
+// returns the version to use instead, or 0 when the configured one is fine,
+// and a warning when there's something to report.
+func (result ProbeResult) CheckProtocol(configured uint32) (uint32, string) {
+	if result.MinProtocol > configured {
+		return result.MinProtocol, fmt.Sprintf("%s rejected protocol %d (%s), it accepts %d and later.",
+			result.Address, configured, result.RejectReason, result.MinProtocol)
+	}
+
+	if result.ProtocolVersion > 0 && uint32(result.ProtocolVersion) > configured+protocolLag {
+		return uint32(result.ProtocolVersion), fmt.Sprintf("%s runs protocol %d, the configured %d is out of date.",
+			result.Address, result.ProtocolVersion, configured)
+	}
+
+	if result.ProtocolVersion > 0 && uint32(result.ProtocolVersion) < configured {
+		return 0, fmt.Sprintf("%s runs protocol %d, older than the configured %d.",
+			result.Address, result.ProtocolVersion, configured)
+	}
+
+	return 0, ""
+}
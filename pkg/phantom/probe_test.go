@@ -0,0 +1,122 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom_test
+
+import (
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"github.com/breakcrypto/phantom/pkg/phantom/phantomtest"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbe(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		minimum    uint32
+		configured uint32
+
+		minProtocol uint32
+		switchTo    uint32
+	}{
+		{"accepted", 70206, 70208, 0, 0},
+		{"rejected as obsolete", 70210, 70208, 70210, 70210},
+	} {
+		peer, err := phantomtest.NewFakePeer(wire.BitcoinNet(0xBD6B0CBF), 70213)
+		if err != nil {
+			t.Fatal(err)
+		}
+		peer.MinProtocolVersion = test.minimum
+		peer.UserAgent = "/Dash Core:0.12.3.3/"
+		peer.BestHeight = 1000
+		peer.Start()
+
+		address := net.JoinHostPort(peer.IpAddress(), strconv.Itoa(int(peer.Port())))
+		result, err := phantom.Probe(address, peer.Magic, test.configured, "/phantomtest:0.0.1/", 5*time.Second)
+		peer.Close()
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		if result.Address != address || result.ProtocolVersion != 70213 || result.UserAgent != peer.UserAgent ||
+			result.BestHeight != 1000 || result.MinProtocol != test.minProtocol {
+			t.Errorf("%s: probed %+v", test.name, result)
+		}
+		if test.minProtocol != 0 && !strings.Contains(result.RejectReason, "70210 or greater") {
+			t.Errorf("%s: reject reason %q", test.name, result.RejectReason)
+		}
+
+		switchTo, warning := result.CheckProtocol(test.configured)
+		if switchTo != test.switchTo || (switchTo == 0) != (warning == "") {
+			t.Errorf("%s: CheckProtocol() = %d, %q, want %d", test.name, switchTo, warning, test.switchTo)
+		}
+	}
+}
+
+func TestProbeUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	if _, err := phantom.Probe(address, wire.MainNet, 70208, "/phantomtest:0.0.1/", time.Second); err == nil {
+		t.Error("probing a closed port succeeded")
+	}
+	if _, err := phantom.Probe("127.0.0.1", wire.MainNet, 70208, "/phantomtest:0.0.1/", time.Second); err == nil {
+		t.Error("probing an address without a port succeeded")
+	}
+}
+
+func TestCheckProtocol(t *testing.T) {
+	for _, test := range []struct {
+		result   phantom.ProbeResult
+		switchTo uint32
+		warns    bool
+	}{
+		{phantom.ProbeResult{ProtocolVersion: 70208}, 0, false},
+		{phantom.ProbeResult{ProtocolVersion: 70218}, 0, false},
+		{phantom.ProbeResult{ProtocolVersion: 70219}, 70219, true},
+		{phantom.ProbeResult{ProtocolVersion: 70206}, 0, true},
+		{phantom.ProbeResult{MinProtocol: 70210, RejectReason: "Version must be 70210 or greater"}, 70210, true},
+		{phantom.ProbeResult{MinProtocol: 70206, ProtocolVersion: 70208}, 0, false},
+		{phantom.ProbeResult{}, 0, false},
+	} {
+		switchTo, warning := test.result.CheckProtocol(70208)
+		if switchTo != test.switchTo || (warning != "") != test.warns {
+			t.Errorf("CheckProtocol() of %+v = %d, %q", test.result, switchTo, warning)
+		}
+	}
+}
@@ -31,7 +31,6 @@ package phantom
 
 import (
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
-	"log"
 	"sync"
 )
 
@@ -61,7 +60,7 @@ func (q *Queue) Push(n *chainhash.Hash) {
 	//see if n is in the queue (inefficient but good enough for now)
 	for _, node := range q.nodes {
 		if node != nil && node.String() == n.String() {
-			log.Println("Duplicate hash found - all is well.")
+			peerLog.Debugf("Duplicate hash found - all is well.")
 			return //skip a hash that we already have
 		}
 	}
@@ -111,4 +110,17 @@ func (q *Queue) Len() int {
 	defer q.mux.Unlock()
 
 	return q.count
+}
+
+// Hashes returns the queued hashes, the one pings are signed with first.
+func (q *Queue) Hashes() []chainhash.Hash {
+	q.mux.Lock()
+
+	defer q.mux.Unlock()
+
+	hashes := make([]chainhash.Hash, 0, q.count)
+	for i := 0; i < q.count; i++ {
+		hashes = append(hashes, *q.nodes[(q.head+i)%len(q.nodes)])
+	}
+	return hashes
 }
\ No newline at end of file
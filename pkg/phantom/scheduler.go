@@ -0,0 +1,465 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantom
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"sync"
+	"time"
+)
+
+// pingHeap is a min-heap of pings ordered by their next slot.
+type pingHeap []*MasternodePing
+
+func (h pingHeap) Len() int {
+	return len(h)
+}
+
+func (h pingHeap) Less(i, j int) bool {
+	return h[i].PingTime.Before(h[j].PingTime)
+}
+
+func (h pingHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *pingHeap) Push(x interface{}) {
+	*h = append(*h, x.(*MasternodePing))
+}
+
+func (h *pingHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	ping := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return ping
+}
+
+// PingScheduler emits every alias in the masternode file exactly at its
+// epoch-derived slot, then re-arms it one ping interval later.
+type PingScheduler struct {
+	MasternodeConf  string
+	HashQueue       *Queue
+	MagicMessage    string
+	SentinelVersion uint32
+	DaemonVersion   uint32
+	PingInterval    time.Duration
+	SigTimeOffset   time.Duration
+	Profile         *wire.MessageProfile
+	SignatureScheme SignatureScheme
+	Broadcasts      *BroadcastStore
+	Sporks          *SporkTable
+	Notifier        *Notifier
+	Clock           Clock
+
+	pings  pingHeap
+	reload chan struct{}
+	mux    sync.Mutex
+
+	//slots up to here have been emitted, a reload must not skip later ones
+	processedUntil time.Time
+
+	//the scheme the last ping was signed with, to log spork driven switches
+	activeScheme SignatureScheme
+	sentinelWarned bool
+
+	//aliases peers rejected, keyed by alias
+	rejects   map[string]int
+	suspended map[string]suspension
+}
+
+// suspension records why an alias stopped pinging. It's lifted once the
+// alias's masternode file entry changes.
+type suspension struct {
+	reason string
+	entry  string
+	since  time.Time
+}
+
+// MasternodeStatus is a scheduled masternode's state.
+type MasternodeStatus struct {
+	Alias         string    `json:"alias"`
+	Outpoint      string    `json:"outpoint"`
+	NextPing      time.Time `json:"next_ping"`
+	Rejects       int       `json:"rejects"`
+	Suspended     bool      `json:"suspended"`
+	SuspendReason string    `json:"suspend_reason,omitempty"`
+}
+
+// suspensionEntry identifies the masternode file line a suspension applies to.
+func suspensionEntry(ping *MasternodePing) string {
+	return OutpointKey(ping.OutpointHash, ping.OutpointIndex) + " " + ping.PrivateKey
+}
+
+// Load (re)reads the masternode file and rebuilds the schedule. Slots are
+// derived from each alias's epoch, so rebuilding never moves a pending ping.
+// Aliases without an epoch get one written to the file.
+func (s *PingScheduler) Load() error {
+	s.mux.Lock()
+	after := s.processedUntil
+	s.mux.Unlock()
+
+	if after.IsZero() {
+		after = clockOrDefault(s.Clock).Now()
+	}
+
+	assigned, err := StoreMissingEpochs(s.MasternodeConf, after, s.PingInterval)
+	if err != nil {
+		schedulerLog.Warnf("Unable to store the assumed epochs, they'll be assumed again on the next reload: %s", err)
+	}
+	for alias, epoch := range assigned {
+		schedulerLog.With("alias", alias).Infof("No epoch time found, stored %d.", epoch)
+	}
+
+	pings, err := LoadPingsFromMasternodeFile(s.MasternodeConf, s.HashQueue, s.MagicMessage,
+		s.SentinelVersion, s.DaemonVersion, s.PingInterval, s.SigTimeOffset, s.Profile, after)
+	if err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	entries := make(map[string]string, len(pings))
+	s.pings = make(pingHeap, 0, len(pings))
+	for i := range pings {
+		schedulerLog.With("alias", pings[i].Name).Infof("Enabling, next ping at %s.", pings[i].PingTime.UTC())
+		s.pings = append(s.pings, &pings[i])
+		entries[pings[i].Name] = suspensionEntry(&pings[i])
+	}
+	heap.Init(&s.pings)
+
+	for alias, suspended := range s.suspended {
+		if entries[alias] != suspended.entry {
+			schedulerLog.With("alias", alias).Infof("Masternode entry changed, lifting the suspension.")
+			delete(s.suspended, alias)
+		}
+	}
+
+	return nil
+}
+
+// Outpoints returns the collateral outpoints of every scheduled masternode.
+func (s *PingScheduler) Outpoints() []string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	outpoints := make([]string, 0, len(s.pings))
+	for _, ping := range s.pings {
+		outpoints = append(outpoints, OutpointKey(ping.OutpointHash, ping.OutpointIndex))
+	}
+	return outpoints
+}
+
+// Masternodes returns a copy of every scheduled masternode.
+func (s *PingScheduler) Masternodes() []MasternodePing {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	masternodes := make([]MasternodePing, 0, len(s.pings))
+	for _, ping := range s.pings {
+		masternodes = append(masternodes, *ping)
+	}
+	return masternodes
+}
+
+// Reject records and notifies a peer rejecting one of our masternode's
+// messages. Invalid, obsolete and malformed messages won't be accepted on a
+// retry, so the alias is suspended until its masternode file entry changes.
+// It returns true when the alias got suspended.
+func (s *PingScheduler) Reject(rejection Rejection) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if rejection.Alias == "" {
+		return false
+	}
+
+	if s.rejects == nil {
+		s.rejects = make(map[string]int)
+	}
+	s.rejects[rejection.Alias]++
+	s.Notifier.Raise(EventReject, rejection.Alias, "Its %s was rejected by %s with %s: %s",
+		rejection.Reject.Cmd, rejection.Peer, rejection.Reject.Code, rejection.Reject.Reason)
+
+	switch rejection.Reject.Code {
+	case wire.RejectInvalid, wire.RejectObsolete, wire.RejectMalformed:
+	default:
+		return false
+	}
+
+	if _, ok := s.suspended[rejection.Alias]; ok {
+		return false
+	}
+
+	for _, ping := range s.pings {
+		if ping.Name != rejection.Alias {
+			continue
+		}
+
+		if s.suspended == nil {
+			s.suspended = make(map[string]suspension)
+		}
+		s.suspended[rejection.Alias] = suspension{
+			reason: fmt.Sprintf("%s rejected the %s with %s: %s", rejection.Peer, rejection.Reject.Cmd,
+				rejection.Reject.Code, rejection.Reject.Reason),
+			entry: suspensionEntry(ping),
+			since: clockOrDefault(s.Clock).Now(),
+		}
+		return true
+	}
+	return false
+}
+
+// Resume lifts the suspension of an alias, returning false if it wasn't
+// suspended.
+func (s *PingScheduler) Resume(alias string) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if _, ok := s.suspended[alias]; !ok {
+		return false
+	}
+	delete(s.suspended, alias)
+	return true
+}
+
+// Pause suspends an alias until it's resumed or its masternode file entry
+// changes. It returns false if the alias isn't scheduled or already
+// suspended.
+func (s *PingScheduler) Pause(alias string) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if _, ok := s.suspended[alias]; ok {
+		return false
+	}
+
+	for _, ping := range s.pings {
+		if ping.Name != alias {
+			continue
+		}
+
+		if s.suspended == nil {
+			s.suspended = make(map[string]suspension)
+		}
+		s.suspended[alias] = suspension{
+			reason: "paused by the operator",
+			entry:  suspensionEntry(ping),
+			since:  clockOrDefault(s.Clock).Now(),
+		}
+		return true
+	}
+	return false
+}
+
+// PingNow returns a ping for alias signed for the current time, outside of
+// its schedule. The scheduled slots stay as they are.
+func (s *PingScheduler) PingNow(alias string) (MasternodePing, error) {
+	now := clockOrDefault(s.Clock).Now()
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, ping := range s.pings {
+		if ping.Name != alias {
+			continue
+		}
+
+		if suspended, ok := s.suspended[alias]; ok {
+			return MasternodePing{}, fmt.Errorf("%s is suspended: %s", alias, suspended.reason)
+		}
+		if ping.HashQueue.Peek() == nil {
+			return MasternodePing{}, errors.New("no block hash available yet")
+		}
+
+		emitted := *ping
+		emitted.PingTime = now
+		emitted.SignatureScheme = s.signatureScheme()
+		emitted.AttachBroadcastTemplate(s.Broadcasts)
+		return emitted, nil
+	}
+	return MasternodePing{}, fmt.Errorf("no masternode named %s", alias)
+}
+
+// Status returns the state of every scheduled masternode.
+func (s *PingScheduler) Status() []MasternodeStatus {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	statuses := make([]MasternodeStatus, 0, len(s.pings))
+	for _, ping := range s.pings {
+		suspended, isSuspended := s.suspended[ping.Name]
+		statuses = append(statuses, MasternodeStatus{
+			Alias:         ping.Name,
+			Outpoint:      OutpointKey(ping.OutpointHash, ping.OutpointIndex),
+			NextPing:      ping.PingTime,
+			Rejects:       s.rejects[ping.Name],
+			Suspended:     isSuspended,
+			SuspendReason: suspended.reason,
+		})
+	}
+	return statuses
+}
+
+// Reload asks a running scheduler to re-read the masternode file.
+func (s *PingScheduler) Reload() {
+	select {
+	case s.reloadChannel() <- struct{}{}:
+	default: //a reload is already pending
+	}
+}
+
+func (s *PingScheduler) reloadChannel() chan struct{} {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.reload == nil {
+		s.reload = make(chan struct{}, 1)
+	}
+	return s.reload
+}
+
+// Run emits due pings on pingChannel until the process exits. The
+// masternode file is re-read once per ping interval to pick up edits.
+func (s *PingScheduler) Run(pingChannel chan<- MasternodePing) {
+	clock := clockOrDefault(s.Clock)
+	reload := s.reloadChannel()
+
+	reloadTimer := clock.NewTimer(s.PingInterval)
+	defer reloadTimer.Stop()
+
+	timer := clock.NewTimer(s.nextWait(clock.Now()))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C():
+			for _, ping := range s.popDue(clock.Now()) {
+				pingChannel <- ping
+			}
+		case <-reloadTimer.C():
+			if err := s.Load(); err != nil {
+				schedulerLog.Errorf("Unable to reload the masternode file, keeping the current schedule: %s", err)
+			}
+			reloadTimer.Reset(s.PingInterval)
+		case <-reload:
+			if err := s.Load(); err != nil {
+				schedulerLog.Errorf("Unable to reload the masternode file, keeping the current schedule: %s", err)
+			}
+		}
+
+		resetTimer(timer, s.nextWait(clock.Now()))
+	}
+}
+
+// popDue removes every ping whose slot has passed, re-arms each for its next
+// slot and returns copies ready to be sent.
+func (s *PingScheduler) popDue(now time.Time) []MasternodePing {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	var due []MasternodePing
+
+	s.processedUntil = now
+	scheme := s.signatureScheme()
+
+	for s.pings.Len() > 0 && !s.pings[0].PingTime.After(now) {
+		ping := s.pings[0]
+
+		if suspended, ok := s.suspended[ping.Name]; ok {
+			schedulerLog.With("alias", ping.Name).Warnf("Suspended since %s (%s), skipping the slot at %s.",
+				suspended.since.UTC(), suspended.reason, ping.PingTime.UTC())
+			s.Notifier.Raise(EventMissedPing, ping.Name, "The ping for slot %s wasn't sent, the alias is suspended (%s).",
+				ping.PingTime.UTC(), suspended.reason)
+		} else if ping.HashQueue.Peek() == nil {
+			schedulerLog.With("alias", ping.Name).Warnf("No block hash available yet, skipping the slot at %s.",
+				ping.PingTime.UTC())
+			s.Notifier.Raise(EventMissedPing, ping.Name, "The ping for slot %s wasn't sent, no block hash is known yet.",
+				ping.PingTime.UTC())
+		} else {
+			schedulerLog.With("alias", ping.Name).Infof("Ping slot %s reached.", ping.PingTime.UTC())
+
+			emitted := *ping
+			emitted.SignatureScheme = scheme
+			emitted.AttachBroadcastTemplate(s.Broadcasts)
+			due = append(due, emitted)
+		}
+
+		for !ping.PingTime.After(now) {
+			ping.PingTime = ping.PingTime.Add(s.PingInterval)
+		}
+		heap.Fix(&s.pings, 0)
+	}
+
+	return due
+}
+
+// signatureScheme returns the configured scheme unless SPORK_6_NEW_SIGS has
+// activated the hash based one. Must be called with the mutex held.
+func (s *PingScheduler) signatureScheme() SignatureScheme {
+	scheme := s.SignatureScheme
+	if s.Sporks.IsActive(SporkNewSigs) {
+		scheme = SignatureHash
+	}
+
+	if scheme != s.activeScheme {
+		schedulerLog.Infof("Signing pings with the %s signature scheme.", scheme)
+		s.activeScheme = scheme
+	}
+
+	if s.Sporks.IsActive(SporkRequireSentinelFlag) && s.SentinelVersion == 0 && !s.sentinelWarned {
+		schedulerLog.Warnf("%s is active but no sentinel version is configured, pings may be rejected.",
+			SporkName(SporkRequireSentinelFlag))
+		s.sentinelWarned = true
+	}
+
+	return scheme
+}
+
+func (s *PingScheduler) nextWait(now time.Time) time.Duration {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.pings.Len() == 0 {
+		return s.PingInterval
+	}
+
+	wait := s.pings[0].PingTime.Sub(now)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
@@ -0,0 +1,338 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom_test
+
+import (
+	"fmt"
+	"github.com/breakcrypto/phantom/pkg/phantom"
+	"github.com/breakcrypto/phantom/pkg/phantom/phantomtest"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// runEpoch is the first slot of mn1, mn2's comes two minutes later.
+var runEpoch = time.Unix(1555555555, 0)
+
+// runningScheduler is a PingScheduler whose Run loop is driven by a fake
+// clock.
+type runningScheduler struct {
+	*phantom.PingScheduler
+	clock *phantomtest.FakeClock
+	pings chan phantom.MasternodePing
+}
+
+// startScheduler schedules mn1 and mn2 ten minutes apart and starts Run a
+// minute before mn1's first slot. Run never returns, it's left waiting on
+// the fake clock.
+func startScheduler(t *testing.T, notifier *phantom.Notifier) (*runningScheduler, func()) {
+	dir, err := ioutil.TempDir("", "phantom")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wif, err := btcutil.NewWIF(key, &chaincfg.MainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := filepath.Join(dir, "masternode.txt")
+	lines := ""
+	for i, alias := range []string{"mn1", "mn2"} {
+		lines += fmt.Sprintf("%s 45.50.22.125:9999 %s %064x %d %d\n", alias, wif.String(), i+1, i,
+			runEpoch.Add(time.Duration(i)*2*time.Minute).Unix())
+	}
+	if err := ioutil.WriteFile(conf, []byte(lines), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := phantom.NewQueue(1)
+	hashes.Push(&chainhash.Hash{7})
+
+	s := &runningScheduler{
+		clock: phantomtest.NewFakeClock(runEpoch.Add(-time.Minute)),
+		pings: make(chan phantom.MasternodePing, 10),
+	}
+	s.PingScheduler = &phantom.PingScheduler{
+		MasternodeConf: conf,
+		HashQueue:      hashes,
+		PingInterval:   10 * time.Minute,
+		Notifier:       notifier,
+		Clock:          s.clock,
+	}
+	if err := s.Load(); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	go s.Run(s.pings)
+	return s, func() { os.RemoveAll(dir) }
+}
+
+// advance moves the clock to at once Run waits on its slot and reload
+// timers again.
+func (s *runningScheduler) advance(t *testing.T, at time.Time) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.clock.PendingTimers() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("the scheduler didn't re-arm its timers")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.clock.Set(at)
+}
+
+// expectPing checks the next ping emitted is alias's for slot.
+func (s *runningScheduler) expectPing(t *testing.T, alias string, slot time.Time) {
+	t.Helper()
+
+	select {
+	case ping := <-s.pings:
+		if ping.Name != alias || !ping.PingTime.Equal(slot) {
+			t.Errorf("emitted %s for %s, want %s for %s", ping.Name, ping.PingTime.UTC(), alias, slot.UTC())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s wasn't pinged for %s", alias, slot.UTC())
+	}
+}
+
+func (s *runningScheduler) expectNoPing(t *testing.T) {
+	t.Helper()
+
+	select {
+	case ping := <-s.pings:
+		t.Errorf("%s was pinged for %s", ping.Name, ping.PingTime.UTC())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSchedulerRunSlots(t *testing.T) {
+	s, cleanup := startScheduler(t, nil)
+	defer cleanup()
+
+	s.advance(t, runEpoch.Add(-time.Second))
+	s.expectNoPing(t)
+
+	s.advance(t, runEpoch)
+	s.expectPing(t, "mn1", runEpoch)
+	s.advance(t, runEpoch.Add(2*time.Minute))
+	s.expectPing(t, "mn2", runEpoch.Add(2*time.Minute))
+	s.advance(t, runEpoch.Add(10*time.Minute))
+	s.expectPing(t, "mn1", runEpoch.Add(10*time.Minute))
+
+	//a stalled clock catching up sends each alias once, for its latest slot
+	//passed, and keeps the slots aligned
+	s.advance(t, runEpoch.Add(35*time.Minute))
+	s.expectPing(t, "mn2", runEpoch.Add(12*time.Minute))
+	s.expectPing(t, "mn1", runEpoch.Add(20*time.Minute))
+	s.expectNoPing(t)
+
+	for _, status := range s.Status() {
+		want := runEpoch.Add(40 * time.Minute)
+		if status.Alias == "mn2" {
+			want = runEpoch.Add(42 * time.Minute)
+		}
+		if !status.NextPing.Equal(want) {
+			t.Errorf("%s: next ping at %s, want %s", status.Alias, status.NextPing.UTC(), want.UTC())
+		}
+	}
+}
+
+func TestSchedulerRunPauseResume(t *testing.T) {
+	notifier, webhook := testNotifier(nil)
+	defer webhook.Close()
+	s, cleanup := startScheduler(t, notifier)
+	defer cleanup()
+
+	if !s.Pause("mn1") {
+		t.Fatal("Pause(mn1) = false")
+	}
+	if s.Pause("mn1") || s.Pause("mn3") {
+		t.Error("pausing a paused or unknown alias succeeded")
+	}
+
+	s.advance(t, runEpoch)
+	s.expectNoPing(t)
+	notification, err := webhook.WaitForNotification(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notification.Event != phantom.EventMissedPing || notification.Subject != "mn1" {
+		t.Errorf("notification %+v, want a missed ping for mn1", notification)
+	}
+
+	//the other alias isn't held up
+	s.advance(t, runEpoch.Add(2*time.Minute))
+	s.expectPing(t, "mn2", runEpoch.Add(2*time.Minute))
+
+	if !s.Resume("mn1") || s.Resume("mn1") {
+		t.Fatal("Resume(mn1) didn't lift the pause exactly once")
+	}
+	s.advance(t, runEpoch.Add(10*time.Minute))
+	s.expectPing(t, "mn1", runEpoch.Add(10*time.Minute))
+}
+
+func TestSchedulerRunReject(t *testing.T) {
+	s, cleanup := startScheduler(t, nil)
+	defer cleanup()
+
+	reject := func(code wire.RejectCode) bool {
+		return s.Reject(phantom.Rejection{
+			Reject: wire.MsgReject{Cmd: wire.CmdMNP, Code: code, Reason: "test"},
+			Peer:   "45.50.22.126",
+			Alias:  "mn1",
+		})
+	}
+
+	//a duplicate is harmless, an invalid ping won't be accepted on a retry
+	if reject(wire.RejectDuplicate) {
+		t.Error("a duplicate reject suspended the alias")
+	}
+	s.advance(t, runEpoch)
+	s.expectPing(t, "mn1", runEpoch)
+
+	if !reject(wire.RejectInvalid) {
+		t.Fatal("an invalid reject didn't suspend the alias")
+	}
+	if reject(wire.RejectInvalid) {
+		t.Error("an already suspended alias was suspended again")
+	}
+
+	s.advance(t, runEpoch.Add(2*time.Minute))
+	s.expectPing(t, "mn2", runEpoch.Add(2*time.Minute))
+	s.advance(t, runEpoch.Add(10*time.Minute))
+	s.expectNoPing(t)
+
+	for _, status := range s.Status() {
+		if status.Alias != "mn1" {
+			continue
+		}
+		if status.Rejects != 3 || !status.Suspended || status.SuspendReason == "" {
+			t.Errorf("mn1 status %+v, want 3 rejects and suspended", status)
+		}
+	}
+
+	//a reload keeps the suspension while the entry is unchanged
+	s.Reload()
+	s.advance(t, runEpoch.Add(20*time.Minute))
+	s.expectPing(t, "mn2", runEpoch.Add(12*time.Minute))
+	s.expectNoPing(t)
+}
+
+// TestSchedulerRunPeerReject relays a scheduled ping to a fake peer that
+// rejects it, the reject has to come back tied to the alias and suspend it.
+func TestSchedulerRunPeerReject(t *testing.T) {
+	notifier, webhook := testNotifier(nil)
+	defer webhook.Close()
+	s, cleanup := startScheduler(t, notifier)
+	defer cleanup()
+
+	peer, err := phantomtest.NewFakePeer(wire.BitcoinNet(0xBD6B0CBF), 70208)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer.RejectPings = &wire.MsgReject{Cmd: wire.CmdMNP, Code: wire.RejectInvalid, Reason: "invalid signature"}
+	peer.Start()
+	defer peer.Close()
+
+	pingChannel := make(chan phantom.MasternodePing, 10)
+	var waitGroup sync.WaitGroup
+	pinger := peer.NewPingerConnection(pingChannel, make(chan chainhash.Hash, 100), &waitGroup)
+	pinger.RejectChannel = make(chan phantom.Rejection, 10)
+	waitGroup.Add(1)
+	go pinger.Start("/phantomtest:0.0.1/")
+	defer func() {
+		close(pingChannel)
+		waitGroup.Wait()
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for pinger.GetStatus() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("the pinger didn't connect to the fake peer")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.advance(t, runEpoch)
+	select {
+	case ping := <-s.pings:
+		pingChannel <- ping
+	case <-time.After(5 * time.Second):
+		t.Fatal("mn1 wasn't pinged")
+	}
+
+	var rejection phantom.Rejection
+	select {
+	case rejection = <-pinger.RejectChannel:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the reject never arrived")
+	}
+	if rejection.Alias != "mn1" || rejection.Outpoint != fmt.Sprintf("%064x:0", 1) {
+		t.Fatalf("rejection %+v, want one for mn1", rejection)
+	}
+	if !s.Reject(rejection) {
+		t.Error("the invalid ping didn't suspend mn1")
+	}
+
+	notification, err := webhook.WaitForNotification(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notification.Event != phantom.EventReject || notification.Subject != "mn1" ||
+		!strings.Contains(notification.Message, "invalid signature") {
+		t.Errorf("notification %+v, want a reject of mn1", notification)
+	}
+
+	for _, status := range s.Status() {
+		rejects := 0
+		if status.Alias == "mn1" {
+			rejects = 1
+		}
+		if status.Rejects != rejects || status.Suspended != (rejects == 1) {
+			t.Errorf("%s status %+v, want %d rejects", status.Alias, status, rejects)
+		}
+	}
+}
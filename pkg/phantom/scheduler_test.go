@@ -0,0 +1,256 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom
+
+import (
+	"container/heap"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingHook keeps every notification it's given.
+type recordingHook struct {
+	notifications []Notification
+	mux           sync.Mutex
+}
+
+func (hook *recordingHook) Notify(notification Notification) error {
+	hook.mux.Lock()
+	defer hook.mux.Unlock()
+
+	hook.notifications = append(hook.notifications, notification)
+	return nil
+}
+
+func (hook *recordingHook) String() string {
+	return "recording"
+}
+
+func (hook *recordingHook) take() []Notification {
+	hook.mux.Lock()
+	defer hook.mux.Unlock()
+
+	notifications := hook.notifications
+	hook.notifications = nil
+	return notifications
+}
+
+func TestSchedulerSkippedSlotsNotify(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler, cleanup := testScheduler(t, key)
+	defer cleanup()
+
+	hook := &recordingHook{}
+	scheduler.Notifier = &Notifier{Hooks: []Hook{hook}, RateLimit: time.Nanosecond}
+
+	slot := time.Unix(1555555555, 0).Add(10 * time.Minute)
+
+	//suspended
+	scheduler.Pause("mn1")
+	if due := scheduler.popDue(slot); len(due) != 0 {
+		t.Fatalf("popDue() emitted %d pings for a suspended alias", len(due))
+	}
+	scheduler.Notifier.Wait()
+	notifications := hook.take()
+	if len(notifications) != 1 || notifications[0].Event != EventMissedPing || notifications[0].Subject != "mn1" ||
+		!strings.Contains(notifications[0].Message, "suspended") {
+		t.Errorf("suspended slot notifications = %+v", notifications)
+	}
+
+	//no block hash
+	scheduler.Resume("mn1")
+	for scheduler.HashQueue.Len() > 0 {
+		scheduler.HashQueue.Pop()
+	}
+	slot = slot.Add(10 * time.Minute)
+	if due := scheduler.popDue(slot); len(due) != 0 {
+		t.Fatalf("popDue() emitted %d pings without a block hash", len(due))
+	}
+	scheduler.Notifier.Wait()
+	notifications = hook.take()
+	if len(notifications) != 1 || notifications[0].Event != EventMissedPing ||
+		!strings.Contains(notifications[0].Message, "no block hash") {
+		t.Errorf("hashless slot notifications = %+v", notifications)
+	}
+}
+
+func TestSchedulerStoresAssumedEpochs(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler, cleanup := testScheduler(t, key)
+	defer cleanup()
+
+	wif, err := btcutil.NewWIF(key, &chaincfg.MainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := "# no epochs yet\r\n" +
+		fmt.Sprintf("mn1 45.50.22.125:9999 %s %s 1\r\n", wif.String(), testCollateral) +
+		fmt.Sprintf("mn2 45.50.22.126:9999 %s %s 0 \n", wif.String(), testCollateral)
+	if err := ioutil.WriteFile(scheduler.MasternodeConf, []byte(lines), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1555555555, 0)
+	scheduler.Clock = fixedClock(now)
+	if err := scheduler.Load(); err != nil {
+		t.Fatal(err)
+	}
+	slots := make(map[string]time.Time)
+	for _, status := range scheduler.Status() {
+		slots[status.Alias] = status.NextPing
+	}
+	if !slots["mn1"].Equal(now.Add(time.Minute)) || !slots["mn2"].Equal(now.Add(time.Minute+5*time.Second)) {
+		t.Errorf("first slots %v, want a minute from now, 5s apart", slots)
+	}
+
+	data, err := ioutil.ReadFile(scheduler.MasternodeConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# no epochs yet\r\n" +
+		fmt.Sprintf("mn1 45.50.22.125:9999 %s %s 1 1555555015\r\n", wif.String(), testCollateral) +
+		fmt.Sprintf("mn2 45.50.22.126:9999 %s %s 0 1555555020\n", wif.String(), testCollateral)
+	if string(data) != want {
+		t.Errorf("masternode file\n%q\nwant\n%q", data, want)
+	}
+
+	//later reloads and restarts find the stored epochs
+	for _, restarted := range []bool{false, true} {
+		scheduler.popDue(now.Add(3 * time.Minute))
+		if restarted {
+			scheduler = &PingScheduler{MasternodeConf: scheduler.MasternodeConf, HashQueue: scheduler.HashQueue,
+				PingInterval: scheduler.PingInterval}
+		}
+		scheduler.Clock = fixedClock(now.Add(3 * time.Minute))
+		if err := scheduler.Load(); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, status := range scheduler.Status() {
+			if want := slots[status.Alias].Add(10 * time.Minute); !status.NextPing.Equal(want) {
+				t.Errorf("restarted %t: %s next ping at %s, want %s", restarted, status.Alias,
+					status.NextPing.UTC(), want.UTC())
+			}
+		}
+	}
+}
+
+func TestSchedulerPingNow(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler, cleanup := testScheduler(t, key)
+	defer cleanup()
+
+	now := time.Unix(1555555555, 0).Add(4 * time.Minute)
+	scheduler.Clock = fixedClock(now)
+
+	ping, err := scheduler.PingNow("mn1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ping.Name != "mn1" || !ping.PingTime.Equal(now) {
+		t.Errorf("PingNow() = %s for %s, want mn1 for %s", ping.Name, ping.PingTime.UTC(), now.UTC())
+	}
+	if next := scheduler.Status()[0].NextPing; !next.Equal(time.Unix(1555555555, 0).Add(10 * time.Minute)) {
+		t.Errorf("PingNow() moved the next slot to %s", next.UTC())
+	}
+
+	if _, err := scheduler.PingNow("mn2"); err == nil {
+		t.Error("PingNow() of an unknown alias succeeded")
+	}
+
+	scheduler.Pause("mn1")
+	if _, err := scheduler.PingNow("mn1"); err == nil || !strings.Contains(err.Error(), "suspended") {
+		t.Errorf("PingNow() of a paused alias: %v", err)
+	}
+
+	scheduler.Resume("mn1")
+	for scheduler.HashQueue.Len() > 0 {
+		scheduler.HashQueue.Pop()
+	}
+	if _, err := scheduler.PingNow("mn1"); err == nil || !strings.Contains(err.Error(), "no block hash") {
+		t.Errorf("PingNow() without a block hash: %v", err)
+	}
+}
+
+func TestSchedulerHeapOrder(t *testing.T) {
+	hashes := NewQueue(1)
+	hashes.Push(&chainhash.Hash{1})
+
+	base := time.Unix(1555555555, 0)
+	scheduler := &PingScheduler{PingInterval: 10 * time.Minute}
+	for i, offset := range []int{7, 3, 9, 1, 5, 0, 8, 2, 6, 4} {
+		scheduler.pings = append(scheduler.pings, &MasternodePing{
+			Name:      fmt.Sprintf("mn%d", i),
+			PingTime:  base.Add(time.Duration(offset) * time.Minute),
+			HashQueue: hashes,
+		})
+	}
+	heap.Init(&scheduler.pings)
+
+	if due := scheduler.popDue(base.Add(-time.Second)); len(due) != 0 {
+		t.Fatalf("popDue() emitted %d pings before the first slot", len(due))
+	}
+
+	due := scheduler.popDue(base.Add(4 * time.Minute))
+	if len(due) != 5 {
+		t.Fatalf("popDue() emitted %d pings, want the 5 slots passed", len(due))
+	}
+	for i, ping := range due {
+		if want := base.Add(time.Duration(i) * time.Minute); !ping.PingTime.Equal(want) {
+			t.Errorf("ping %d (%s) is for %s, want %s", i, ping.Name, ping.PingTime.UTC(), want.UTC())
+		}
+	}
+
+	//the emitted ones were re-armed behind the rest
+	if next := scheduler.nextWait(base.Add(4 * time.Minute)); next != time.Minute {
+		t.Errorf("nextWait() = %s, want a minute", next)
+	}
+	due = scheduler.popDue(base.Add(14 * time.Minute))
+	if len(due) != 10 || !due[9].PingTime.Equal(base.Add(14*time.Minute)) {
+		t.Errorf("popDue() emitted %d pings, the last for %s", len(due), due[len(due)-1].PingTime.UTC())
+	}
+}
@@ -0,0 +1,110 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantom
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+)
+
+// SignatureScheme selects how masternode pings are signed.
+type SignatureScheme int
+
+const (
+	// SignatureLegacy signs the magic message followed by the
+	// "CTxIn(...)<blockhash><sigtime>" string.
+	SignatureLegacy SignatureScheme = iota
+
+	// SignatureHash signs the ping's serialized signature hash, used once
+	// SPORK_6_NEW_SIGS is active on Dash-derived coins.
+	SignatureHash
+)
+
+var signatureSchemeNames = map[SignatureScheme]string{
+	SignatureLegacy: "legacy",
+	SignatureHash:   "hash",
+}
+
+func (scheme SignatureScheme) String() string {
+	if name, ok := signatureSchemeNames[scheme]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int(scheme))
+}
+
+// ParseSignatureScheme returns the scheme for a coin conf or flag value. An
+// empty string is the legacy scheme.
+func ParseSignatureScheme(name string) (SignatureScheme, error) {
+	if name == "" {
+		return SignatureLegacy, nil
+	}
+
+	for scheme, schemeName := range signatureSchemeNames {
+		if schemeName == name {
+			return scheme, nil
+		}
+	}
+	return SignatureLegacy, fmt.Errorf("unknown signature scheme %q (known: legacy, hash)", name)
+}
+
+// signedMessageHash returns the digest of a message signed with the coin's
+// magic message prefix, as done by the daemon's message signer.
+func signedMessageHash(magicMessage string, message string) []byte {
+	var buf bytes.Buffer
+	wire.WriteVarString(&buf, 0, magicMessage) //"DarkCoin Signed Message:\n" - $PAC || "ProtonCoin Signed Message:\n" - ANDS
+	wire.WriteVarString(&buf, 0, message)
+	return chainhash.DoubleHashB(buf.Bytes())
+}
+
+// signedBy reports whether sig is a compact signature by pubKey over any of
+// hashes. Messages that may use either scheme are checked against both.
+func signedBy(sig []byte, pubKey *btcec.PublicKey, hashes ...[]byte) bool {
+	for _, hash := range hashes {
+		recovered, _, err := btcec.RecoverCompact(btcec.S256(), sig, hash)
+		if err == nil && recovered.IsEqual(pubKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateMNPHashSignature signs the signature hash of mnp. The ping must be
+// complete apart from its signature.
+func GenerateMNPHashSignature(mnp *wire.MsgMNP, privKey btcec.PrivateKey) []byte {
+	hash := mnp.SignatureHash()
+
+	sig, _ := btcec.SignCompact(btcec.S256(), &privKey, hash[:], false)
+
+	return sig
+}
@@ -0,0 +1,184 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"net"
+	"testing"
+	"time"
+)
+
+//fixed inputs shared by the signature vectors, compact signatures are
+//deterministic (RFC 6979) so they can be pinned
+const (
+	vectorPrivateKey = "0101010101010101010101010101010101010101010101010101010101010101"
+	vectorPublicKey  = "031b84c5567b126440995d3ed5aaba0565d71e1834604819ff9c17f5e9d5dd078f"
+	vectorOutpoint   = "b3c1e5a8e1d5c42c2f7ab1c43bd2b5e4d5e8d6b3c2a1f0e9d8c7b6a5f4e3d2c1"
+	vectorBlockHash  = "00000000000000112e41e4b3afda8b233b8cc07c532d2eac5de097b68358c43e"
+	vectorSigTime    = 1555556155
+)
+
+func vectorKey(t *testing.T) *btcec.PrivateKey {
+	raw, err := hex.DecodeString(vectorPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), raw)
+	if got := hex.EncodeToString(key.PubKey().SerializeCompressed()); got != vectorPublicKey {
+		t.Fatalf("public key = %s, want %s", got, vectorPublicKey)
+	}
+	return key
+}
+
+func vectorHash(t *testing.T, s string) chainhash.Hash {
+	var hash chainhash.Hash
+	err := chainhash.Decode(&hash, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func checkVector(t *testing.T, sig []byte, want string, pubKey *btcec.PublicKey, digest []byte) {
+	t.Helper()
+
+	wantSig, err := hex.DecodeString(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, wantSig) {
+		t.Errorf("signature = %x, want %s", sig, want)
+	}
+	if !signedBy(sig, pubKey, digest) {
+		t.Error("signature doesn't recover to the signing key")
+	}
+}
+
+func TestGenerateMNPSignatureVectors(t *testing.T) {
+	key := vectorKey(t)
+
+	const message = "CTxIn(COutPoint(" + vectorOutpoint + ", 1), scriptSig=)" + vectorBlockHash + "1555556155"
+
+	tests := []struct {
+		profile      string
+		magicMessage string
+		sig          string
+	}{
+		{"dash-12.1", "DarkCoin Signed Message:\n",
+			"1c0d1032fcaf00fa231e6e51593478eb2b05bf6fdb98295425c2c4af026f9b01673f4d000a9dac00b88f53b2404d8fc42309b8ab473755fb5a4bcd757c16e35cc0"},
+		{"pivx", "DarkNet Signed Message:\n",
+			"1cfe96d3c6317aa9c6227211805fe6e2f47085f31a3a58b965f7b97a1273b3ad941d8fc86422b95551d7e4cdf1b7e811cf670723da0477ab68580e174ba8b604f6"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.profile, func(t *testing.T) {
+			profile, err := wire.LookupMessageProfile(test.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := profile.PingSignatureMessage(vectorOutpoint, 1, nil, vectorBlockHash, vectorSigTime); got != message {
+				t.Errorf("signed message = %q, want %q", got, message)
+			}
+
+			sig := GenerateMNPSignature(profile, test.magicMessage, vectorOutpoint, 1, nil, vectorBlockHash, vectorSigTime, *key)
+			checkVector(t, sig, test.sig, key.PubKey(), signedMessageHash(test.magicMessage, message))
+		})
+	}
+}
+
+func TestGenerateMNPHashSignatureVector(t *testing.T) {
+	key := vectorKey(t)
+
+	profile, err := wire.LookupMessageProfile("dash-12.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outpoint := vectorHash(t, vectorOutpoint)
+	mnp := wire.MsgMNP{
+		Profile:           profile,
+		Vin:               *wire.NewTxIn(wire.NewOutPoint(&outpoint, 1), nil, nil),
+		BlockHash:         vectorHash(t, vectorBlockHash),
+		SigTime:           vectorSigTime,
+		SentinelEnabled:   true,
+		SentinelIsCurrent: true,
+		SentinelVersion:   0x010001,
+		DaemonEnabled:     true,
+		DaemonVersion:     120300,
+	}
+
+	hash := mnp.SignatureHash()
+	if want := "c4f1869ed1c90978d36ad10927ed27dfc9e4ec937db6871897211dc1afe1e299"; hash.String() != want {
+		t.Errorf("SignatureHash() = %s, want %s", hash, want)
+	}
+
+	sig := GenerateMNPHashSignature(&mnp, *key)
+	checkVector(t, sig, "1b5dee4d0d9595f53d6f5a78f9015bff22a91001a1d846c52c74b0d79f83b11a670c361ce277f0edc31ccd6851a7ff1dc4529da8cc2a5327a7bfcd2d52e266d1cf",
+		key.PubKey(), hash[:])
+}
+
+func TestMNBHashSignatureVector(t *testing.T) {
+	key := vectorKey(t)
+
+	outpoint := vectorHash(t, vectorOutpoint)
+	mnb := wire.MsgMNB{
+		Vin:                     *wire.NewTxIn(wire.NewOutPoint(&outpoint, 1), nil, nil),
+		PubKeyCollateralAddress: key.PubKey().SerializeCompressed(),
+		PubKeyMasternode:        key.PubKey().SerializeUncompressed(),
+		SigTime:                 1555555555,
+		ProtocolVersion:         70208,
+	}
+	copy(mnb.Addr.IpAddress[:], net.ParseIP("45.50.22.125").To16())
+	mnb.Addr.Port = 9999
+
+	hash := mnb.SignatureHash()
+	if want := "ca426a1709765f8b6d0cc55da1e22f60c4e62d47d16843fc2869f0dae5e3b15f"; hash.String() != want {
+		t.Errorf("SignatureHash() = %s, want %s", hash, want)
+	}
+
+	sig, err := btcec.SignCompact(btcec.S256(), key, hash[:], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVector(t, sig, "1b5aaf6c4772b4f259129b2e30ffe5db03372003f9c5a050e295635124a810f3a77277ad7f2ab13d483fbf907329c1798fc8426dd31fd0fe6a052781fd0a63023f",
+		key.PubKey(), hash[:])
+
+	mnb.Sig = sig
+	err = validateBroadcast(&mnb, time.Unix(int64(mnb.SigTime), 0), testMagicMessage)
+	if err != nil {
+		t.Errorf("validateBroadcast() = %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantom
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"sync"
+)
+
+// Spork ids shared by the Dash-derived coins.
+const (
+	SporkInstantSendEnabled           int32 = 10001
+	SporkNewSigs                      int32 = 10005
+	SporkMasternodePaymentEnforcement int32 = 10007
+	SporkSuperblocksEnabled           int32 = 10008
+	SporkMasternodePayUpdatedNodes    int32 = 10009
+	SporkRequireSentinelFlag          int32 = 10013
+)
+
+var sporkNames = map[int32]string{
+	SporkInstantSendEnabled:           "SPORK_2_INSTANTSEND_ENABLED",
+	SporkNewSigs:                      "SPORK_6_NEW_SIGS",
+	SporkMasternodePaymentEnforcement: "SPORK_8_MASTERNODE_PAYMENT_ENFORCEMENT",
+	SporkSuperblocksEnabled:           "SPORK_9_SUPERBLOCKS_ENABLED",
+	SporkMasternodePayUpdatedNodes:    "SPORK_10_MASTERNODE_PAY_UPDATED_NODES",
+	SporkRequireSentinelFlag:          "SPORK_14_REQUIRE_SENTINEL_FLAG",
+}
+
+// SporkName returns the daemon's name for a spork id.
+func SporkName(id int32) string {
+	if name, ok := sporkNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("SPORK_%d", id)
+}
+
+// ParseSporkPubKey decodes the hex encoded spork public key from a coin conf.
+func ParseSporkPubKey(pubKeyHex string) (*btcec.PublicKey, error) {
+	raw, err := hex.DecodeString(pubKeyHex)
+	if err != nil {
+		return nil, err
+	}
+	return btcec.ParsePubKey(raw, btcec.S256())
+}
+
+// SporkTable keeps the newest verified value of every spork seen on the
+// network.
+type SporkTable struct {
+	pubKey       *btcec.PublicKey
+	magicMessage string
+	sporks       map[int32]wire.MsgSpork
+	mux          sync.RWMutex
+
+	Clock Clock
+}
+
+func NewSporkTable(pubKey *btcec.PublicKey, magicMessage string) *SporkTable {
+	return &SporkTable{
+		pubKey:       pubKey,
+		magicMessage: magicMessage,
+		sporks:       make(map[int32]wire.MsgSpork),
+	}
+}
+
+// Update verifies spork and stores it if it's newer than the known value. It
+// returns true when the table changed.
+func (table *SporkTable) Update(spork wire.MsgSpork) (bool, error) {
+	err := table.verify(&spork)
+	if err != nil {
+		return false, err
+	}
+
+	table.mux.Lock()
+	defer table.mux.Unlock()
+
+	if known, ok := table.sporks[spork.SporkID]; ok && known.TimeSigned >= spork.TimeSigned {
+		return false, nil
+	}
+
+	table.sporks[spork.SporkID] = spork
+	return true, nil
+}
+
+// verify accepts either signature scheme, the network switches between
+// them with SPORK_6_NEW_SIGS itself.
+func (table *SporkTable) verify(spork *wire.MsgSpork) error {
+	if table.pubKey == nil {
+		return errors.New("no spork public key configured")
+	}
+
+	hash := spork.SignatureHash()
+	if !signedBy(spork.Sig, table.pubKey, hash[:], signedMessageHash(table.magicMessage, spork.SignatureMessage())) {
+		return errors.New("spork is not signed by the spork key")
+	}
+	return nil
+}
+
+// Value returns the current value of a spork.
+func (table *SporkTable) Value(id int32) (int64, bool) {
+	table.mux.RLock()
+	defer table.mux.RUnlock()
+
+	spork, ok := table.sporks[id]
+	return spork.Value, ok
+}
+
+// IsActive reports whether a spork's activation time has passed. Unknown
+// sporks are inactive.
+func (table *SporkTable) IsActive(id int32) bool {
+	if table == nil {
+		return false
+	}
+
+	value, ok := table.Value(id)
+	if !ok {
+		return false
+	}
+	return value < clockOrDefault(table.Clock).Now().Unix()
+}
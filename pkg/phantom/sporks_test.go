@@ -0,0 +1,171 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+package phantom
+
+import (
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"github.com/btcsuite/btcd/btcec"
+	"testing"
+	"time"
+)
+
+// signedSpork returns a spork signed by key with scheme.
+func signedSpork(t *testing.T, key *btcec.PrivateKey, scheme SignatureScheme, id int32, value int64,
+	timeSigned int64) wire.MsgSpork {
+
+	spork := wire.MsgSpork{SporkID: id, Value: value, TimeSigned: timeSigned}
+
+	hash := signedMessageHash(testMagicMessage, spork.SignatureMessage())
+	if scheme == SignatureHash {
+		signatureHash := spork.SignatureHash()
+		hash = signatureHash[:]
+	}
+
+	sig, err := btcec.SignCompact(btcec.S256(), key, hash, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spork.Sig = sig
+	return spork
+}
+
+func TestSporkTableVerifies(t *testing.T) {
+	sporkKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, scheme := range []SignatureScheme{SignatureLegacy, SignatureHash} {
+		table := NewSporkTable(sporkKey.PubKey(), testMagicMessage)
+
+		changed, err := table.Update(signedSpork(t, sporkKey, scheme, SporkNewSigs, 1555555555, 1555000000))
+		if err != nil || !changed {
+			t.Errorf("%s: Update() = %t, %v", scheme, changed, err)
+		}
+		if value, ok := table.Value(SporkNewSigs); !ok || value != 1555555555 {
+			t.Errorf("%s: Value() = %d, %t", scheme, value, ok)
+		}
+
+		forged := signedSpork(t, otherKey, scheme, SporkRequireSentinelFlag, 0, 1555000000)
+		if _, err := table.Update(forged); err == nil {
+			t.Errorf("%s: a spork signed by another key was accepted", scheme)
+		}
+
+		tampered := signedSpork(t, sporkKey, scheme, SporkRequireSentinelFlag, 4070908800, 1555000000)
+		tampered.Value = 0
+		if _, err := table.Update(tampered); err == nil {
+			t.Errorf("%s: a spork with a changed value was accepted", scheme)
+		}
+
+		if _, ok := table.Value(SporkRequireSentinelFlag); ok {
+			t.Errorf("%s: a rejected spork was stored", scheme)
+		}
+	}
+
+	unkeyed := NewSporkTable(nil, testMagicMessage)
+	if _, err := unkeyed.Update(signedSpork(t, sporkKey, SignatureHash, SporkNewSigs, 0, 1555000000)); err == nil {
+		t.Error("a table without a spork key accepted a spork")
+	}
+}
+
+func TestSporkTableKeepsNewest(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := NewSporkTable(key.PubKey(), testMagicMessage)
+
+	if _, err := table.Update(signedSpork(t, key, SignatureHash, SporkNewSigs, 4070908800, 1555000000)); err != nil {
+		t.Fatal(err)
+	}
+
+	//replays and older values signed before the known one are ignored
+	for _, spork := range []wire.MsgSpork{
+		signedSpork(t, key, SignatureHash, SporkNewSigs, 0, 1555000000),
+		signedSpork(t, key, SignatureLegacy, SporkNewSigs, 0, 1554000000),
+	} {
+		changed, err := table.Update(spork)
+		if err != nil || changed {
+			t.Errorf("Update(signed at %d) = %t, %v", spork.TimeSigned, changed, err)
+		}
+	}
+	if value, _ := table.Value(SporkNewSigs); value != 4070908800 {
+		t.Errorf("Value() = %d after older sporks, want the newest", value)
+	}
+
+	changed, err := table.Update(signedSpork(t, key, SignatureHash, SporkNewSigs, 0, 1556000000))
+	if err != nil || !changed {
+		t.Errorf("Update(newer) = %t, %v", changed, err)
+	}
+	if value, _ := table.Value(SporkNewSigs); value != 0 {
+		t.Errorf("Value() = %d, want the newer 0", value)
+	}
+}
+
+func TestSporkTableIsActive(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1555555555, 0)
+	table := NewSporkTable(key.PubKey(), testMagicMessage)
+	table.Clock = fixedClock(now)
+
+	for _, spork := range []wire.MsgSpork{
+		signedSpork(t, key, SignatureHash, SporkNewSigs, now.Unix()-1, 1555000000),
+		signedSpork(t, key, SignatureHash, SporkRequireSentinelFlag, now.Unix(), 1555000000),
+		signedSpork(t, key, SignatureHash, SporkSuperblocksEnabled, 4070908800, 1555000000),
+	} {
+		if _, err := table.Update(spork); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//a spork is active once its activation time has passed
+	for id, want := range map[int32]bool{
+		SporkNewSigs:                      true,
+		SporkRequireSentinelFlag:          false,
+		SporkSuperblocksEnabled:           false,
+		SporkMasternodePaymentEnforcement: false,
+	} {
+		if active := table.IsActive(id); active != want {
+			t.Errorf("IsActive(%s) = %t, want %t", SporkName(id), active, want)
+		}
+	}
+
+	var unset *SporkTable
+	if unset.IsActive(SporkNewSigs) {
+		t.Error("a nil table reports an active spork")
+	}
+}
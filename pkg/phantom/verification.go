@@ -0,0 +1,192 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantom
+
+import (
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
+	"github.com/breakcrypto/phantom/pkg/logging"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"sync"
+)
+
+// Verification is an mnv received from a peer, replies go back to Peer.
+type Verification struct {
+	Message wire.MsgMNV
+	Peer    *PingerConnection
+}
+
+// VerificationCounts tallies the verifications that involved a masternode.
+// Relayed results are only seen, their signatures aren't checked.
+type VerificationCounts struct {
+	Requests int `json:"requests"`
+	Answered int `json:"answered"`
+	Seen     int `json:"seen"`
+	Failed   int `json:"failed"`
+}
+
+// VerificationMonitor watches masternode verifications (PoSe checks) for our
+// masternodes. Requests for them are answered when the block hash they
+// commit to can be looked up, relayed results are reported.
+type VerificationMonitor struct {
+	Broadcasts      *BroadcastStore
+	Scheduler       *PingScheduler
+	Bootstrapper    *Bootstrapper
+	MagicMessage    string
+	SignatureScheme SignatureScheme
+	Sporks          *SporkTable
+
+	counts map[string]*VerificationCounts
+	mux    sync.Mutex
+}
+
+// Handle processes a verification and returns the reply to send back, if
+// any.
+func (monitor *VerificationMonitor) Handle(verification Verification) *wire.MsgMNV {
+	mnv := verification.Message
+	log := verifyLog.With("peer", verification.Peer.IpAddress).With("addr", mnv.Addr.String())
+
+	target, byAddr := monitor.ours(&mnv)
+	if target == nil {
+		log.Debugf("Ignoring verification for another masternode.")
+		return nil
+	}
+	log = log.With("alias", target.Name)
+
+	if mnv.IsRequest() {
+		monitor.count(target.Name, func(counts *VerificationCounts) { counts.Requests++ })
+		log.Warnf("Verification request for block %d received.", mnv.BlockHeight)
+		return monitor.answer(target, mnv, log)
+	}
+
+	vin1 := OutpointKey(mnv.Vin1.PreviousOutPoint.Hash.String(), mnv.Vin1.PreviousOutPoint.Index)
+	if byAddr && vin1 != OutpointKey(target.OutpointHash, target.OutpointIndex) {
+		monitor.count(target.Name, func(counts *VerificationCounts) { counts.Failed++ })
+		log.Warnf("Another masternode (%s) answered a verification at our address, expect a PoSe ban score.", vin1)
+		return nil
+	}
+
+	monitor.count(target.Name, func(counts *VerificationCounts) { counts.Seen++ })
+	log.Infof("Verification result for block %d relayed.", mnv.BlockHeight)
+	return nil
+}
+
+// ours returns the masternode a verification targets, by address or by
+// either collateral, and whether it matched by address.
+func (monitor *VerificationMonitor) ours(mnv *wire.MsgMNV) (*MasternodePing, bool) {
+	if monitor.Scheduler == nil {
+		return nil, false
+	}
+
+	vin1 := OutpointKey(mnv.Vin1.PreviousOutPoint.Hash.String(), mnv.Vin1.PreviousOutPoint.Index)
+	vin2 := OutpointKey(mnv.Vin2.PreviousOutPoint.Hash.String(), mnv.Vin2.PreviousOutPoint.Index)
+
+	for _, masternode := range monitor.Scheduler.Masternodes() {
+		outpoint := OutpointKey(masternode.OutpointHash, masternode.OutpointIndex)
+
+		if monitor.Broadcasts != nil {
+			if mnb, ok := monitor.Broadcasts.Get(outpoint); ok && mnb.Addr == mnv.Addr {
+				masternode := masternode
+				return &masternode, true
+			}
+		}
+
+		if outpoint == vin1 || outpoint == vin2 {
+			masternode := masternode
+			return &masternode, false
+		}
+	}
+	return nil, false
+}
+
+// answer signs a request with the masternode key, which needs the hash of
+// the block the request was made at.
+func (monitor *VerificationMonitor) answer(target *MasternodePing, mnv wire.MsgMNV, log *logging.Logger) *wire.MsgMNV {
+	if monitor.Bootstrapper == nil {
+		log.Warnf("Unable to answer without a block explorer (-bootstrap_url).")
+		return nil
+	}
+
+	blockHash, err := monitor.Bootstrapper.LoadBlockHashAt(int(mnv.BlockHeight))
+	if err != nil {
+		log.Warnf("Unable to answer, the block hash isn't available: %s", err)
+		return nil
+	}
+
+	wif, err := btcutil.DecodeWIF(target.PrivateKey)
+	if err != nil {
+		log.Errorf("Unable to decode the masternode private key: %s", err)
+		return nil
+	}
+
+	var hash []byte
+	if monitor.SignatureScheme == SignatureHash || monitor.Sporks.IsActive(SporkNewSigs) {
+		signatureHash := mnv.SignatureHash1(blockHash)
+		hash = signatureHash[:]
+	} else {
+		hash = signedMessageHash(monitor.MagicMessage, mnv.SignatureMessage1(blockHash))
+	}
+
+	mnv.Sig1, err = btcec.SignCompact(btcec.S256(), wif.PrivKey, hash, false)
+	if err != nil {
+		log.Errorf("Unable to sign the verification: %s", err)
+		return nil
+	}
+
+	monitor.count(target.Name, func(counts *VerificationCounts) { counts.Answered++ })
+	log.Infof("Answering the verification request.")
+	return &mnv
+}
+
+func (monitor *VerificationMonitor) count(alias string, update func(*VerificationCounts)) {
+	monitor.mux.Lock()
+	defer monitor.mux.Unlock()
+
+	if monitor.counts == nil {
+		monitor.counts = make(map[string]*VerificationCounts)
+	}
+	if _, ok := monitor.counts[alias]; !ok {
+		monitor.counts[alias] = &VerificationCounts{}
+	}
+	update(monitor.counts[alias])
+}
+
+// Counts returns the verification tallies of every masternode seen so far.
+func (monitor *VerificationMonitor) Counts() map[string]VerificationCounts {
+	monitor.mux.Lock()
+	defer monitor.mux.Unlock()
+
+	counts := make(map[string]VerificationCounts, len(monitor.counts))
+	for alias, count := range monitor.counts {
+		counts[alias] = *count
+	}
+	return counts
+}
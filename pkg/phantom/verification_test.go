@@ -0,0 +1,163 @@
+/**
+*    Copyright (C) 2019-present C2CV Holdings, LLC.
+*
+*    This program is free software: you can redistribute it and/or modify
+*    it under the terms of the Server Side Public License, version 1,
+*    as published by C2CV Holdings, LLC.
+*
+*    This program is distributed in the hope that it will be useful,
+*    but WITHOUT ANY WARRANTY; without even the implied warranty of
+*    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*    Server Side Public License for more details.
+*
+*    You should have received a copy of the Server Side Public License
+*    along with this program. If not, see
+*    <http://www.mongodb.com/licensing/server-side-public-license>.
+*
+*    As a special exception, the copyright holders give permission to link the
+*    code of portions of this program with the OpenSSL library under certain
+*    conditions as described in each individual source file and distribute
+*    linked combinations including the program with the OpenSSL library. You
+*    must comply with the Server Side Public License in all respects for
+*    all of the code used other than as permitted herein. If you modify file(s)
+*    with this exception, you may extend this exception to your version of the
+*    file(s), but you are not obligated to do so. If you do not wish to do so,
+*    delete this exception statement from your version. If you delete this
+*    exception statement from all source files in the program, then also delete
+*    it in the license file.
+*/
+
+
+package phantom
+
+import (
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"github.com/breakcrypto/phantom/pkg/socket/wire"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testCollateral = "2bcd3c84c84f87eaa86e4e56834c92927a07f9e18718810b92e0d0324456a67c"
+
+// testScheduler schedules one masternode, mn1, for the key. The returned
+// function removes its masternode file.
+func testScheduler(t *testing.T, key *btcec.PrivateKey) (*PingScheduler, func()) {
+	wif, err := btcutil.NewWIF(key, &chaincfg.MainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "phantom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	path := filepath.Join(dir, "masternode.txt")
+	line := fmt.Sprintf("mn1 45.50.22.125:9999 %s %s 1 1555555555\n", wif.String(), testCollateral)
+	if err := ioutil.WriteFile(path, []byte(line), 0600); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	hashes := NewQueue(12)
+	hashes.Push(&chainhash.Hash{1})
+
+	scheduler := &PingScheduler{
+		MasternodeConf: path,
+		HashQueue:      hashes,
+		MagicMessage:   testMagicMessage,
+		PingInterval:   10 * time.Minute,
+		SigTimeOffset:  3 * time.Second,
+		Clock:          fixedClock(time.Unix(1555555555, 0)),
+	}
+	if err := scheduler.Load(); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return scheduler, cleanup
+}
+
+func testVerification(index uint32, answered bool) Verification {
+	collateral, _ := chainhash.NewHashFromStr(testCollateral)
+	mnv := wire.MsgMNV{
+		Vin1:        *wire.NewTxIn(wire.NewOutPoint(collateral, index), nil, nil),
+		Vin2:        *wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{2}, 0), nil, nil),
+		Nonce:       42,
+		BlockHeight: 1000,
+	}
+	copy(mnv.Addr.IpAddress[:], net.ParseIP("45.50.22.125").To16())
+	mnv.Addr.Port = 9999
+	if answered {
+		mnv.Sig1 = []byte{1}
+		mnv.Sig2 = []byte{2}
+	}
+	return Verification{Message: mnv, Peer: &PingerConnection{IpAddress: "127.0.0.1"}}
+}
+
+func TestVerificationMonitorAnswers(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blockHash := chainhash.Hash{7}
+	explorer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, blockHash.String())
+	}))
+	defer explorer.Close()
+
+	scheduler, cleanup := testScheduler(t, key)
+	defer cleanup()
+
+	monitor := &VerificationMonitor{
+		Scheduler:    scheduler,
+		Bootstrapper: &Bootstrapper{BaseURL: explorer.URL},
+		MagicMessage: testMagicMessage,
+	}
+
+	reply := monitor.Handle(testVerification(1, false))
+	if reply == nil {
+		t.Fatal("the request wasn't answered")
+	}
+
+	message := "45.50.22.125:999942" + blockHash.String()
+	if !signedBy(reply.Sig1, key.PubKey(), signedMessageHash(testMagicMessage, message)) {
+		t.Errorf("Sig1 doesn't sign %q with the masternode key", message)
+	}
+
+	counts := monitor.Counts()["mn1"]
+	if counts.Requests != 1 || counts.Answered != 1 {
+		t.Errorf("counts = %+v, want one request answered", counts)
+	}
+}
+
+func TestVerificationMonitorRelayedResults(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler, cleanup := testScheduler(t, key)
+	defer cleanup()
+
+	monitor := &VerificationMonitor{Scheduler: scheduler}
+
+	if reply := monitor.Handle(testVerification(1, true)); reply != nil {
+		t.Errorf("replied to a relayed result")
+	}
+
+	counts := monitor.Counts()["mn1"]
+	if counts.Seen != 1 || counts.Failed != 0 {
+		t.Errorf("counts = %+v, want the result seen", counts)
+	}
+}
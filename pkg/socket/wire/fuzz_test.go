@@ -0,0 +1,169 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package wire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// FuzzReadMessageN feeds arbitrary messages to the reader under every
+// message profile. Whatever decodes must encode again without panicking.
+func FuzzReadMessageN(f *testing.F) {
+	for _, fixture := range wireFixtures(f) {
+		var buf bytes.Buffer
+		_, err := WriteMessageN(&buf, fixture.msg, 70208, MainNet)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf.Bytes())
+	}
+
+	profiles := []*MessageProfile{nil}
+	for name := range MessageProfiles {
+		profiles = append(profiles, mustProfile(f, name))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, profile := range profiles {
+			_, msg, _, err := ReadMessageWithProfileN(bytes.NewReader(data), 70208, MainNet, profile)
+			if err != nil {
+				continue
+			}
+
+			var buf bytes.Buffer
+			WriteMessageN(&buf, msg, 70208, MainNet)
+		}
+
+		ReadMessageN(bytes.NewReader(data), 70208, MainNet)
+	})
+}
+
+// fuzzProfiles returns nil, the legacy layout, and every message profile.
+func fuzzProfiles(f *testing.F) []*MessageProfile {
+	profiles := []*MessageProfile{nil}
+	for name := range MessageProfiles {
+		profiles = append(profiles, mustProfile(f, name))
+	}
+	return profiles
+}
+
+// fuzzDecode decodes data straight into the messages newMsg returns, past
+// the envelope checksum that random input hardly ever gets by. Whatever
+// decodes has to encode to bytes that decode to the same encoding again.
+func fuzzDecode(t *testing.T, data []byte, newMsg func() Message) {
+	msg := newMsg()
+	if msg.BtcDecode(bytes.NewReader(data), 70208, BaseEncoding) != nil {
+		return
+	}
+
+	var encoded bytes.Buffer
+	if err := msg.BtcEncode(&encoded, 70208, BaseEncoding); err != nil {
+		t.Fatalf("%s: BtcEncode of a decoded message: %s", msg.Command(), err)
+	}
+
+	again := newMsg()
+	if err := again.BtcDecode(bytes.NewReader(encoded.Bytes()), 70208, BaseEncoding); err != nil {
+		t.Fatalf("%s: decoding %x: %s", msg.Command(), encoded.Bytes(), err)
+	}
+
+	var reencoded bytes.Buffer
+	again.BtcEncode(&reencoded, 70208, BaseEncoding)
+	if !bytes.Equal(reencoded.Bytes(), encoded.Bytes()) {
+		t.Fatalf("%s: encoding changed\n got %x\nwant %x", msg.Command(), reencoded.Bytes(), encoded.Bytes())
+	}
+}
+
+// fuzzProfileDecode seeds with the fixtures for seed's command and seed
+// itself, then decodes every input under every profile.
+func fuzzProfileDecode(f *testing.F, seed Message, newMsg func(profile *MessageProfile) Message) {
+	for _, fixture := range wireFixtures(f) {
+		if fixture.msg.Command() == seed.Command() {
+			f.Add(fixture.payload)
+		}
+	}
+	var payload bytes.Buffer
+	if err := seed.BtcEncode(&payload, 70208, BaseEncoding); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(payload.Bytes())
+
+	profiles := fuzzProfiles(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, profile := range profiles {
+			fuzzDecode(t, data, func() Message { return newMsg(profile) })
+		}
+	})
+}
+
+// testCollateral is a TxIn spending the output a seed message references.
+func testCollateral() TxIn {
+	return *NewTxIn(&OutPoint{Hash: chainhash.Hash{1}, Index: 1}, nil, nil)
+}
+
+func FuzzMNPDecode(f *testing.F) {
+	fuzzProfileDecode(f, &MsgMNP{Vin: testCollateral(), SigTime: 1555652579, VchSig: []byte{1}},
+		func(profile *MessageProfile) Message { return &MsgMNP{Profile: profile} })
+}
+
+func FuzzMNBDecode(f *testing.F) {
+	fuzzProfileDecode(f, &MsgMNB{Vin: testCollateral(), LastPing: MsgMNP{Vin: testCollateral()}},
+		func(profile *MessageProfile) Message { return &MsgMNB{Profile: profile} })
+}
+
+func FuzzDSEGDecode(f *testing.F) {
+	fuzzProfileDecode(f, &MsgDSEG{Vin: testCollateral()},
+		func(profile *MessageProfile) Message { return &MsgDSEG{Profile: profile} })
+}
+
+func FuzzMNVDecode(f *testing.F) {
+	seed := &MsgMNV{Vin1: testCollateral(), Vin2: testCollateral(), Nonce: 123456, BlockHeight: 1000000,
+		Sig1: []byte{1}, Sig2: []byte{2}}
+	fuzzProfileDecode(f, seed, func(profile *MessageProfile) Message { return &MsgMNV{Profile: profile} })
+}
+
+func FuzzMNWDecode(f *testing.F) {
+	seed := &MsgMNW{Vin: testCollateral(), BlockHeight: 1000000, Payee: []byte{0x76, 0xa9}, Sig: []byte{1}}
+	fuzzProfileDecode(f, seed, func(profile *MessageProfile) Message { return &MsgMNW{Profile: profile} })
+}
+
+func FuzzGovObjDecode(f *testing.F) {
+	seed := &MsgGovObj{Revision: 1, Time: 1555652579, Data: "7b7d", ObjectType: 1, Vin: testCollateral(),
+		Sig: []byte{1}}
+	fuzzProfileDecode(f, seed, func(profile *MessageProfile) Message { return &MsgGovObj{Profile: profile} })
+}
+
+func FuzzGovObjVoteDecode(f *testing.F) {
+	seed := &MsgGovObjVote{Vin: testCollateral(), Outcome: 1, Signal: 1, Time: 1555652579, Sig: []byte{1}}
+	fuzzProfileDecode(f, seed, func(profile *MessageProfile) Message { return &MsgGovObjVote{Profile: profile} })
+}
+
+// fuzzMessageDecode is fuzzProfileDecode for messages without a profile.
+func fuzzMessageDecode(f *testing.F, seed Message, newMsg func() Message) {
+	var payload bytes.Buffer
+	if err := seed.BtcEncode(&payload, 70208, BaseEncoding); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(payload.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzDecode(t, data, newMsg)
+	})
+}
+
+func FuzzSporkDecode(f *testing.F) {
+	seed := &MsgSpork{SporkID: 10001, Value: 1, TimeSigned: 1555652579, Sig: []byte{1}}
+	fuzzMessageDecode(f, seed, func() Message { return NewMsgSpork() })
+}
+
+func FuzzGovSyncDecode(f *testing.F) {
+	fuzzMessageDecode(f, &MsgGovSync{Filter: []byte{1}}, func() Message { return &MsgGovSync{} })
+}
@@ -36,6 +36,15 @@ const (
 	InvTypeWitnessBlock         InvType = InvTypeBlock | InvWitnessFlag
 	InvTypeWitnessTx            InvType = InvTypeTx | InvWitnessFlag
 	InvTypeFilteredWitnessBlock InvType = InvTypeFilteredBlock | InvWitnessFlag
+
+	// Masternode inventory types shared by the Dash and PIVX forks.
+	InvTypeSpork              InvType = 6
+	InvTypeMasternodeWinner   InvType = 7
+	InvTypeMasternodeAnnounce InvType = 14
+	InvTypeMasternodePing     InvType = 15
+	InvTypeGovernanceObject   InvType = 17
+	InvTypeGovernanceVote     InvType = 18
+	InvTypeMasternodeVerify   InvType = 19
 )
 
 // Map of service flags back to their constant names for pretty printing.
@@ -47,6 +56,13 @@ var ivStrings = map[InvType]string{
 	InvTypeWitnessBlock:         "MSG_WITNESS_BLOCK",
 	InvTypeWitnessTx:            "MSG_WITNESS_TX",
 	InvTypeFilteredWitnessBlock: "MSG_FILTERED_WITNESS_BLOCK",
+	InvTypeSpork:                "MSG_SPORK",
+	InvTypeMasternodeWinner:     "MSG_MASTERNODE_PAYMENT_VOTE",
+	InvTypeMasternodeAnnounce:   "MSG_MASTERNODE_ANNOUNCE",
+	InvTypeMasternodePing:       "MSG_MASTERNODE_PING",
+	InvTypeGovernanceObject:     "MSG_GOVERNANCE_OBJECT",
+	InvTypeGovernanceVote:       "MSG_GOVERNANCE_OBJECT_VOTE",
+	InvTypeMasternodeVerify:     "MSG_MASTERNODE_VERIFY",
 }
 
 // String returns the InvType in human-readable form.
@@ -60,7 +60,13 @@ const (
 	CmdMNP			= "mnp"
 	CmdMNB			= "mnb"
 	CmdDESG			= "dseg"
+	CmdMNW			= "mnw"
+	CmdMNV			= "mnv"
 	CmdGovObj		= "govobj"
+	CmdGovObjVote	= "govobjvote"
+	CmdGovSync		= "govsync"
+	CmdSpork		= "spork"
+	CmdGetSporks	= "getsporks"
 )
 
 // MessageEncoding represents the wire message encoding format to be used.
@@ -93,8 +99,8 @@ type Message interface {
 }
 
 // makeEmptyMessage creates a message of the appropriate concrete type based
-// on the command.
-func makeEmptyMessage(command string) (Message, error) {
+// on the command.  The masternode messages are decoded with profile.
+func makeEmptyMessage(command string, profile *MessageProfile) (Message, error) {
 	var msg Message
 	switch command {
 	case CmdVersion:
@@ -112,6 +118,12 @@ func makeEmptyMessage(command string) (Message, error) {
 	case CmdGetBlocks:
 		msg = &MsgGetBlocks{}
 
+	case CmdGetHeaders:
+		msg = &MsgGetHeaders{}
+
+	case CmdHeaders:
+		msg = &MsgHeaders{}
+
 	case CmdInv:
 		msg = &MsgInv{}
 
@@ -128,10 +140,37 @@ func makeEmptyMessage(command string) (Message, error) {
 		msg = &MsgPong{}
 
 	case CmdMNP:
-		msg = &MsgMNP{}
+		msg = &MsgMNP{Profile: profile}
 
 	case CmdMNB:
-		msg = &MsgMNB{}
+		msg = &MsgMNB{Profile: profile}
+
+	case CmdDESG:
+		msg = &MsgDSEG{Profile: profile}
+
+	case CmdMNW:
+		msg = &MsgMNW{Profile: profile}
+
+	case CmdMNV:
+		msg = &MsgMNV{Profile: profile}
+
+	case CmdSpork:
+		msg = &MsgSpork{}
+
+	case CmdGetSporks:
+		msg = &MsgGetSporks{}
+
+	case CmdGovObj:
+		msg = &MsgGovObj{Profile: profile}
+
+	case CmdGovObjVote:
+		msg = &MsgGovObjVote{Profile: profile}
+
+	case CmdGovSync:
+		msg = &MsgGovSync{}
+
+	case CmdReject:
+		msg = &MsgReject{}
 
 	default:
 		return nil, fmt.Errorf("unhandled command [%s]", command)
@@ -166,7 +205,7 @@ func readMessageHeader(r io.Reader) (int, *messageHeader, error) {
 	readElements(hr, &hdr.magic, &command, &hdr.length, &hdr.checksum)
 
 	// Strip trailing zeros from command string.
-	hdr.command = string(bytes.TrimRight(command[:], string(0)))
+	hdr.command = string(bytes.TrimRight(command[:], "\x00"))
 
 	return n, &hdr, nil
 }
@@ -288,6 +327,18 @@ func WriteMessageWithEncodingN(w io.Writer, msg Message, pver uint32,
 // decoding wire messages.
 func ReadMessageWithEncodingN(r io.Reader, pver uint32, btcnet BitcoinNet,
 	enc MessageEncoding) (int, Message, []byte, error) {
+	return readMessage(r, pver, btcnet, enc, nil)
+}
+
+// ReadMessageWithProfileN is the same as ReadMessageN except that masternode
+// messages are decoded using the layout described by profile.
+func ReadMessageWithProfileN(r io.Reader, pver uint32, btcnet BitcoinNet,
+	profile *MessageProfile) (int, Message, []byte, error) {
+	return readMessage(r, pver, btcnet, BaseEncoding, profile)
+}
+
+func readMessage(r io.Reader, pver uint32, btcnet BitcoinNet,
+	enc MessageEncoding, profile *MessageProfile) (int, Message, []byte, error) {
 
 	totalBytes := 0
 	n, hdr, err := readMessageHeader(r)
@@ -321,7 +372,7 @@ func ReadMessageWithEncodingN(r io.Reader, pver uint32, btcnet BitcoinNet,
 	}
 
 	// Create struct of appropriate message type based on the command.
-	msg, err := makeEmptyMessage(command)
+	msg, err := makeEmptyMessage(command, profile)
 	if err != nil {
 		discardInput(r, hdr.length)
 		return totalBytes, nil, nil, messageError("ReadMessage",
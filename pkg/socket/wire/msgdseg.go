@@ -4,15 +4,12 @@ import (
 "io"
 )
 
-// MsgPong implements the Message interface and represents a bitcoin pong
-// message which is used primarily to confirm that a connection is still valid
-// in response to a bitcoin ping message (MsgPing).
-//
-// This message was not added until protocol versions AFTER BIP0031Version.
+// MsgDSEG implements the Message interface and represents a masternode list
+// request.  An empty vin (null outpoint) asks for the whole list, otherwise
+// only the entry for the given collateral is requested.
 type MsgDSEG struct {
-	// Unique value associated with message that is used to identify
-	// specific ping message.
 	Vin TxIn
+	Profile *MessageProfile
 }
 
 // BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
@@ -27,12 +24,7 @@ func (msg *MsgDSEG) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) err
 	//}
 
 	//read the tx
-	err := readTxIn(r, pver, 0, &msg.Vin)
-	if err != nil {
-		return err
-	}
-
-	return err
+	return readCollateral(r, pver, msg.Profile, &msg.Vin)
 }
 
 // BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
@@ -40,7 +32,7 @@ func (msg *MsgDSEG) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) err
 func (msg *MsgDSEG) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
 	//msg.Vin.PreviousOutPoint.Index = MaxTxInSequenceNum
 	//msg.Vin.Sequence = MaxTxInSequenceNum
-	return writeTxIn(w, pver, 0, &msg.Vin)
+	return writeCollateral(w, pver, msg.Profile, &msg.Vin)
 }
 
 // Command returns the protocol command string for the message.  This is part
@@ -52,14 +44,12 @@ func (msg *MsgDSEG) Command() string {
 // MaxPayloadLength returns the maximum length the payload can be for the
 // receiver.  This is part of the Message interface implementation.
 func (msg *MsgDSEG) MaxPayloadLength(pver uint32) uint32 {
-	//vin + addr + pubKeyCollateralAddress + pubKeyMasternode + sig +
-	//sigTime + nProtovolVersion + 	MNP + nLastDsq
-	//ping = vin + blockhash + sigTime + vchSig
-	return 41+18+65+65+73+8+4+(41+32+8+73)+8
+	//vin = outpoint + scriptSig + sequence, the scriptSig is always empty
+	return 36+1+4
 }
 
-// NewMsgPong returns a new bitcoin pong message that conforms to the Message
-// interface.  See MsgPong for details.
+// NewMsgDSEG returns a new masternode list request message that conforms to
+// the Message interface.  See MsgDSEG for details.
 func NewMsgDSEG() *MsgDSEG {
 	return &MsgDSEG{}
 }
@@ -0,0 +1,46 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"testing"
+)
+
+// nullOutpoint is the outpoint a dseg for the whole list carries.
+const nullOutpoint = "0000000000000000000000000000000000000000000000000000000000000000" + "ffffffff"
+
+// dsegFixtures are dseg payloads in the layout of each profile.
+var dsegFixtures = []struct {
+	profile string
+	fields  []string
+}{
+	{"pivx", []string{nullOutpoint, fixtureScriptSig, fixtureSequence}},
+	{"dash-12.1", []string{fixtureCollateral, fixtureScriptSig, fixtureSequence}},
+	{"dash-12.2", []string{nullOutpoint}},
+}
+
+func TestDSEGWire(t *testing.T) {
+	for _, fixture := range dsegFixtures {
+		payload := fixtureBytes(t, fixture.fields...)
+
+		msg := MsgDSEG{Profile: mustProfile(t, fixture.profile)}
+		err := msg.BtcDecode(bytes.NewReader(payload), 0, BaseEncoding)
+		if err != nil {
+			t.Errorf("%s: BtcDecode: %s", fixture.profile, err)
+			continue
+		}
+
+		var buf bytes.Buffer
+		err = msg.BtcEncode(&buf, 0, BaseEncoding)
+		if err != nil {
+			t.Errorf("%s: BtcEncode: %s", fixture.profile, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), payload) {
+			t.Errorf("%s: round trip\n got %x\nwant %x", fixture.profile, buf.Bytes(), payload)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// Governance fixture fields, in wire order and byte order.
+const (
+	fixtureProposal = "d1a0e9f8c7b6a5948372615f4e3d2c1b0a99887766554433221100ffeeddccbb"
+	fixtureVoteTime = "a3a3b95c00000000" //1555669923
+)
+
+// fixtureProposalData is the hex JSON body carried by the govobj fixture.
+var fixtureProposalData = hex.EncodeToString([]byte(`{"name":"fund-the-devs","type":1}`))
+
+// testRoundTrip decodes payload into msg, then checks it encodes back to the
+// same bytes and that every truncation of it fails to decode.
+func testRoundTrip(t *testing.T, name string, msg Message, pver uint32, payload []byte, fresh func() Message) {
+	if err := msg.BtcDecode(bytes.NewReader(payload), pver, BaseEncoding); err != nil {
+		t.Fatalf("%s: %s", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, pver, BaseEncoding); err != nil {
+		t.Fatalf("%s: %s", name, err)
+	}
+	if !bytes.Equal(buf.Bytes(), payload) {
+		t.Errorf("%s: round trip\n got %x\nwant %x", name, buf.Bytes(), payload)
+	}
+	if uint32(len(payload)) > msg.MaxPayloadLength(pver) {
+		t.Errorf("%s: a %d byte payload exceeds MaxPayloadLength()", name, len(payload))
+	}
+
+	for i := 0; i < len(payload); i++ {
+		if fresh().BtcDecode(bytes.NewReader(payload[:i]), pver, BaseEncoding) == nil {
+			t.Errorf("%s: a payload truncated to %d bytes decoded", name, i)
+			break
+		}
+	}
+}
+
+func TestGovObjVoteWire(t *testing.T) {
+	var hash chainhash.Hash
+	for _, fixture := range []struct {
+		profile string
+		fields  []string
+	}{
+		{"dash-12.1", []string{fixtureCollateral, fixtureScriptSig, fixtureSequence, fixtureProposal, "02000000",
+			"01000000", fixtureVoteTime, fixtureSig}},
+		//Dash 12.2 references the masternode by COutPoint
+		{"dash-12.2", []string{fixtureCollateral, fixtureProposal, "02000000", "01000000", fixtureVoteTime,
+			fixtureSig}},
+	} {
+		profile := mustProfile(t, fixture.profile)
+		msg := &MsgGovObjVote{Profile: profile}
+		testRoundTrip(t, fixture.profile, msg, 70208, fixtureBytes(t, fixture.fields...),
+			func() Message { return &MsgGovObjVote{Profile: profile} })
+
+		if msg.Vin.PreviousOutPoint.String() != "2bcd3c84c84f87eaa86e4e56834c92927a07f9e18718810b92e0d0324456a67c:1" ||
+			msg.Outcome != VoteOutcomeNo || msg.Signal != VoteSignalFunding || msg.Time != 1555669923 ||
+			len(msg.Sig) != 65 {
+			t.Errorf("%s: decoded %+v", fixture.profile, msg)
+		}
+
+		want := "2bcd3c84c84f87eaa86e4e56834c92927a07f9e18718810b92e0d0324456a67c-1|" +
+			"bbccddeeff001122334455667788990a1b2c3d4e5f61728394a5b6c7f8e9a0d1|1|2|1555669923"
+		if got := msg.SignatureMessage(); got != want {
+			t.Errorf("%s: SignatureMessage() = %q", fixture.profile, got)
+		}
+
+		//the network hash doesn't depend on the layout
+		if hash == (chainhash.Hash{}) {
+			hash = msg.GetHash()
+		} else if msg.GetHash() != hash {
+			t.Errorf("%s: GetHash() = %s, want %s", fixture.profile, msg.GetHash(), hash)
+		}
+
+		signatureHash := msg.SignatureHash()
+		msg.Sig = nil
+		if msg.SignatureHash() != signatureHash || msg.GetHash() != hash {
+			t.Errorf("%s: the hashes depend on the signature", fixture.profile)
+		}
+		msg.Outcome = VoteOutcomeYes
+		if msg.SignatureHash() == signatureHash || msg.GetHash() == hash {
+			t.Errorf("%s: the hashes don't cover the outcome", fixture.profile)
+		}
+	}
+}
+
+func TestGovObjWire(t *testing.T) {
+	fields := []string{fixtureProposal, "01000000", fixtureVoteTime, fixtureBlockHash,
+		hex.EncodeToString([]byte{byte(len(fixtureProposalData))}), hex.EncodeToString([]byte(fixtureProposalData)),
+		"01000000", fixtureCollateral, fixtureScriptSig, fixtureSequence, fixtureSig}
+
+	msg := &MsgGovObj{}
+	testRoundTrip(t, "govobj", msg, 70208, fixtureBytes(t, fields...), func() Message { return &MsgGovObj{} })
+
+	if msg.Revision != 1 || msg.Time != 1555669923 || msg.Data != fixtureProposalData ||
+		msg.ObjectType != GovObjTypeProposal || len(msg.Sig) != 65 ||
+		msg.CollateralHash.String() != "00000ffd590b1485b3caadc19b22e6379c733355108f107a430458cdf3407ab6" {
+		t.Errorf("decoded %+v", msg)
+	}
+
+	//the network hash doesn't depend on the layout or the collateral
+	hash := msg.GetHash()
+	outpointOnly := *msg
+	outpointOnly.Profile = mustProfile(t, "dash-12.2")
+	outpointOnly.CollateralHash = chainhash.Hash{}
+	if outpointOnly.GetHash() != hash {
+		t.Errorf("GetHash() = %s, want %s", outpointOnly.GetHash(), hash)
+	}
+	outpointOnly.Data = ""
+	if outpointOnly.GetHash() == hash {
+		t.Error("GetHash() doesn't cover the data")
+	}
+
+	var oversized bytes.Buffer
+	msg.Data = string(bytes.Repeat([]byte{'0'}, MaxGovObjDataSize+2))
+	if err := msg.BtcEncode(&oversized, 70208, BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	if (&MsgGovObj{}).BtcDecode(&oversized, 70208, BaseEncoding) == nil {
+		t.Error("a governance object with oversized data decoded")
+	}
+}
+
+func TestGovSyncWire(t *testing.T) {
+	//older peers read the hash alone
+	msg := &MsgGovSync{}
+	testRoundTrip(t, "govsync", msg, GovernanceFilterVersion-1, fixtureBytes(t, fixtureProposal),
+		func() Message { return &MsgGovSync{} })
+	if msg.Hash.String() != "bbccddeeff001122334455667788990a1b2c3d4e5f61728394a5b6c7f8e9a0d1" {
+		t.Errorf("decoded hash %s", msg.Hash)
+	}
+
+	//later ones an empty bloom filter after it
+	payload := fixtureBytes(t, fixtureProposal, "00", "00000000", "00000000", "00")
+	msg = &MsgGovSync{}
+	testRoundTrip(t, "filtered govsync", msg, GovernanceFilterVersion, payload,
+		func() Message { return &MsgGovSync{} })
+
+	var buf bytes.Buffer
+	if err := (&MsgGovSync{}).BtcEncode(&buf, GovernanceFilterVersion, BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 32+1+4+4+1 {
+		t.Errorf("a request for every object is %d bytes, want %d", buf.Len(), 32+1+4+4+1)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// MaxGovObjDataSize is the largest hex encoded payload a governance object
+// may carry.
+const MaxGovObjDataSize = 16 * 1024
+
+// maxGovSigLength is a generous upper bound for the compact signatures
+// carried by governance objects and votes.
+const maxGovSigLength = 80
+
+// Governance object types.
+const (
+	GovObjTypeProposal int32 = 1
+	GovObjTypeTrigger  int32 = 2
+)
+
+// MsgGovObj implements the Message interface and represents a governance
+// object such as a budget proposal or a superblock trigger.  Data holds the
+// hex encoded JSON body of the object.
+type MsgGovObj struct {
+	HashParent     chainhash.Hash
+	Revision       int32
+	Time           int64
+	CollateralHash chainhash.Hash
+	Data           string
+	ObjectType     int32
+	Vin            TxIn
+	Sig            []byte
+	Profile        *MessageProfile
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGovObj) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	_, err := io.ReadFull(r, msg.HashParent[:])
+	if err != nil {
+		return err
+	}
+
+	err = readElements(r, &msg.Revision, &msg.Time)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.ReadFull(r, msg.CollateralHash[:])
+	if err != nil {
+		return err
+	}
+
+	msg.Data, err = ReadVarString(r, pver)
+	if err != nil {
+		return err
+	}
+	if len(msg.Data) > MaxGovObjDataSize {
+		return messageError("MsgGovObj.BtcDecode", "governance object data is too large")
+	}
+
+	err = readElement(r, &msg.ObjectType)
+	if err != nil {
+		return err
+	}
+
+	err = readCollateral(r, pver, msg.Profile, &msg.Vin)
+	if err != nil {
+		return err
+	}
+
+	msg.Sig, err = ReadVarBytes(r, pver, maxGovSigLength, "Sig")
+	return err
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGovObj) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	_, err := w.Write(msg.HashParent[:])
+	if err != nil {
+		return err
+	}
+
+	err = writeElements(w, msg.Revision, msg.Time)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(msg.CollateralHash[:])
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarString(w, pver, msg.Data)
+	if err != nil {
+		return err
+	}
+
+	err = writeElement(w, msg.ObjectType)
+	if err != nil {
+		return err
+	}
+
+	err = writeCollateral(w, pver, msg.Profile, &msg.Vin)
+	if err != nil {
+		return err
+	}
+
+	return WriteVarBytes(w, pver, msg.Sig)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGovObj) Command() string {
+	return CmdGovObj
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGovObj) MaxPayloadLength(pver uint32) uint32 {
+	//parent + revision + time + collateral + data + type + vin + sig
+	return 32 + 4 + 8 + 32 + MaxVarIntPayload + MaxGovObjDataSize + 4 + 41 +
+		MaxVarIntPayload + maxGovSigLength
+}
+
+// GetHash returns the hash the network identifies the object by.  The
+// masternode is always hashed in the CTxIn layout, whatever the profile.
+func (msg *MsgGovObj) GetHash() chainhash.Hash {
+	var b bytes.Buffer
+
+	b.Write(msg.HashParent[:])
+	writeElements(&b, msg.Revision, msg.Time)
+	WriteVarString(&b, 0, msg.Data)
+	writeOutPoint(&b, 0, 0, &msg.Vin.PreviousOutPoint)
+	WriteVarBytes(&b, 0, nil)
+	writeElement(&b, MaxTxInSequenceNum)
+	WriteVarBytes(&b, 0, msg.Sig)
+
+	return chainhash.DoubleHashH(b.Bytes())
+}
+
+// NewMsgGovObj returns a new governance object message that conforms to the
+// Message interface.  See MsgGovObj for details.
+func NewMsgGovObj() *MsgGovObj {
+	return &MsgGovObj{}
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// Governance vote outcomes.
+const (
+	VoteOutcomeNone    int32 = 0
+	VoteOutcomeYes     int32 = 1
+	VoteOutcomeNo      int32 = 2
+	VoteOutcomeAbstain int32 = 3
+)
+
+// Governance vote signals, funding is the one cast on proposals.
+const (
+	VoteSignalNone    int32 = 0
+	VoteSignalFunding int32 = 1
+	VoteSignalValid   int32 = 2
+	VoteSignalDelete  int32 = 3
+)
+
+// MsgGovObjVote implements the Message interface and represents a
+// masternode's vote on a governance object.
+type MsgGovObjVote struct {
+	Vin        TxIn
+	ParentHash chainhash.Hash
+	Outcome    int32
+	Signal     int32
+	Time       int64
+	Sig        []byte
+	Profile    *MessageProfile
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGovObjVote) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	err := readCollateral(r, pver, msg.Profile, &msg.Vin)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.ReadFull(r, msg.ParentHash[:])
+	if err != nil {
+		return err
+	}
+
+	err = readElements(r, &msg.Outcome, &msg.Signal, &msg.Time)
+	if err != nil {
+		return err
+	}
+
+	msg.Sig, err = ReadVarBytes(r, pver, maxGovSigLength, "Sig")
+	return err
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGovObjVote) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	err := writeCollateral(w, pver, msg.Profile, &msg.Vin)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(msg.ParentHash[:])
+	if err != nil {
+		return err
+	}
+
+	err = writeElements(w, msg.Outcome, msg.Signal, msg.Time)
+	if err != nil {
+		return err
+	}
+
+	return WriteVarBytes(w, pver, msg.Sig)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGovObjVote) Command() string {
+	return CmdGovObjVote
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGovObjVote) MaxPayloadLength(pver uint32) uint32 {
+	//vin + parent + outcome + signal + time + sig
+	return 41 + 32 + 4 + 4 + 8 + MaxVarIntPayload + maxGovSigLength
+}
+
+// GetHash returns the hash the network identifies the vote by.  The
+// masternode is always hashed in the CTxIn layout, whatever the profile.
+func (msg *MsgGovObjVote) GetHash() chainhash.Hash {
+	var b bytes.Buffer
+
+	writeOutPoint(&b, 0, 0, &msg.Vin.PreviousOutPoint)
+	WriteVarBytes(&b, 0, nil)
+	writeElement(&b, MaxTxInSequenceNum)
+	b.Write(msg.ParentHash[:])
+	writeElements(&b, msg.Signal, msg.Outcome, msg.Time)
+
+	return chainhash.DoubleHashH(b.Bytes())
+}
+
+// SignatureMessage returns the string the legacy vote signature commits to.
+func (msg *MsgGovObjVote) SignatureMessage() string {
+	outpoint := msg.Vin.PreviousOutPoint
+	return fmt.Sprintf("%s-%d", outpoint.Hash.String(), outpoint.Index) + "|" +
+		msg.ParentHash.String() + "|" +
+		strconv.FormatInt(int64(msg.Signal), 10) + "|" +
+		strconv.FormatInt(int64(msg.Outcome), 10) + "|" +
+		strconv.FormatInt(msg.Time, 10)
+}
+
+// SignatureHash returns the hash signed by the newer (SPORK_6_NEW_SIGS)
+// signature scheme.
+func (msg *MsgGovObjVote) SignatureHash() chainhash.Hash {
+	var b bytes.Buffer
+
+	writeOutPoint(&b, 0, 0, &msg.Vin.PreviousOutPoint)
+	b.Write(msg.ParentHash[:])
+	writeElements(&b, msg.Outcome, msg.Signal, msg.Time)
+
+	return chainhash.DoubleHashH(b.Bytes())
+}
+
+// NewMsgGovObjVote returns a new governance vote message that conforms to the
+// Message interface.  See MsgGovObjVote for details.
+func NewMsgGovObjVote() *MsgGovObjVote {
+	return &MsgGovObjVote{}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// GovernanceFilterVersion is the first protocol version that expects a bloom
+// filter at the end of govsync.
+const GovernanceFilterVersion uint32 = 70206
+
+// maxGovSyncFilterSize is the largest bloom filter the daemon accepts.
+const maxGovSyncFilterSize = 36000
+
+// MsgGovSync implements the Message interface and asks a peer for its
+// governance objects.  A zero Hash requests every object, otherwise only the
+// given object and its votes are sent.  Peers on GovernanceFilterVersion and
+// later also read a bloom filter of votes to skip, left empty here.
+type MsgGovSync struct {
+	Hash        chainhash.Hash
+	Filter      []byte
+	FilterFuncs uint32
+	FilterTweak uint32
+	FilterFlags uint8
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGovSync) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	_, err := io.ReadFull(r, msg.Hash[:])
+	if err != nil {
+		return err
+	}
+
+	if pver < GovernanceFilterVersion {
+		return nil
+	}
+
+	msg.Filter, err = ReadVarBytes(r, pver, maxGovSyncFilterSize, "Filter")
+	if err != nil {
+		return err
+	}
+
+	return readElements(r, &msg.FilterFuncs, &msg.FilterTweak, &msg.FilterFlags)
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGovSync) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	_, err := w.Write(msg.Hash[:])
+	if err != nil {
+		return err
+	}
+
+	if pver < GovernanceFilterVersion {
+		return nil
+	}
+
+	err = WriteVarBytes(w, pver, msg.Filter)
+	if err != nil {
+		return err
+	}
+
+	return writeElements(w, msg.FilterFuncs, msg.FilterTweak, msg.FilterFlags)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGovSync) Command() string {
+	return CmdGovSync
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGovSync) MaxPayloadLength(pver uint32) uint32 {
+	//hash + filter + funcs + tweak + flags
+	return 32 + MaxVarIntPayload + maxGovSyncFilterSize + 4 + 4 + 1
+}
@@ -5,18 +5,29 @@
 package wire
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/binary"
+	"encoding/hex"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
 	"io"
+	"net"
+	"strconv"
 )
 
+// CService is a masternode's network address, an IPv6 (or IPv4-mapped)
+// address followed by a big endian port.
 type CService struct {
 	IpAddress [16]byte
 	Port uint16
 }
 
+// String returns the address as ip:port, the way the daemon prints it in
+// signed messages.
+func (service *CService) String() string {
+	return net.JoinHostPort(net.IP(service.IpAddress[:]).String(), strconv.Itoa(int(service.Port)))
+}
+
 func (service *CService) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
 	err := writeElement(w, &service.IpAddress)
 	if err != nil {
@@ -33,18 +44,12 @@ func (service *CService) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding
 	}
 
 	service.Port, err = binarySerializer.Uint16(r, bigEndian)
-	if err != nil {
-		return err
-	}
-
 	return err
 }
 
-// MsgPong implements the Message interface and represents a bitcoin pong
-// message which is used primarily to confirm that a connection is still valid
-// in response to a bitcoin ping message (MsgPing).
-//
-// This message was not added until protocol versions AFTER BIP0031Version.
+// MsgMNB implements the Message interface and represents a masternode
+// broadcast message which announces a masternode and its keys to the network.
+// Profile selects the fork's layout and is handed down to LastPing.
 type MsgMNB struct {
 	// Unique value associated with message that is used to identify
 	// specific ping message.
@@ -57,6 +62,7 @@ type MsgMNB struct {
 	ProtocolVersion uint32
 	LastPing MsgMNP
 	LastDsq uint64
+	Profile *MessageProfile
 }
 
 // BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
@@ -71,7 +77,7 @@ func (msg *MsgMNB) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) erro
 	//}
 
 	//read the tx
-	err := readTxIn(r, pver, 0, &msg.Vin)
+	err := readCollateral(r, pver, msg.Profile, &msg.Vin)
 	if err != nil {
 		return err
 	}
@@ -84,12 +90,21 @@ func (msg *MsgMNB) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) erro
 
 	msg.PubKeyCollateralAddress, err = ReadVarBytes(r, pver, MaxMessagePayload,
 		"PubKeyCollateralAddress")
+	if err != nil {
+		return err
+	}
 
 	msg.PubKeyMasternode, err = ReadVarBytes(r, pver, MaxMessagePayload,
 		"PubKeyMasternode")
+	if err != nil {
+		return err
+	}
 
 	msg.Sig, err = ReadVarBytes(r, pver, MaxMessagePayload,
 		"Sig")
+	if err != nil {
+		return err
+	}
 
 	msg.SigTime, err = binarySerializer.Uint64(r, littleEndian)
 	if err != nil {
@@ -102,13 +117,19 @@ func (msg *MsgMNB) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) erro
 	}
 
 	//decode the ping
-	msg.LastPing.BtcDecode(r, pver, enc)
-
-	msg.LastDsq, err = binarySerializer.Uint64(r, littleEndian)
+	if msg.Profile != nil {
+		msg.LastPing.Profile = msg.Profile
+	}
+	err = msg.LastPing.BtcDecode(r, pver, enc)
 	if err != nil {
 		return err
 	}
 
+	if msg.Profile != nil && !msg.Profile.LastDsq {
+		return nil
+	}
+
+	msg.LastDsq, err = binarySerializer.Uint64(r, littleEndian)
 	return err
 }
 
@@ -116,17 +137,49 @@ func (msg *MsgMNB) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) erro
 // This is part of the Message interface implementation.
 func (msg *MsgMNB) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
 
-	writeTxIn(w, pver, 0, &msg.Vin)
-	msg.Addr.BtcEncode(w, pver, enc)
-	WriteVarBytes(w, pver, msg.PubKeyCollateralAddress[:])
-	WriteVarBytes(w, pver, msg.PubKeyMasternode[:])
-	WriteVarBytes(w, pver, msg.Sig[:])
-	writeElement(w, msg.SigTime)
-	writeElement(w, msg.ProtocolVersion)
-	msg.LastPing.BtcEncode(w, pver, enc)
-	writeElement(w, msg.LastDsq)
+	err := writeCollateral(w, pver, msg.Profile, &msg.Vin)
+	if err != nil {
+		return err
+	}
+
+	err = msg.Addr.BtcEncode(w, pver, enc)
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarBytes(w, pver, msg.PubKeyCollateralAddress[:])
+	if err != nil {
+		return err
+	}
 
-	return nil
+	err = WriteVarBytes(w, pver, msg.PubKeyMasternode[:])
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarBytes(w, pver, msg.Sig[:])
+	if err != nil {
+		return err
+	}
+
+	err = writeElements(w, msg.SigTime, msg.ProtocolVersion)
+	if err != nil {
+		return err
+	}
+
+	if msg.Profile != nil {
+		msg.LastPing.Profile = msg.Profile
+	}
+	err = msg.LastPing.BtcEncode(w, pver, enc)
+	if err != nil {
+		return err
+	}
+
+	if msg.Profile != nil && !msg.Profile.LastDsq {
+		return nil
+	}
+
+	return writeElement(w, msg.LastDsq)
 }
 
 // Command returns the protocol command string for the message.  This is part
@@ -140,12 +193,11 @@ func (msg *MsgMNB) Command() string {
 func (msg *MsgMNB) MaxPayloadLength(pver uint32) uint32 {
 	//vin + addr + pubKeyCollateralAddress + pubKeyMasternode + sig +
 	//sigTime + nProtovolVersion + 	MNP + nLastDsq
-	//ping = vin + blockhash + sigTime + vchSig
-	return 41+18+65+65+73+8+4+(41+32+8+73)+8
+	return 41+18+(1+65)+(1+65)+(1+73)+8+4+msg.LastPing.MaxPayloadLength(pver)+8
 }
 
-// NewMsgPong returns a new bitcoin pong message that conforms to the Message
-// interface.  See MsgPong for details.
+// NewMsgMNB returns a new masternode broadcast message that conforms to the
+// Message interface.  See MsgMNB for details.
 func NewMsgMNB() *MsgMNB {
 	return &MsgMNB{}
 }
@@ -164,10 +216,52 @@ func (msg *MsgMNB) Serialize(w io.Writer) error {
 
 func (msg *MsgMNB) GetHash() chainhash.Hash {
 	var b bytes.Buffer
-	w := bufio.NewWriter(&b)
 
-	writeElement(w, msg.SigTime)
-	WriteVarBytes(w, 0, msg.PubKeyCollateralAddress[:])
+	writeElement(&b, msg.SigTime)
+	WriteVarBytes(&b, 0, msg.PubKeyCollateralAddress[:])
+
+	return chainhash.DoubleHashH(b.Bytes())
+}
+
+// SignatureMessage returns the string the legacy broadcast signature commits
+// to on Dash 12.1 and later and on newer PIVX releases, with the keys given
+// by their key ids.
+func (msg *MsgMNB) SignatureMessage() string {
+	return msg.Addr.String() + strconv.FormatUint(msg.SigTime, 10) +
+		keyID(msg.PubKeyCollateralAddress) + keyID(msg.PubKeyMasternode) +
+		strconv.FormatUint(uint64(msg.ProtocolVersion), 10)
+}
+
+// OldSignatureMessage returns the string the legacy broadcast signature
+// commits to on Dash 12.0 and older PIVX releases, with the raw keys.
+func (msg *MsgMNB) OldSignatureMessage() string {
+	return msg.Addr.String() + strconv.FormatUint(msg.SigTime, 10) +
+		string(msg.PubKeyCollateralAddress) + string(msg.PubKeyMasternode) +
+		strconv.FormatUint(uint64(msg.ProtocolVersion), 10)
+}
+
+// SignatureHash returns the hash signed by the newer (SPORK_6_NEW_SIGS)
+// signature scheme.  The collateral is written as an empty CTxIn to match
+// the daemon's older hashing format.
+func (msg *MsgMNB) SignatureHash() chainhash.Hash {
+	var b bytes.Buffer
+
+	writeOutPoint(&b, 0, 0, &msg.Vin.PreviousOutPoint)
+	writeElements(&b, uint8(0), uint32(MaxTxInSequenceNum))
+	msg.Addr.BtcEncode(&b, 0, BaseEncoding)
+	WriteVarBytes(&b, 0, msg.PubKeyCollateralAddress)
+	WriteVarBytes(&b, 0, msg.PubKeyMasternode)
+	writeElements(&b, msg.SigTime, msg.ProtocolVersion)
 
 	return chainhash.DoubleHashH(b.Bytes())
-}
\ No newline at end of file
+}
+
+// keyID formats the hash160 of a public key the way the daemon prints a
+// CKeyID, byte reversed.
+func keyID(pubKey []byte) string {
+	id := btcutil.Hash160(pubKey)
+	for i, j := 0, len(id)-1; i < j; i, j = i+1, j-1 {
+		id[i], id[j] = id[j], id[i]
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"testing"
+)
+
+// mnbFixtures are mnb payloads in the layout of each profile, see
+// mnpFixtures for the embedded pings.
+var mnbFixtures = []struct {
+	profile string
+	fields  []string
+	sigTime uint64
+}{
+	//PIVX: CTxIn, CService, keys, signature, sigTime, protocol, ping, nLastDsq
+	{"pivx", []string{fixturePivxCollateral, fixtureScriptSig, fixtureSequence, fixtureAddr, fixtureCollateralKey,
+		fixtureMasternodeKey, fixtureSig, fixturePivxSigTime, "03150100",
+		fixturePivxCollateral, fixtureScriptSig, fixtureSequence, fixturePivxBlockHash, fixturePivxSigTime,
+		fixtureSig, "0000000000000000"}, 1556300442},
+	//Dash 12.2: COutPoint collateral, a 12.2 ping and no nLastDsq
+	{"dash-12.2", []string{fixtureCollateral, fixtureAddr, fixtureCollateralKey, fixtureMasternodeKey, fixtureSig,
+		fixtureSigTime, "41120100",
+		fixtureCollateral, fixtureBlockHash, fixtureSigTime, fixtureSig, "01", "01000100", "8bd50100"}, 1555652579},
+}
+
+func TestMNBWire(t *testing.T) {
+	for _, fixture := range mnbFixtures {
+		payload := fixtureBytes(t, fixture.fields...)
+		profile := mustProfile(t, fixture.profile)
+
+		msg := MsgMNB{Profile: profile}
+		err := msg.BtcDecode(bytes.NewReader(payload), 0, BaseEncoding)
+		if err != nil {
+			t.Errorf("%s: BtcDecode: %s", fixture.profile, err)
+			continue
+		}
+
+		if msg.Addr.String() != "45.50.22.125:9999" {
+			t.Errorf("%s: address %s", fixture.profile, msg.Addr.String())
+		}
+		if len(msg.PubKeyCollateralAddress) != 33 || len(msg.PubKeyMasternode) != 33 || len(msg.Sig) != 65 {
+			t.Errorf("%s: keys of %d and %d bytes, signature of %d bytes", fixture.profile,
+				len(msg.PubKeyCollateralAddress), len(msg.PubKeyMasternode), len(msg.Sig))
+		}
+		if msg.SigTime != fixture.sigTime || (msg.ProtocolVersion != 70915 && msg.ProtocolVersion != 70209) {
+			t.Errorf("%s: sigTime %d, protocol %d", fixture.profile, msg.SigTime, msg.ProtocolVersion)
+		}
+		if msg.LastPing.SigTime != msg.SigTime || msg.LastPing.Vin.PreviousOutPoint != msg.Vin.PreviousOutPoint {
+			t.Errorf("%s: last ping %+v", fixture.profile, msg.LastPing)
+		}
+
+		var buf bytes.Buffer
+		err = msg.BtcEncode(&buf, 0, BaseEncoding)
+		if err != nil {
+			t.Errorf("%s: BtcEncode: %s", fixture.profile, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), payload) {
+			t.Errorf("%s: round trip\n got %x\nwant %x", fixture.profile, buf.Bytes(), payload)
+		}
+	}
+}
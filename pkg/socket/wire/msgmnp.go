@@ -5,15 +5,17 @@
 package wire
 
 import (
+	"bytes"
 	"io"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 )
 
-// MsgPong implements the Message interface and represents a bitcoin pong
-// message which is used primarily to confirm that a connection is still valid
-// in response to a bitcoin ping message (MsgPing).
+// MsgMNP implements the Message interface and represents a masternode ping
+// message which a masternode relays periodically to prove it's still alive.
 //
-// This message was not added until protocol versions AFTER BIP0031Version.
+// The optional sentinel and daemon fields are selected by Profile.  Without
+// a profile they must be enabled by the caller before decoding since the
+// payload doesn't say whether they are present.
 type MsgMNP struct {
 	// Unique value associated with message that is used to identify
 	// specific ping message.
@@ -26,6 +28,7 @@ type MsgMNP struct {
 	SentinelVersion uint32
 	DaemonEnabled bool
 	DaemonVersion uint32
+	Profile *MessageProfile
 }
 
 // BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
@@ -39,8 +42,13 @@ func (msg *MsgMNP) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) erro
 	//	return messageError("MsgPong.BtcDecode", str)
 	//}
 
+	if msg.Profile != nil {
+		msg.SentinelEnabled = msg.Profile.Sentinel
+		msg.DaemonEnabled = msg.Profile.DaemonVersion
+	}
+
 	//read the tx
-	err := readTxIn(r, pver, 0, &msg.Vin)
+	err := readCollateral(r, pver, msg.Profile, &msg.Vin)
 	if err != nil {
 		return err
 	}
@@ -58,48 +66,77 @@ func (msg *MsgMNP) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) erro
 
 	msg.VchSig, err = ReadVarBytes(r, pver, MaxMessagePayload,
 		"vchSig")
+	if err != nil {
+		return err
+	}
 
 	if msg.SentinelEnabled { //defaults to false
-		val, _ := binarySerializer.Uint8(r)
-		if val != 0 {
-			msg.SentinelIsCurrent = true
+		val, err := binarySerializer.Uint8(r)
+		if err != nil {
+			return err
 		}
+		msg.SentinelIsCurrent = val != 0
+
 		msg.SentinelVersion, err = binarySerializer.Uint32(r, littleEndian)
+		if err != nil {
+			return err
+		}
 	}
 
 	if msg.DaemonEnabled { //defaults to false
 		msg.DaemonVersion, err = binarySerializer.Uint32(r, littleEndian)
+		if err != nil {
+			return err
+		}
 	}
 
-	return err
+	return nil
 }
 
 // BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
 // This is part of the Message interface implementation.
 func (msg *MsgMNP) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
 
-	writeTxIn(w, pver, 0, &msg.Vin)
+	if msg.Profile != nil {
+		msg.SentinelEnabled = msg.Profile.Sentinel
+		msg.DaemonEnabled = msg.Profile.DaemonVersion
+	}
 
-	_, err := w.Write(msg.BlockHash[:])
+	err := writeCollateral(w, pver, msg.Profile, &msg.Vin)
 	if err != nil {
 		return err
 	}
 
-	writeElement(w, msg.SigTime)
+	_, err = w.Write(msg.BlockHash[:])
+	if err != nil {
+		return err
+	}
 
-	WriteVarBytes(w, pver, msg.VchSig[:])
+	err = writeElement(w, msg.SigTime)
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarBytes(w, pver, msg.VchSig[:])
+	if err != nil {
+		return err
+	}
 
 	if msg.SentinelEnabled { //defaults to false
-		var enabled uint8 = 1
-		writeElement(w, enabled)
-		writeElement(w, msg.SentinelVersion)
+		err = writeElements(w, msg.SentinelIsCurrent, msg.SentinelVersion)
+		if err != nil {
+			return err
+		}
 	}
 
 	if msg.DaemonEnabled {
-		writeElement(w, msg.DaemonVersion)
+		err = writeElement(w, msg.DaemonVersion)
+		if err != nil {
+			return err
+		}
 	}
 
-	return err
+	return nil
 }
 
 func (msg *MsgMNP) Serialize(w io.Writer) error {
@@ -123,12 +160,39 @@ func (msg *MsgMNP) Command() string {
 // MaxPayloadLength returns the maximum length the payload can be for the
 // receiver.  This is part of the Message interface implementation.
 func (msg *MsgMNP) MaxPayloadLength(pver uint32) uint32 {
-	//vin + blockhash + sigTime + vchSig
-	return 41+32+8+74+1+4
+	//vin + blockhash + sigTime + vchSig + sentinel + daemon version
+	return 41+32+8+(1+73)+(1+4)+4
+}
+
+// SignatureHash returns the hash signed by the newer (SPORK_6_NEW_SIGS)
+// signature scheme.  It covers the collateral outpoint, block hash, sigTime
+// and the optional sentinel and daemon fields, but never the signature.
+func (msg *MsgMNP) SignatureHash() chainhash.Hash {
+	var b bytes.Buffer
+
+	sentinelEnabled, daemonEnabled := msg.SentinelEnabled, msg.DaemonEnabled
+	if msg.Profile != nil {
+		sentinelEnabled = msg.Profile.Sentinel
+		daemonEnabled = msg.Profile.DaemonVersion
+	}
+
+	writeOutPoint(&b, 0, 0, &msg.Vin.PreviousOutPoint)
+	b.Write(msg.BlockHash[:])
+	writeElement(&b, msg.SigTime)
+
+	if sentinelEnabled {
+		writeElements(&b, msg.SentinelIsCurrent, msg.SentinelVersion)
+	}
+
+	if daemonEnabled {
+		writeElement(&b, msg.DaemonVersion)
+	}
+
+	return chainhash.DoubleHashH(b.Bytes())
 }
 
-// NewMsgPong returns a new bitcoin pong message that conforms to the Message
-// interface.  See MsgPong for details.
+// NewMsgMNP returns a new masternode ping message that conforms to the
+// Message interface.  See MsgMNP for details.
 func NewMsgMNP() *MsgMNP {
 	return &MsgMNP{}
 }
\ No newline at end of file
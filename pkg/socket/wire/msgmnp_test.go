@@ -0,0 +1,264 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Payload fields shared by the masternode message fixtures, in wire order
+// and byte order. The fixtures are assembled field by field from the
+// daemons' serialization (CTxIn, COutPoint, CService, ...).
+const (
+	fixtureCollateral = "7ca6564432d0e0920b811887e1f9077a92924c83564e6ea8ea874fc8843ccd2b" + "01000000"
+	fixtureScriptSig  = "00"
+	fixtureSequence   = "ffffffff"
+	fixtureBlockHash  = "b67a40f3cd5804437a108f105533739c37e6229bc1adcab385140b59fd0f0000"
+	fixtureSigTime    = "e35fb95c00000000" //1555652579
+	fixtureSig        = "41" + "1f" +
+		"5a0e58c5dd2ec7ed1ee9e1ad3f7f0d3f1b2b6e6cd2f0a5fc2fd6d6f1d0d1a6b4" +
+		"3c5a8e5f2d0b9a7c6e1f4d3b2a19087f6e5d4c3b2a1908f7e6d5c4b3a2918070"
+	fixtureAddr          = "00000000000000000000ffff2d32167d" + "270f" //45.50.22.125:9999
+	fixtureCollateralKey = "21" + "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	fixtureMasternodeKey = "21" + "02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5"
+)
+
+// The PIVX fixtures reference their own collateral and block, a PIVX ping is
+// laid out like a Dash 12.0 one and would otherwise be byte-identical.
+const (
+	fixturePivxCollateral = "d3b5b9e4a8ee3c1f5e0f3a5c2d9d2e8f0c4b7a1e6f9d8c3b2a1f0e9d8c7b6a59" + "00000000"
+	fixturePivxBlockHash  = "5c1e8f0b7d2a3e4f6a9b8c7d0e1f2a3b4c5d6e7f8091a2b3c4d5e6f708192a3b"
+	fixturePivxSigTime    = "9a42c35c00000000" //1556300442
+)
+
+// fixtureBytes decodes the concatenated hex fields of a fixture.
+func fixtureBytes(t testing.TB, fields ...string) []byte {
+	raw, err := hex.DecodeString(strings.Join(fields, ""))
+	if err != nil {
+		t.Fatalf("invalid fixture: %s", err)
+	}
+	return raw
+}
+
+// mustProfile returns the named message profile, nil for "".
+func mustProfile(t testing.TB, name string) *MessageProfile {
+	profile, err := LookupMessageProfile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return profile
+}
+
+// mnpFixtures are mnp payloads in the layout of each profile.
+var mnpFixtures = []struct {
+	profile string
+	fields  []string
+
+	collateral string
+	blockHash  string
+	sigTime    uint64
+}{
+	//PIVX and Dash 12.0: CTxIn, block hash, sigTime, signature
+	{"pivx", []string{fixturePivxCollateral, fixtureScriptSig, fixtureSequence, fixturePivxBlockHash,
+		fixturePivxSigTime, fixtureSig},
+		"596a7b8c9d0e1f2a3b8c9d6f1e7a4b0c8f2e9d2d5c3a0f5e1f3ceea8e4b9b5d3:0",
+		"3b2a1908f7e6d5c4b3a291807f6e5d4c3b2a1f0e7d8c9b6a4f3e2a7d0b8f1e5c", 1556300442},
+	{"dash-12.0", []string{fixtureCollateral, fixtureScriptSig, fixtureSequence, fixtureBlockHash, fixtureSigTime,
+		fixtureSig},
+		"2bcd3c84c84f87eaa86e4e56834c92927a07f9e18718810b92e0d0324456a67c:1",
+		"00000ffd590b1485b3caadc19b22e6379c733355108f107a430458cdf3407ab6", 1555652579},
+	//Dash 12.1 adds fSentinelIsCurrent and nSentinelVersion
+	{"dash-12.1", []string{fixtureCollateral, fixtureScriptSig, fixtureSequence, fixtureBlockHash, fixtureSigTime,
+		fixtureSig, "01", "01000100"},
+		"2bcd3c84c84f87eaa86e4e56834c92927a07f9e18718810b92e0d0324456a67c:1",
+		"00000ffd590b1485b3caadc19b22e6379c733355108f107a430458cdf3407ab6", 1555652579},
+	//Dash 12.2 references the collateral by COutPoint and adds nDaemonVersion
+	{"dash-12.2", []string{fixtureCollateral, fixtureBlockHash, fixtureSigTime, fixtureSig, "01", "01000100",
+		"8bd50100"},
+		"2bcd3c84c84f87eaa86e4e56834c92927a07f9e18718810b92e0d0324456a67c:1",
+		"00000ffd590b1485b3caadc19b22e6379c733355108f107a430458cdf3407ab6", 1555652579},
+}
+
+func TestMNPWire(t *testing.T) {
+	for _, fixture := range mnpFixtures {
+		payload := fixtureBytes(t, fixture.fields...)
+		profile := mustProfile(t, fixture.profile)
+
+		msg := MsgMNP{Profile: profile}
+		err := msg.BtcDecode(bytes.NewReader(payload), 0, BaseEncoding)
+		if err != nil {
+			t.Errorf("%s: BtcDecode: %s", fixture.profile, err)
+			continue
+		}
+
+		if msg.Vin.PreviousOutPoint.String() != fixture.collateral {
+			t.Errorf("%s: collateral %s", fixture.profile, msg.Vin.PreviousOutPoint)
+		}
+		if msg.BlockHash.String() != fixture.blockHash {
+			t.Errorf("%s: block hash %s", fixture.profile, msg.BlockHash)
+		}
+		if msg.SigTime != fixture.sigTime || len(msg.VchSig) != 65 {
+			t.Errorf("%s: sigTime %d, signature of %d bytes", fixture.profile, msg.SigTime, len(msg.VchSig))
+		}
+		if profile.Sentinel && (!msg.SentinelIsCurrent || msg.SentinelVersion != 0x010001) {
+			t.Errorf("%s: sentinel %v version %d", fixture.profile, msg.SentinelIsCurrent, msg.SentinelVersion)
+		}
+		if profile.DaemonVersion && msg.DaemonVersion != 120203 {
+			t.Errorf("%s: daemon version %d", fixture.profile, msg.DaemonVersion)
+		}
+
+		var buf bytes.Buffer
+		err = msg.BtcEncode(&buf, 0, BaseEncoding)
+		if err != nil {
+			t.Errorf("%s: BtcEncode: %s", fixture.profile, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), payload) {
+			t.Errorf("%s: round trip\n got %x\nwant %x", fixture.profile, buf.Bytes(), payload)
+		}
+
+		for i := 0; i < len(payload); i++ {
+			truncated := MsgMNP{Profile: profile}
+			if truncated.BtcDecode(bytes.NewReader(payload[:i]), 0, BaseEncoding) == nil {
+				t.Errorf("%s: a payload truncated to %d bytes decoded", fixture.profile, i)
+				break
+			}
+		}
+	}
+}
+
+// TestMessageRoundTrip sends every fixture through the message framing.
+func TestMessageRoundTrip(t *testing.T) {
+	for _, fixture := range wireFixtures(t) {
+		var buf bytes.Buffer
+		_, err := WriteMessageN(&buf, fixture.msg, 70208, MainNet)
+		if err != nil {
+			t.Errorf("%s %s: WriteMessageN: %s", fixture.msg.Command(), fixture.profile, err)
+			continue
+		}
+
+		_, msg, payload, err := ReadMessageWithProfileN(bytes.NewReader(buf.Bytes()), 70208, MainNet,
+			mustProfile(t, fixture.profile))
+		if err != nil {
+			t.Errorf("%s %s: ReadMessageWithProfileN: %s", fixture.msg.Command(), fixture.profile, err)
+			continue
+		}
+		if msg.Command() != fixture.msg.Command() || !bytes.Equal(payload, fixture.payload) {
+			t.Errorf("%s %s: read back %s with payload %x", fixture.msg.Command(), fixture.profile,
+				msg.Command(), payload)
+		}
+	}
+}
+
+// wireFixture is a decoded fixture and its payload.
+type wireFixture struct {
+	profile string
+	msg     Message
+	payload []byte
+}
+
+// wireFixtures decodes every masternode message fixture.
+func wireFixtures(t testing.TB) []wireFixture {
+	var fixtures []wireFixture
+
+	add := func(profile string, msg Message, fields []string) {
+		payload := fixtureBytes(t, fields...)
+		err := msg.BtcDecode(bytes.NewReader(payload), 0, BaseEncoding)
+		if err != nil {
+			t.Fatalf("%s %s: %s", msg.Command(), profile, err)
+		}
+		fixtures = append(fixtures, wireFixture{profile, msg, payload})
+	}
+
+	for _, fixture := range mnpFixtures {
+		add(fixture.profile, &MsgMNP{Profile: mustProfile(t, fixture.profile)}, fixture.fields)
+	}
+	for _, fixture := range mnbFixtures {
+		add(fixture.profile, &MsgMNB{Profile: mustProfile(t, fixture.profile)}, fixture.fields)
+	}
+	for _, fixture := range dsegFixtures {
+		add(fixture.profile, &MsgDSEG{Profile: mustProfile(t, fixture.profile)}, fixture.fields)
+	}
+	return fixtures
+}
+
+// TestMNPProfileLayouts decodes every fixture under the other profiles. Only
+// profiles with the same ping layout may read it back unchanged.
+func TestMNPProfileLayouts(t *testing.T) {
+	for _, fixture := range mnpFixtures {
+		payload := fixtureBytes(t, fixture.fields...)
+		written := mustProfile(t, fixture.profile)
+
+		for _, other := range mnpFixtures {
+			profile := mustProfile(t, other.profile)
+			sameLayout := profile.OutpointOnly == written.OutpointOnly && profile.Sentinel == written.Sentinel &&
+				profile.DaemonVersion == written.DaemonVersion
+
+			r := bytes.NewReader(payload)
+			msg := MsgMNP{Profile: profile}
+			err := msg.BtcDecode(r, 0, BaseEncoding)
+			readBack := err == nil && r.Len() == 0
+
+			if readBack != sameLayout {
+				t.Errorf("%s payload read as %s: error %v, %d bytes left", fixture.profile, other.profile, err, r.Len())
+			}
+		}
+	}
+}
+
+// TestMNPMaxPayload round trips the largest ping, a legacy CTxIn collateral
+// with the sentinel and daemon fields and a DER signature.
+func TestMNPMaxPayload(t *testing.T) {
+	msg := MsgMNP{
+		Vin:               *NewTxIn(&OutPoint{Index: 1}, nil, nil),
+		SigTime:           1555652579,
+		VchSig:            bytes.Repeat([]byte{0x30}, 73),
+		SentinelEnabled:   true,
+		SentinelIsCurrent: true,
+		SentinelVersion:   0x010001,
+		DaemonEnabled:     true,
+		DaemonVersion:     120203,
+	}
+
+	var payload bytes.Buffer
+	if err := msg.BtcEncode(&payload, 70208, BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	if max := msg.MaxPayloadLength(70208); uint32(payload.Len()) != max {
+		t.Errorf("largest ping is %d bytes, MaxPayloadLength() = %d", payload.Len(), max)
+	}
+
+	var buf bytes.Buffer
+	if _, err := WriteMessageN(&buf, &msg, 70208, MainNet); err != nil {
+		t.Fatalf("WriteMessageN: %s", err)
+	}
+	_, _, read, err := ReadMessageN(&buf, 70208, MainNet)
+	if err != nil {
+		t.Fatalf("ReadMessageN: %s", err)
+	}
+
+	decoded := MsgMNP{SentinelEnabled: true, DaemonEnabled: true}
+	if err := decoded.BtcDecode(bytes.NewReader(read), 70208, BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.DaemonVersion != msg.DaemonVersion || !bytes.Equal(decoded.VchSig, msg.VchSig) {
+		t.Errorf("read back %+v, want %+v", decoded, msg)
+	}
+
+	//the broadcast carrying it, with uncompressed keys
+	mnb := MsgMNB{
+		Vin:                     msg.Vin,
+		PubKeyCollateralAddress: bytes.Repeat([]byte{0x04}, 65),
+		PubKeyMasternode:        bytes.Repeat([]byte{0x04}, 65),
+		Sig:                     bytes.Repeat([]byte{0x30}, 73),
+		LastPing:                msg,
+	}
+	if _, err := WriteMessageN(&buf, &mnb, 70208, MainNet); err != nil {
+		t.Errorf("WriteMessageN(mnb): %s", err)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// MsgMNV implements the Message interface and represents a masternode
+// verification, used to check that a masternode really runs at its address.
+//
+// A request only carries Addr, Nonce and BlockHeight.  The masternode at
+// Addr answers with Sig1, and the verifier then fills in Vin1 (the answering
+// masternode), Vin2 (itself) and Sig2 before relaying the result.
+type MsgMNV struct {
+	Vin1        TxIn
+	Vin2        TxIn
+	Addr        CService
+	Nonce       int32
+	BlockHeight int32
+	Sig1        []byte
+	Sig2        []byte
+	Profile     *MessageProfile
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgMNV) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	err := readCollateral(r, pver, msg.Profile, &msg.Vin1)
+	if err != nil {
+		return err
+	}
+
+	err = readCollateral(r, pver, msg.Profile, &msg.Vin2)
+	if err != nil {
+		return err
+	}
+
+	err = msg.Addr.BtcDecode(r, pver, enc)
+	if err != nil {
+		return err
+	}
+
+	err = readElements(r, &msg.Nonce, &msg.BlockHeight)
+	if err != nil {
+		return err
+	}
+
+	msg.Sig1, err = ReadVarBytes(r, pver, maxGovSigLength, "Sig1")
+	if err != nil {
+		return err
+	}
+
+	msg.Sig2, err = ReadVarBytes(r, pver, maxGovSigLength, "Sig2")
+	return err
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgMNV) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	err := writeCollateral(w, pver, msg.Profile, &msg.Vin1)
+	if err != nil {
+		return err
+	}
+
+	err = writeCollateral(w, pver, msg.Profile, &msg.Vin2)
+	if err != nil {
+		return err
+	}
+
+	err = msg.Addr.BtcEncode(w, pver, enc)
+	if err != nil {
+		return err
+	}
+
+	err = writeElements(w, msg.Nonce, msg.BlockHeight)
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarBytes(w, pver, msg.Sig1)
+	if err != nil {
+		return err
+	}
+
+	return WriteVarBytes(w, pver, msg.Sig2)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgMNV) Command() string {
+	return CmdMNV
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgMNV) MaxPayloadLength(pver uint32) uint32 {
+	//vin1 + vin2 + addr + nonce + height + sig1 + sig2
+	return 41 + 41 + 18 + 4 + 4 + 2*(MaxVarIntPayload+maxGovSigLength)
+}
+
+// IsRequest reports whether the message is an unanswered request.
+func (msg *MsgMNV) IsRequest() bool {
+	return len(msg.Sig1) == 0
+}
+
+// GetHash returns the hash the network identifies the verification by.
+func (msg *MsgMNV) GetHash() chainhash.Hash {
+	var b bytes.Buffer
+
+	for _, vin := range []*TxIn{&msg.Vin1, &msg.Vin2} {
+		writeOutPoint(&b, 0, 0, &vin.PreviousOutPoint)
+		WriteVarBytes(&b, 0, nil)
+		writeElement(&b, MaxTxInSequenceNum)
+	}
+	msg.Addr.BtcEncode(&b, 0, BaseEncoding)
+	writeElements(&b, msg.Nonce, msg.BlockHeight)
+
+	return chainhash.DoubleHashH(b.Bytes())
+}
+
+// SignatureMessage1 returns the string the answering masternode signs with
+// the legacy scheme, blockHash being the hash of the block at BlockHeight.
+// The daemon formats the address with CService::ToString(false), where
+// false only turns off name lookups, so the port is part of it.
+func (msg *MsgMNV) SignatureMessage1(blockHash chainhash.Hash) string {
+	return msg.Addr.String() + strconv.FormatInt(int64(msg.Nonce), 10) + blockHash.String()
+}
+
+// SignatureHash1 returns the hash the answering masternode signs with the
+// newer (SPORK_6_NEW_SIGS) signature scheme.
+func (msg *MsgMNV) SignatureHash1(blockHash chainhash.Hash) chainhash.Hash {
+	var b bytes.Buffer
+
+	msg.Addr.BtcEncode(&b, 0, BaseEncoding)
+	writeElement(&b, msg.Nonce)
+	b.Write(blockHash[:])
+
+	return chainhash.DoubleHashH(b.Bytes())
+}
+
+// SignatureMessage2 returns the string the verifying masternode signs with
+// the legacy scheme.
+func (msg *MsgMNV) SignatureMessage2(blockHash chainhash.Hash) string {
+	outpoint1 := msg.Vin1.PreviousOutPoint
+	outpoint2 := msg.Vin2.PreviousOutPoint
+	return msg.SignatureMessage1(blockHash) +
+		fmt.Sprintf("%s-%d", outpoint1.Hash.String(), outpoint1.Index) +
+		fmt.Sprintf("%s-%d", outpoint2.Hash.String(), outpoint2.Index)
+}
+
+// NewMsgMNV returns a new masternode verification message that conforms to
+// the Message interface.  See MsgMNV for details.
+func NewMsgMNV() *MsgMNV {
+	return &MsgMNV{}
+}
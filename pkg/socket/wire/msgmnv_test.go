@@ -0,0 +1,44 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"net"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// TestMNVSignatureMessages checks the legacy strings against the daemon's
+// strprintf("%s%d%s", service.ToString(false), nonce, blockHash.ToString()).
+func TestMNVSignatureMessages(t *testing.T) {
+	blockHash, err := chainhash.NewHashFromStr("00000000000000170a6c8d4a1b4e9c2a7b0fd31e5d4f0b9a8c7d6e5f4a3b2c1d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collateral, err := chainhash.NewHashFromStr("2bcd3c84c84f87eaa86e4e56834c92927a07f9e18718810b92e0d0324456a67c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mnv := MsgMNV{
+		Vin1:        *NewTxIn(NewOutPoint(collateral, 1), nil, nil),
+		Vin2:        *NewTxIn(NewOutPoint(collateral, 0), nil, nil),
+		Nonce:       123456,
+		BlockHeight: 1000000,
+	}
+	copy(mnv.Addr.IpAddress[:], net.ParseIP("45.50.22.125").To16())
+	mnv.Addr.Port = 9999
+
+	want1 := "45.50.22.125:9999123456" + blockHash.String()
+	if got := mnv.SignatureMessage1(*blockHash); got != want1 {
+		t.Errorf("SignatureMessage1() = %q, want %q", got, want1)
+	}
+
+	want2 := want1 + collateral.String() + "-1" + collateral.String() + "-0"
+	if got := mnv.SignatureMessage2(*blockHash); got != want2 {
+		t.Errorf("SignatureMessage2() = %q, want %q", got, want2)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// maxPayeeScriptLength caps the payee script of a payment vote.
+const maxPayeeScriptLength = 10000
+
+// MsgMNW implements the Message interface and represents a masternode
+// payment vote (masternode winner), naming the payee a top ranked masternode
+// expects to be paid at BlockHeight.
+type MsgMNW struct {
+	Vin         TxIn
+	BlockHeight int32
+	Payee       []byte
+	Sig         []byte
+	Profile     *MessageProfile
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgMNW) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	err := readCollateral(r, pver, msg.Profile, &msg.Vin)
+	if err != nil {
+		return err
+	}
+
+	err = readElement(r, &msg.BlockHeight)
+	if err != nil {
+		return err
+	}
+
+	msg.Payee, err = ReadVarBytes(r, pver, maxPayeeScriptLength, "Payee")
+	if err != nil {
+		return err
+	}
+
+	msg.Sig, err = ReadVarBytes(r, pver, maxGovSigLength, "Sig")
+	return err
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgMNW) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	err := writeCollateral(w, pver, msg.Profile, &msg.Vin)
+	if err != nil {
+		return err
+	}
+
+	err = writeElement(w, msg.BlockHeight)
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarBytes(w, pver, msg.Payee)
+	if err != nil {
+		return err
+	}
+
+	return WriteVarBytes(w, pver, msg.Sig)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgMNW) Command() string {
+	return CmdMNW
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgMNW) MaxPayloadLength(pver uint32) uint32 {
+	//vin + height + payee + sig
+	return 41 + 4 + MaxVarIntPayload + maxPayeeScriptLength + MaxVarIntPayload + maxGovSigLength
+}
+
+// GetHash returns the hash the network identifies the vote by.
+func (msg *MsgMNW) GetHash() chainhash.Hash {
+	var b bytes.Buffer
+
+	WriteVarBytes(&b, 0, msg.Payee)
+	writeElement(&b, msg.BlockHeight)
+	writeOutPoint(&b, 0, 0, &msg.Vin.PreviousOutPoint)
+
+	return chainhash.DoubleHashH(b.Bytes())
+}
+
+// SignatureMessage returns the string the legacy vote signature commits to.
+// The payee is rendered in script assembly, which matches the daemon's
+// output for the standard pay-to-pubkey-hash and pay-to-script-hash payees.
+func (msg *MsgMNW) SignatureMessage() string {
+	payee, _ := txscript.DisasmString(msg.Payee)
+
+	outpoint := msg.Vin.PreviousOutPoint
+	return fmt.Sprintf("%s-%d", outpoint.Hash.String(), outpoint.Index) +
+		strconv.FormatInt(int64(msg.BlockHeight), 10) + payee
+}
+
+// SignatureHash returns the hash signed by the newer (SPORK_6_NEW_SIGS)
+// signature scheme.
+func (msg *MsgMNW) SignatureHash() chainhash.Hash {
+	var b bytes.Buffer
+
+	writeOutPoint(&b, 0, 0, &msg.Vin.PreviousOutPoint)
+	writeElement(&b, msg.BlockHeight)
+	WriteVarBytes(&b, 0, msg.Payee)
+
+	return chainhash.DoubleHashH(b.Bytes())
+}
+
+// NewMsgMNW returns a new payment vote message that conforms to the Message
+// interface.  See MsgMNW for details.
+func NewMsgMNW() *MsgMNW {
+	return &MsgMNW{}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// maxSporkSigLength is a generous upper bound for the compact signature a
+// spork carries.
+const maxSporkSigLength = 80
+
+// MsgSpork implements the Message interface and represents a network wide
+// switch set by the holder of the coin's spork key.  A spork is active once
+// the unix time in Value has passed.
+type MsgSpork struct {
+	SporkID    int32
+	Value      int64
+	TimeSigned int64
+	Sig        []byte
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgSpork) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	err := readElements(r, &msg.SporkID, &msg.Value, &msg.TimeSigned)
+	if err != nil {
+		return err
+	}
+
+	msg.Sig, err = ReadVarBytes(r, pver, maxSporkSigLength, "Sig")
+	return err
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgSpork) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	err := writeElements(w, msg.SporkID, msg.Value, msg.TimeSigned)
+	if err != nil {
+		return err
+	}
+
+	return WriteVarBytes(w, pver, msg.Sig)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgSpork) Command() string {
+	return CmdSpork
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgSpork) MaxPayloadLength(pver uint32) uint32 {
+	//id + value + time signed + sig
+	return 4 + 8 + 8 + MaxVarIntPayload + maxSporkSigLength
+}
+
+// SignatureMessage returns the string the legacy spork signature commits to.
+func (msg *MsgSpork) SignatureMessage() string {
+	return strconv.FormatInt(int64(msg.SporkID), 10) +
+		strconv.FormatInt(msg.Value, 10) +
+		strconv.FormatInt(msg.TimeSigned, 10)
+}
+
+// SignatureHash returns the hash signed by the newer (SPORK_6_NEW_SIGS)
+// signature scheme.
+func (msg *MsgSpork) SignatureHash() chainhash.Hash {
+	var b bytes.Buffer
+	writeElements(&b, msg.SporkID, msg.Value, msg.TimeSigned)
+	return chainhash.DoubleHashH(b.Bytes())
+}
+
+// NewMsgSpork returns a new spork message that conforms to the Message
+// interface.  See MsgSpork for details.
+func NewMsgSpork() *MsgSpork {
+	return &MsgSpork{}
+}
+
+// MsgGetSporks implements the Message interface and asks a peer to send
+// every spork it knows about.
+//
+// This message has no payload.
+type MsgGetSporks struct{}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGetSporks) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGetSporks) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGetSporks) Command() string {
+	return CmdGetSporks
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGetSporks) MaxPayloadLength(pver uint32) uint32 {
+	return 0
+}
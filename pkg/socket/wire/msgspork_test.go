@@ -0,0 +1,62 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSporkWire(t *testing.T) {
+	//SPORK_6_NEW_SIGS, value, time signed, signature
+	payload := fixtureBytes(t, "15270000", "e35fb95c00000000", "a3a3b95c00000000", fixtureSig)
+
+	var msg MsgSpork
+	if err := msg.BtcDecode(bytes.NewReader(payload), 70208, BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	if msg.SporkID != 10005 || msg.Value != 1555652579 || msg.TimeSigned != 1555669923 || len(msg.Sig) != 65 {
+		t.Errorf("decoded %+v", msg)
+	}
+	if got := msg.SignatureMessage(); got != "1000515556525791555669923" {
+		t.Errorf("SignatureMessage() = %q", got)
+	}
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, 70208, BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), payload) {
+		t.Errorf("round trip\n got %x\nwant %x", buf.Bytes(), payload)
+	}
+	if uint32(len(payload)) > msg.MaxPayloadLength(70208) {
+		t.Errorf("a %d byte spork exceeds MaxPayloadLength()", len(payload))
+	}
+
+	for i := 0; i < len(payload); i++ {
+		var truncated MsgSpork
+		if truncated.BtcDecode(bytes.NewReader(payload[:i]), 70208, BaseEncoding) == nil {
+			t.Errorf("a payload truncated to %d bytes decoded", i)
+			break
+		}
+	}
+
+	oversized := append(fixtureBytes(t, "15270000", "e35fb95c00000000", "a3a3b95c00000000", "51"),
+		make([]byte, 81)...)
+	if (&MsgSpork{}).BtcDecode(bytes.NewReader(oversized), 70208, BaseEncoding) == nil {
+		t.Error("a spork with an 81 byte signature decoded")
+	}
+
+	//the signature hash covers everything but the signature
+	hash := msg.SignatureHash()
+	msg.Sig = nil
+	if msg.SignatureHash() != hash {
+		t.Error("SignatureHash() depends on the signature")
+	}
+	msg.Value++
+	if msg.SignatureHash() == hash {
+		t.Error("SignatureHash() doesn't cover the value")
+	}
+}
@@ -0,0 +1,105 @@
+package wire
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// MessageProfile describes how a coin's fork serializes the masternode
+// messages (mnp, mnb and dseg).  The forks all share the same fields but
+// disagree on how the collateral is referenced and which optional fields
+// are present.
+type MessageProfile struct {
+	Name string
+
+	// OutpointOnly references the collateral with a bare COutPoint (Dash
+	// 12.2+) instead of a full CTxIn (PIVX, Dash 12.0 and 12.1).
+	OutpointOnly bool
+
+	// Sentinel adds fSentinelIsCurrent and nSentinelVersion to the mnp.
+	Sentinel bool
+
+	// DaemonVersion adds nDaemonVersion to the mnp.
+	DaemonVersion bool
+
+	// LastDsq adds nLastDsq to the end of the mnb.
+	LastDsq bool
+}
+
+// MessageProfiles are the known masternode message layouts by name.
+var MessageProfiles = map[string]MessageProfile{
+	"pivx": {
+		Name:    "pivx",
+		LastDsq: true,
+	},
+	"dash-12.0": {
+		Name:    "dash-12.0",
+		LastDsq: true,
+	},
+	"dash-12.1": {
+		Name:     "dash-12.1",
+		Sentinel: true,
+	},
+	"dash-12.2": {
+		Name:          "dash-12.2",
+		OutpointOnly:  true,
+		Sentinel:      true,
+		DaemonVersion: true,
+	},
+}
+
+// LookupMessageProfile returns the named profile.  An empty name returns nil,
+// which keeps the legacy behaviour of a CTxIn collateral, a trailing nLastDsq
+// and optional mnp fields enabled by the caller.
+func LookupMessageProfile(name string) (*MessageProfile, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	profile, ok := MessageProfiles[name]
+	if !ok {
+		names := make([]string, 0, len(MessageProfiles))
+		for known := range MessageProfiles {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown message profile %q (known: %v)", name, names)
+	}
+	return &profile, nil
+}
+
+// PingSignatureMessage returns the string a ping's legacy signature commits
+// to.  Every known fork formats the collateral as a CTxIn, but outpoint only
+// encodings never carry a script so it is always empty for them.
+func (profile *MessageProfile) PingSignatureMessage(hash string, n uint32, scriptSig []byte,
+	blockHash string, sigTime uint64) string {
+
+	if profile != nil && profile.OutpointOnly {
+		scriptSig = nil
+	}
+
+	return fmt.Sprintf("CTxIn(COutPoint(%s, %d), scriptSig=%s)%s%s", hash, n,
+		hex.EncodeToString(scriptSig), blockHash, strconv.FormatInt(int64(sigTime), 10))
+}
+
+// readCollateral reads the collateral reference for the profile.  Outpoint
+// only encodings are expanded into a TxIn with an empty script so callers
+// can treat both layouts the same way.
+func readCollateral(r io.Reader, pver uint32, profile *MessageProfile, ti *TxIn) error {
+	if profile != nil && profile.OutpointOnly {
+		ti.SignatureScript = []byte{}
+		ti.Sequence = MaxTxInSequenceNum
+		return readOutPoint(r, pver, 0, &ti.PreviousOutPoint)
+	}
+	return readTxIn(r, pver, 0, ti)
+}
+
+func writeCollateral(w io.Writer, pver uint32, profile *MessageProfile, ti *TxIn) error {
+	if profile != nil && profile.OutpointOnly {
+		return writeOutPoint(w, pver, 0, &ti.PreviousOutPoint)
+	}
+	return writeTxIn(w, pver, 0, ti)
+}